@@ -0,0 +1,174 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// Uploads may have some bytes of multipart framing on top of the file.
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler implements the http api for attachments, nested under their task. [/v1/tasks/{uuid}/attachments]
+type AttachmentHandler struct {
+	service *AttachmentService
+}
+
+func NewAttachmentHandler(service *AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// Returns the task of the url, and the attachment if the url has one. Writes an error response if they're invalid.
+func parseParams(w http.ResponseWriter, r *http.Request, withAttachment bool) (uuid.UUID, uuid.UUID, bool) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if !withAttachment {
+		return taskID, uuid.Nil, true
+	}
+
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "attachment"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return taskID, attachmentID, true
+}
+
+// List lists the attachments of a task. [GET /v1/tasks/{uuid}/attachments]
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Get the attachments
+	attachments, err := h.service.List(r.Context(), taskID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, attachments, len(attachments), len(attachments), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Get returns the metadata of an attachment. [GET /v1/tasks/{uuid}/attachments/{attachment}]
+func (h *AttachmentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	taskID, attachmentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Get the attachment
+	attachment, err := h.service.Get(r.Context(), taskID, attachmentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, attachment, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Create uploads a file to a task, as the "file" part of a multipart form. [POST /v1/tasks/{uuid}/attachments]
+// The file is streamed to the blob store, it's never held in memory as a whole.
+func (h *AttachmentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.Limits().MaxSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		krest.WriteErrorResponse(w, krest.NewError(http.StatusBadRequest, "expected a multipart form: %v", err))
+		return
+	}
+
+	// Find the file part.
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			krest.WriteErrorResponse(w, &krest.ValidationError{Errors: []krest.FieldError{{Field: "file", Rule: "required", Message: "is required"}}})
+			return
+		}
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			krest.WriteErrorResponse(w, krest.NewError(http.StatusRequestEntityTooLarge, "files can't be larger than %d bytes", h.service.Limits().MaxSize))
+			return
+		}
+		if err != nil {
+			krest.WriteErrorResponse(w, krest.NewError(http.StatusBadRequest, "invalid multipart form: %v", err))
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		// Create the attachment.
+		attachment, err := h.service.Create(r.Context(), taskID, part.FileName(), part)
+		if errors.As(err, &maxBytesError) {
+			err = krest.NewError(http.StatusRequestEntityTooLarge, "files can't be larger than %d bytes", h.service.Limits().MaxSize)
+		}
+		if err != nil {
+			krest.WriteErrorResponse(w, err)
+			return
+		}
+
+		// Write the response.
+		krest.WriteResourceResponse(w, http.StatusCreated, attachment, krest.ResourceQuery{}, krest.MetaQuery{})
+		return
+	}
+}
+
+// Content downloads the content of an attachment, supporting Range requests. [GET /v1/tasks/{uuid}/attachments/{attachment}/content]
+func (h *AttachmentHandler) Content(w http.ResponseWriter, r *http.Request) {
+	taskID, attachmentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Open the content
+	attachment, content, err := h.service.Open(r.Context(), taskID, attachmentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	defer content.Close()
+
+	// Content is served as a download with the sniffed type, browsers must not sniff it again.
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(attachment.Digest))
+	http.ServeContent(w, r, attachment.Name, attachment.CreatedAt, content)
+}
+
+// Delete deletes an attachment. [DELETE /v1/tasks/{uuid}/attachments/{attachment}]
+func (h *AttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	taskID, attachmentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Delete the attachment.
+	err := h.service.Delete(r.Context(), taskID, attachmentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
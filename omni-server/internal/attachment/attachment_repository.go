@@ -0,0 +1,48 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// AttachmentRepository queries the attachments of every organization, to find the content still in use.
+// The attachments themselves are stored by the generic repository.
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+// NewAttachmentRepository queries the attachments and tasks tables, create them first.
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// DeleteDetached deletes the attachments of deleted tasks, not every database cascades deletes.
+func (r *AttachmentRepository) DeleteDetached(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE task_id NOT IN (SELECT uuid FROM tasks)`)
+	if err != nil {
+		return fmt.Errorf("failed to delete detached attachments: %v", err)
+	}
+	return nil
+}
+
+// Digests returns the digests of the content of all attachments.
+func (r *AttachmentRepository) Digests(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT digest FROM attachments`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digests: %v", err)
+	}
+	defer rows.Close()
+
+	digests := map[string]bool{}
+	for rows.Next() {
+		var digest string
+		err = rows.Scan(&digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query digests: %v", err)
+		}
+		digests[digest] = true
+	}
+
+	return digests, rows.Err()
+}
@@ -0,0 +1,340 @@
+package attachment
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var ErrNotUploader = krest.NewError(http.StatusForbidden, "only the uploader or a project admin can delete an attachment")
+
+// Limits restrict the files that can be uploaded.
+type Limits struct {
+	MaxSize int64    // In bytes.
+	Types   []string // The allowed MIME types, e.g. application/pdf, or image/ for any image. Any type is allowed without types.
+}
+
+// DefaultLimits allow files of any type up to 25 MiB.
+var DefaultLimits = Limits{MaxSize: 25 << 20}
+
+// Returns whether the limits allow a MIME type.
+func (l Limits) allows(mimeType string) bool {
+	if len(l.Types) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range l.Types {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachmentService manages the files attached to tasks, storing their content in a blob store.
+// Attachments are visible to everyone who can see the task, members of the project can upload them.
+// Only the uploader or a project admin can delete an attachment. Uploads and deletes are audited.
+type AttachmentService struct {
+	attachments  krest.Repository[models.Attachment]
+	transactions krest_orm.Transactor
+	store        BlobStore
+	tasks        krest.Service[models.Task]
+	policy       *authz.Policy
+	auditLog     *audit.Log
+	limits       Limits
+}
+
+// The task service must authorize reading tasks, attachments are only visible through their task.
+func NewAttachmentService(attachments *krest_orm.GenericPostgresRepository[models.Attachment], store BlobStore, tasks krest.Service[models.Task], policy *authz.Policy, auditLog *audit.Log, limits Limits) *AttachmentService {
+	return &AttachmentService{attachments: attachments, transactions: attachments, store: store, tasks: tasks, policy: policy, auditLog: auditLog, limits: limits}
+}
+
+// Limits returns the limits of uploads.
+func (s *AttachmentService) Limits() Limits {
+	return s.limits
+}
+
+// Returns a visible task, with its project.
+func (s *AttachmentService) task(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	return s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+}
+
+// List lists the attachments of a task, oldest first.
+func (s *AttachmentService) List(ctx context.Context, taskID uuid.UUID) ([]models.Attachment, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.attachments.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "task_id", Operator: krest.FilterEqual, Value: taskID}},
+		Sort:    []krest.Sort{{Field: "created_at"}},
+	})
+}
+
+func (s *AttachmentService) Get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Attachment, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	return s.get(ctx, taskID, id)
+}
+
+// Returns an attachment of a task, without authorizing.
+func (s *AttachmentService) get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Attachment, error) {
+	attachment, err := s.attachments.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	if attachment.TaskID != taskID {
+		return models.Attachment{}, krest.NewError(http.StatusNotFound, "attachments %s not found", id)
+	}
+
+	return attachment, nil
+}
+
+// Open returns an attachment of a task with its content, close the content when done.
+func (s *AttachmentService) Open(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.Get(ctx, taskID, id)
+	if err != nil {
+		return models.Attachment{}, nil, err
+	}
+
+	content, err := s.store.Open(ctx, attachment.Digest)
+	if errors.Is(err, ErrBlobNotFound) {
+		return models.Attachment{}, nil, krest.NewError(http.StatusNotFound, "content of attachment %s not found", id)
+	}
+	if err != nil {
+		return models.Attachment{}, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+// Create uploads a file to a task, uploaded by the authenticated user.
+// The MIME type is sniffed from the content, files exceeding the limits are rejected before they're stored.
+func (s *AttachmentService) Create(ctx context.Context, taskID uuid.UUID, name string, content io.Reader) (models.Attachment, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Attachment{}, auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	// Only the base name of the upload is kept, some clients send a path.
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return models.Attachment{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "file", Rule: "required", Message: "requires a file name"}}}
+	}
+	if len([]rune(name)) > 255 {
+		return models.Attachment{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "file", Rule: "max", Message: "must have a file name of at most 255 characters"}}}
+	}
+
+	// Sniff the type from the start of the content.
+	buffered := bufio.NewReaderSize(content, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return models.Attachment{}, err
+	}
+	mimeType := http.DetectContentType(head)
+	if !s.limits.allows(mimeType) {
+		return models.Attachment{}, krest.NewError(http.StatusUnsupportedMediaType, "files of type %s can't be attached", mimeType)
+	}
+
+	digest, size, err := s.store.Put(ctx, &limitedReader{reader: buffered, remaining: s.limits.MaxSize})
+	if errors.Is(err, errTooLarge) {
+		return models.Attachment{}, krest.NewError(http.StatusRequestEntityTooLarge, "files can't be larger than %d bytes", s.limits.MaxSize)
+	}
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	var created models.Attachment
+	err = s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		created, err = s.attachments.Create(ctx, models.Attachment{
+			TaskID:     task.UUID,
+			UploaderID: user.UUID,
+			Name:       name,
+			Size:       size,
+			MimeType:   mimeType,
+			Digest:     digest,
+			CreatedAt:  time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionCreate, task, created.UUID, nil, created)
+	})
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	return created, nil
+}
+
+// Delete deletes an attachment, its content is removed with the orphans once no attachment uses it.
+func (s *AttachmentService) Delete(ctx context.Context, taskID uuid.UUID, id uuid.UUID) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := s.get(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+
+	if attachment.UploaderID != user.UUID {
+		role, err := s.policy.Role(ctx, task.ProjectID, user.UUID)
+		if err != nil {
+			return err
+		}
+		if !role.Includes(authz.RoleAdmin) {
+			return ErrNotUploader
+		}
+	}
+
+	return s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		err := s.attachments.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionDelete, task, id, attachment, nil)
+	})
+}
+
+// Records a change of an attachment in the audit log, pass nil as before or after for created and deleted attachments.
+func (s *AttachmentService) record(ctx context.Context, action audit.Action, task models.Task, id uuid.UUID, before interface{}, after interface{}) error {
+	err := s.auditLog.Record(ctx, action, "attachments", id, task.ProjectID, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+var errTooLarge = errors.New("content exceeds the size limit")
+
+// Reads up to the remaining bytes, returns errTooLarge if the reader has more.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, errTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, errTooLarge
+	}
+	return n, err
+}
+
+// OrphanCollector removes content that no attachment uses anymore, in the background.
+// Content is kept for a grace period after it's written, so uploads in progress aren't removed.
+type OrphanCollector struct {
+	attachments *AttachmentRepository
+	store       BlobStore
+	grace       time.Duration
+}
+
+func NewOrphanCollector(attachments *AttachmentRepository, store BlobStore, grace time.Duration) *OrphanCollector {
+	return &OrphanCollector{attachments: attachments, store: store, grace: grace}
+}
+
+// Run collects orphans at every interval, until the context is done.
+func (c *OrphanCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := c.Collect(ctx)
+			if err != nil {
+				log.Printf("Failed to remove orphaned attachments: %v", err)
+			} else if removed > 0 {
+				log.Printf("Removed %d orphaned attachments", removed)
+			}
+		}
+	}
+}
+
+// Collect removes the attachments of deleted tasks, and the content no attachment uses. Returns the number of removed blobs.
+func (c *OrphanCollector) Collect(ctx context.Context) (int, error) {
+	err := c.attachments.DeleteDetached(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// List the blobs first, content written after listing the digests is within the grace period.
+	// Content uploaded again after listing is written after the cutoff, the store keeps it.
+	cutoff := time.Now().Add(-c.grace)
+	blobs, err := c.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	digests, err := c.attachments.Digests(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, blob := range blobs {
+		if digests[blob.Digest] || !blob.ModTime.Before(cutoff) {
+			continue
+		}
+
+		deleted, err := c.store.Delete(ctx, blob.Digest, cutoff)
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove blob %s: %v", blob.Digest, err)
+		}
+		if deleted {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,184 @@
+package attachment_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/attachment"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The signature of png files.
+var png = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+func TestAttachments(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	attachmentRepository := krest_orm.NewGenericPostgresRepository[models.Attachment](db)
+	policy := authz.NewPolicy(membershipRepository)
+	tasks := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	store, err := attachment.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore failed: %v", err)
+	}
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	attachments := attachment.NewAttachmentService(attachmentRepository, store, tasks, policy, auditLog, attachment.Limits{MaxSize: 64, Types: []string{"image/", "text/plain"}})
+	collector := attachment.NewOrphanCollector(attachment.NewAttachmentRepository(db), store, 0)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	user, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleMember)
+	if err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+	alice := auth.WithUser(ctx, user)
+	task, err := tasks.Create(alice, models.Task{Summary: "Screenshots", ProjectID: project.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The type is sniffed from the content, and only the base name is kept.
+	screenshot, err := attachments.Create(alice, task.UUID, `C:\Users\alice\screenshot.txt`, bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if screenshot.Name != "screenshot.txt" || screenshot.MimeType != "image/png" || screenshot.Size != int64(len(png)) {
+		t.Errorf("expected a png named screenshot.txt, got %+v", screenshot)
+	}
+
+	// The same content is stored once.
+	copied, err := attachments.Create(alice, task.UUID, "copy.png", bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if copied.Digest != screenshot.Digest {
+		t.Errorf("expected the same digest for the same content, got %s and %s", copied.Digest, screenshot.Digest)
+	}
+
+	_, err = attachments.Create(alice, task.UUID, "notes.txt", strings.NewReader(strings.Repeat("a", 65)))
+	if krest.ErrorStatus(err) != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a file over the size limit, got %v", err)
+	}
+	_, err = attachments.Create(alice, task.UUID, "report.pdf", strings.NewReader("%PDF-1.7"))
+	if krest.ErrorStatus(err) != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for a type that isn't allowed, got %v", err)
+	}
+
+	_, content, err := attachments.Open(alice, task.UUID, screenshot.UUID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	read, err := io.ReadAll(content)
+	content.Close()
+	if err != nil || !bytes.Equal(read, png) {
+		t.Errorf("expected the uploaded content, got %q (%v)", read, err)
+	}
+
+	// Uploading and deleting attachments is audited.
+	err = attachments.Delete(alice, task.UUID, screenshot.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: screenshot.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ResourceType != "attachments" || events[0].ProjectID != project.UUID || events[0].Changes["name"].After != "screenshot.txt" || events[1].Action != string(audit.ActionDelete) {
+		t.Errorf("expected the attachment to be audited when uploaded and deleted, got %+v", events)
+	}
+
+	// Content is removed once no attachment uses it.
+	removed, err := collector.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("expected the content of the copy to be kept, removed %d blobs", removed)
+	}
+
+	err = taskRepository.Delete(ctx, task.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	removed, err = collector.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected the content of the deleted task to be removed, removed %d blobs", removed)
+	}
+	blobs, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(blobs) != 0 {
+		t.Errorf("expected no content left, got %+v", blobs)
+	}
+}
+
+func TestBlobStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := attachment.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore failed: %v", err)
+	}
+
+	digest, _, err := store.Put(ctx, strings.NewReader("orphan"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Content uploaded again after the cutoff of a collection is kept.
+	cutoff := time.Now()
+	_, _, err = store.Put(ctx, strings.NewReader("orphan"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	deleted, err := store.Delete(ctx, digest, cutoff)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted {
+		t.Errorf("expected content uploaded after the cutoff to be kept")
+	}
+
+	deleted, err = store.Delete(ctx, digest, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if !deleted {
+		t.Errorf("expected content uploaded before the cutoff to be deleted")
+	}
+	_, err = store.Open(ctx, digest)
+	if err != attachment.ErrBlobNotFound {
+		t.Errorf("expected the content to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package attachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrBlobNotFound is returned for digests that aren't stored.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores content by its SHA-256 digest, so the same content is only stored once.
+type BlobStore interface {
+	// Put stores the content of a reader, and returns its digest and size.
+	// Errors of the reader are returned as is, and nothing is stored.
+	Put(ctx context.Context, r io.Reader) (string, int64, error)
+
+	// Open opens the content with a digest.
+	Open(ctx context.Context, digest string) (io.ReadSeekCloser, error)
+
+	// Delete deletes the content with a digest, unless it was written at or after a time.
+	// Content put again while it's deleted is either kept or written anew, never lost.
+	// Returns whether the content was deleted, digests that aren't stored are ignored.
+	Delete(ctx context.Context, digest string, writtenBefore time.Time) (bool, error)
+
+	// List lists the stored content.
+	List(ctx context.Context) ([]Blob, error)
+}
+
+// Blob describes stored content.
+type Blob struct {
+	Digest  string
+	Size    int64
+	ModTime time.Time
+}
+
+var digestPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LocalBlobStore stores content in files on local disk, named by their digest in directories by the first two characters.
+type LocalBlobStore struct {
+	root string
+	mu   sync.Mutex // Orders moving uploads in place and deletes, so uploads aren't deleted.
+}
+
+// NewLocalBlobStore stores content in the directory, creating it if it doesn't exist.
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	return &LocalBlobStore{root: root}, nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, r io.Reader) (string, int64, error) {
+	// Write to a temporary file first, the digest is only known at the end.
+	file, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create blob: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	err = file.Close()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(digest)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	// Stamp the time with the clock deletes compare against, file systems may lag behind it.
+	now := time.Now()
+	err = os.Chtimes(path, now, now)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	return digest, size, nil
+}
+
+func (s *LocalBlobStore) Open(ctx context.Context, digest string) (io.ReadSeekCloser, error) {
+	if !digestPattern.MatchString(digest) {
+		return nil, ErrBlobNotFound
+	}
+
+	file, err := os.Open(s.path(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %v", err)
+	}
+	return file, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, digest string, writtenBefore time.Time) (bool, error) {
+	if !digestPattern.MatchString(digest) {
+		return false, nil
+	}
+
+	// Check the time again while holding the lock, the content may have been uploaded again since it was listed.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.path(digest)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete blob: %v", err)
+	}
+	if !info.ModTime().Before(writtenBefore) {
+		return false, nil
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to delete blob: %v", err)
+	}
+	return err == nil, nil
+}
+
+func (s *LocalBlobStore) List(ctx context.Context) ([]Blob, error) {
+	blobs := []Blob{}
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !digestPattern.MatchString(entry.Name()) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, Blob{Digest: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %v", err)
+	}
+	return blobs, nil
+}
+
+// Returns the path of the file with the content of a digest.
+func (s *LocalBlobStore) path(digest string) string {
+	return filepath.Join(s.root, digest[:2], digest)
+}
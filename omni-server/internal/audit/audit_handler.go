@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// Handler implements the read-only http api of the audit log. [/v1/audit-events]
+// Events of resources in projects are only visible to members of the project,
+// other events are visible to everyone in the organization.
+type Handler struct {
+	log    *Log
+	policy *authz.Policy
+}
+
+func NewHandler(log *Log, policy *authz.Policy) *Handler {
+	return &Handler{log: log, policy: policy}
+}
+
+// Get returns an audit event. [GET /v1/audit-events/{uuid}]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visible, err := h.visibleProjects(r)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the event
+	events, err := h.log.List(r.Context(), krest.CollectionQuery{
+		Limit: 1,
+		Filters: []krest.Filter{
+			{Field: "uuid", Operator: krest.FilterEqual, Value: id},
+			visible,
+		},
+	})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	if len(events) == 0 {
+		krest.WriteErrorResponse(w, krest.NewError(http.StatusNotFound, "audit event %s not found", id))
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, events[0], krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// List lists audit events. [GET /v1/audit-events]
+// Filters: resource_type, resource_id, actor_id, action, and the time range since and until (RFC 3339).
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filters, err := parseFilters(r)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	visible, err := h.visibleProjects(r)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	query.Filters = append(query.Filters, append(filters, visible)...)
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the events
+	events, err := h.log.List(r.Context(), query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, events, len(events), len(events), query, metaQuery)
+}
+
+// Returns a filter on the events the authenticated user can see.
+func (h *Handler) visibleProjects(r *http.Request) (krest.Filter, error) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		return krest.Filter{}, auth.ErrUnauthenticated
+	}
+
+	projects, err := h.policy.VisibleProjects(r.Context(), user.UUID)
+	if err != nil {
+		return krest.Filter{}, err
+	}
+
+	// Events outside of projects have no project.
+	projects = append(projects, uuid.Nil)
+	return krest.Filter{Field: "project_id", Operator: krest.FilterIn, Value: projects}, nil
+}
+
+// Returns the filters of the query parameters.
+func parseFilters(r *http.Request) ([]krest.Filter, error) {
+	params := r.URL.Query()
+	filters := []krest.Filter{}
+
+	for _, name := range []string{"resource_type", "action"} {
+		if value := params.Get(name); value != "" {
+			filters = append(filters, krest.Filter{Field: name, Operator: krest.FilterEqual, Value: value})
+		}
+	}
+
+	for _, name := range []string{"resource_id", "actor_id"} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, krest.NewError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		filters = append(filters, krest.Filter{Field: name, Operator: krest.FilterEqual, Value: id})
+	}
+
+	timeRange := map[string]krest.FilterOperator{"since": krest.FilterGreaterOrEqual, "until": krest.FilterLessThan}
+	for name, operator := range timeRange {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, krest.NewError(http.StatusBadRequest, "invalid %s, expected an RFC 3339 time: %v", name, err)
+		}
+		filters = append(filters, krest.Filter{Field: "created_at", Operator: operator, Value: t.UTC()})
+	}
+
+	return filters, nil
+}
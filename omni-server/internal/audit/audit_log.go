@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Action is the kind of change recorded by an audit event.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Log records audit events. Events can only be appended, never changed or deleted.
+type Log struct {
+	events krest.Repository[models.AuditEvent]
+}
+
+func NewLog(events krest.Repository[models.AuditEvent]) *Log {
+	return &Log{events: events}
+}
+
+// Record appends an event for a change to a resource, made by the authenticated user of the context.
+// Pass nil as before or after for created and deleted resources.
+func (l *Log) Record(ctx context.Context, action Action, resourceType string, resourceID uuid.UUID, projectID uuid.UUID, before interface{}, after interface{}) error {
+	diff, err := krest.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	changes := models.AuditChanges{}
+	for field, change := range diff {
+		changes[field] = models.AuditChange{Before: change.Before, After: change.After}
+	}
+
+	// Anonymous changes have no actor.
+	actorID := uuid.Nil
+	if user, ok := auth.UserFromContext(ctx); ok {
+		actorID = user.UUID
+	}
+
+	_, err = l.events.Create(ctx, models.AuditEvent{
+		ActorID:      actorID,
+		RequestID:    middleware.GetReqID(ctx),
+		CreatedAt:    time.Now().UTC(),
+		Action:       string(action),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ProjectID:    projectID,
+		Changes:      changes,
+	})
+	return err
+}
+
+// List lists recorded events.
+func (l *Log) List(ctx context.Context, query krest.CollectionQuery) ([]models.AuditEvent, error) {
+	return l.events.List(ctx, query)
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	krest_sql_helpers "github.com/khaossystems/omni-server/internal/pkg/krest_orm/sql"
+)
+
+// Service wraps a krest.Service[T], recording every change in the audit log.
+// Changes are recorded after they succeed, wrap the service before authorizing it so only permitted changes are recorded.
+// Implements krest.Service[T]
+type Service[T any] struct {
+	krest.ServiceWrapper[T]
+	log          *Log
+	resourceType string
+	// Returns the project a resource belongs to, nil for resources outside of projects.
+	project func(resource T) uuid.UUID
+}
+
+func NewService[T any](service krest.Service[T], log *Log, project func(resource T) uuid.UUID) *Service[T] {
+	return &Service[T]{
+		ServiceWrapper: krest.ServiceWrapper[T]{Service: service},
+		log:            log,
+		resourceType:   krest_sql_helpers.TableName[T](),
+		project:        project,
+	}
+}
+
+func (s *Service[T]) Create(ctx context.Context, resource T) (T, error) {
+	createdResource, err := s.Service.Create(ctx, resource)
+	if err != nil {
+		return *new(T), err
+	}
+
+	err = s.record(ctx, ActionCreate, nil, &createdResource)
+	if err != nil {
+		return *new(T), err
+	}
+
+	return createdResource, nil
+}
+
+func (s *Service[T]) Update(ctx context.Context, id uuid.UUID, resource T) (T, error) {
+	current, err := s.current(ctx, id)
+	if err != nil {
+		return *new(T), err
+	}
+
+	updatedResource, err := s.Service.Update(ctx, id, resource)
+	if err != nil {
+		return *new(T), err
+	}
+
+	err = s.record(ctx, ActionUpdate, &current, &updatedResource)
+	if err != nil {
+		return *new(T), err
+	}
+
+	return updatedResource, nil
+}
+
+func (s *Service[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	current, err := s.current(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.Service.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.record(ctx, ActionDelete, &current, nil)
+}
+
+// Returns the stored resource with all fields, to diff changes against.
+func (s *Service[T]) current(ctx context.Context, id uuid.UUID) (T, error) {
+	expand, err := krest.ExpandableFieldNames[T]()
+	if err != nil {
+		return *new(T), err
+	}
+
+	return s.Service.Get(ctx, id, krest.ResourceQuery{Expand: expand})
+}
+
+// Records a change, before or after is nil for created and deleted resources.
+func (s *Service[T]) record(ctx context.Context, action Action, before *T, after *T) error {
+	resource := after
+	if resource == nil {
+		resource = before
+	}
+
+	resourceID, err := primaryKey(*resource)
+	if err != nil {
+		return err
+	}
+
+	projectID := uuid.Nil
+	if s.project != nil {
+		projectID = s.project(*resource)
+	}
+
+	// Typed nil pointers aren't nil interfaces, pass created and deleted resources on as untyped nil.
+	var beforeData, afterData interface{}
+	if before != nil {
+		beforeData = *before
+	}
+	if after != nil {
+		afterData = *after
+	}
+
+	err = s.log.Record(ctx, action, s.resourceType, resourceID, projectID, beforeData, afterData)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+
+	return nil
+}
+
+// Returns the value of the primary key of a resource.
+func primaryKey[T any](resource T) (uuid.UUID, error) {
+	field, err := krest.ReflectPrimaryKeyField[T]()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id, ok := reflect.ValueOf(resource).FieldByIndex(field.Index).Interface().(uuid.UUID)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("primary key of %T is not a uuid", resource)
+	}
+
+	return id, nil
+}
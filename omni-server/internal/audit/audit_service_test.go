@@ -0,0 +1,75 @@
+package audit_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestServiceRecordsChanges(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	log := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	projects := audit.NewService(
+		krest_orm.NewGenericService(krest_orm.NewGenericPostgresRepository[models.Project](db)),
+		log,
+		func(project models.Project) uuid.UUID { return project.UUID },
+	)
+
+	actor := models.User{UUID: uuid.New()}
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	ctx = auth.WithUser(ctx, actor)
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "request-1")
+
+	project, err := projects.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project.Name = "Omni Server"
+	_, err = projects.Update(ctx, project.UUID, project)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	err = projects.Delete(ctx, project.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	events, err := log.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: project.UUID}},
+	})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("List returned incorrect number of events: got %d, want 3", len(events))
+	}
+
+	for i, action := range []string{"create", "update", "delete"} {
+		event := events[i]
+		if event.Action != action || event.ActorID != actor.UUID || event.RequestID != "request-1" || event.ResourceType != "projects" || event.ProjectID != project.UUID {
+			t.Errorf("event %d has incorrect data: got %+v", i, event)
+		}
+	}
+
+	update := events[1].Changes
+	if len(update) != 1 || update["name"].Before != "Omni" || update["name"].After != "Omni Server" {
+		t.Errorf("update event has incorrect changes: got %+v", update)
+	}
+	if events[2].Changes["key"].Before != "OMNI" || events[2].Changes["key"].After != nil {
+		t.Errorf("delete event has incorrect changes: got %+v", events[2].Changes)
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// Handler implements the http api for signing in and out. [/v1/auth]
+type Handler struct {
+	service *AuthService
+}
+
+func NewHandler(service *AuthService) *Handler {
+	return &Handler{service: service}
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Login signs a user in, and sets the session cookie. [POST /v1/auth/login]
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body.
+	var request loginRequest
+	err := krest.DecodeRequestBody(w, r, &request, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Verify the credentials, and create a session.
+	user, token, err := h.service.Login(r.Context(), request.Email, request.Password)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	h.service.setSessionCookie(w, r, token)
+	krest.WriteResourceResponse(w, http.StatusOK, user, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Logout ends the current session, and clears the session cookie. [POST /v1/auth/logout]
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(h.service.config.CookieName)
+	if err == nil && cookie.Value != "" {
+		err = h.service.Logout(r.Context(), cookie.Value)
+		if err != nil && err != ErrUnauthenticated {
+			krest.WriteErrorResponse(w, err)
+			return
+		}
+	}
+
+	h.service.clearSessionCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me returns the authenticated user. [GET /v1/auth/me]
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		krest.WriteErrorResponse(w, ErrUnauthenticated)
+		return
+	}
+
+	query, err := krest.ParseResourceQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	krest.WriteResourceResponse(w, http.StatusOK, user, query, metaQuery)
+}
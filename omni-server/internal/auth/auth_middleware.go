@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	scopesContextKey
+)
+
+// WithUser returns a copy of the context carrying the authenticated user.
+// The user is also recorded as the author of revisions, see krest_orm.WithActor.
+func WithUser(ctx context.Context, user models.User) context.Context {
+	ctx = krest_orm.WithActor(ctx, user.UUID)
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user of a request, if any.
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userContextKey).(models.User)
+	return user, ok
+}
+
+// WithScopes returns a copy of the context carrying the scopes the request is restricted to.
+func WithScopes(ctx context.Context, scopes Scopes) context.Context {
+	return context.WithValue(ctx, scopesContextKey, scopes)
+}
+
+// ScopesFromContext returns the scopes a request is restricted to.
+// Requests authenticated with a session are not restricted, in which case ok is false.
+func ScopesFromContext(ctx context.Context) (Scopes, bool) {
+	scopes, ok := ctx.Value(scopesContextKey).(Scopes)
+	return scopes, ok
+}
+
+// Middleware authenticates requests carrying a session cookie, and puts the user into the request context.
+// Requests without a valid session are passed on unauthenticated, use RequireUser to reject them.
+func (s *AuthService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(s.config.CookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, newToken, err := s.Authenticate(r.Context(), cookie.Value)
+		if err != nil {
+			s.clearSessionCookie(w, r)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if newToken != "" {
+			s.setSessionCookie(w, r, newToken)
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// RequireUser rejects requests that are not authenticated with 401 Unauthorized.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFromContext(r.Context()); !ok {
+			krest.WriteErrorResponse(w, ErrUnauthenticated)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware authenticates requests carrying a personal access token in the 'Authorization: Bearer' header,
+// and puts the user and the scopes of the token into the request context.
+// Requests with an invalid token are rejected with 401 Unauthorized.
+func (s *TokenService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		scheme, secret, found := strings.Cut(authorization, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, scopes, err := s.Authenticate(r.Context(), strings.TrimSpace(secret))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			krest.WriteErrorResponse(w, err)
+			return
+		}
+
+		ctx := WithScopes(WithUser(r.Context(), user), scopes)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope returns a middleware rejecting token authenticated requests without a scope for the resource.
+// Safe methods require read access, all other methods require write access.
+func RequireScope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := ScopesFromContext(r.Context())
+			if ok && !scopes.Allows(requestAction(r), resource) {
+				krest.WriteErrorResponse(w, krest.NewError(http.StatusForbidden, "token is missing the %s:%s scope", requestAction(r), resource))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *AuthService) setSessionCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.config.CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(s.config.SessionLifetime),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *AuthService) clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.config.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var (
+	ErrInvalidCredentials = krest.NewError(http.StatusUnauthorized, "invalid email or password")
+	ErrUnauthenticated    = krest.NewError(http.StatusUnauthorized, "authentication required")
+)
+
+// How long a rotated session stays valid, so concurrent requests using the old token don't fail.
+const rotationGracePeriod = 30 * time.Second
+
+type Config struct {
+	// Name of the session cookie.
+	CookieName string
+	// How long a session is valid after it was created.
+	SessionLifetime time.Duration
+	// How often the session token is replaced by a new one.
+	RotationInterval time.Duration
+}
+
+var DefaultConfig = Config{
+	CookieName:       "omni_session",
+	SessionLifetime:  14 * 24 * time.Hour,
+	RotationInterval: time.Hour,
+}
+
+// AuthService signs users in and out, and resolves session tokens to users.
+type AuthService struct {
+	users    krest.Repository[models.User]
+	sessions krest.Repository[models.Session]
+	config   Config
+
+	// Hash verified when signing in with an unknown email, so the response time doesn't reveal which emails exist.
+	dummyHash string
+}
+
+func NewAuthService(users krest.Repository[models.User], sessions krest.Repository[models.Session], config Config) *AuthService {
+	dummyHash, err := HashPassword(uuid.NewString())
+	if err != nil {
+		panic(fmt.Sprintf("failed to hash dummy password: %v", err))
+	}
+
+	return &AuthService{users: users, sessions: sessions, config: config, dummyHash: dummyHash}
+}
+
+// Login verifies the credentials of a user, and creates a new session.
+// Returns the user and the session token.
+func (s *AuthService) Login(ctx context.Context, email string, password string) (models.User, string, error) {
+	// The organization of the user isn't known yet, emails are unique across organizations.
+	users, err := s.users.List(krest_orm.WithoutTenant(ctx), krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "email", Operator: krest.FilterEqual, Value: email}},
+	})
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	if len(users) == 0 {
+		VerifyPassword(password, s.dummyHash)
+		return models.User{}, "", ErrInvalidCredentials
+	}
+
+	user := users[0]
+	ok, err := VerifyPassword(password, user.Password)
+	if err != nil || !ok {
+		return models.User{}, "", ErrInvalidCredentials
+	}
+
+	token, err := s.createSession(ctx, user.UUID)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	return user, token, nil
+}
+
+// Logout ends the session identified by the token.
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	session, err := s.findSession(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return s.sessions.Delete(ctx, session.UUID)
+}
+
+// Authenticate resolves a session token to its user.
+// If the session is due for rotation, a new session is created, and its token returned. Otherwise the returned token is empty.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (models.User, string, error) {
+	session, err := s.findSession(ctx, token)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	now := time.Now().UTC()
+	if !now.Before(session.ExpiresAt) {
+		s.sessions.Delete(ctx, session.UUID)
+		return models.User{}, "", ErrUnauthenticated
+	}
+
+	user, err := s.users.Get(krest_orm.WithoutTenant(ctx), session.UserID, krest.ResourceQuery{})
+	if err != nil {
+		return models.User{}, "", ErrUnauthenticated
+	}
+
+	// Rotate the session token, the old session expires shortly after.
+	newToken := ""
+	if now.Sub(session.CreatedAt) >= s.config.RotationInterval {
+		newToken, err = s.createSession(ctx, user.UUID)
+		if err != nil {
+			return models.User{}, "", err
+		}
+
+		if gracefulExpiry := now.Add(rotationGracePeriod); gracefulExpiry.Before(session.ExpiresAt) {
+			session.ExpiresAt = gracefulExpiry
+			_, err = s.sessions.Update(ctx, session.UUID, session)
+			if err != nil {
+				return models.User{}, "", err
+			}
+		}
+	}
+
+	return user, newToken, nil
+}
+
+func (s *AuthService) createSession(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	_, err = s.sessions.Create(ctx, models.Session{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.SessionLifetime),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+
+	return token, nil
+}
+
+func (s *AuthService) findSession(ctx context.Context, token string) (models.Session, error) {
+	sessions, err := s.sessions.List(ctx, krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "token_hash", Operator: krest.FilterEqual, Value: hashToken(token)}},
+	})
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	if len(sessions) == 0 {
+		return models.Session{}, ErrUnauthenticated
+	}
+
+	return sessions[0], nil
+}
+
+// Generates a random, url safe, token.
+func generateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Tokens are random and long, so a fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,117 @@
+package auth_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/user"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setup(t *testing.T, config auth.Config) *auth.AuthService {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	sessionRepository := krest_orm.NewGenericPostgresRepository[models.Session](db)
+
+	// Users are created through the user service, so their password is hashed.
+	_, err = user.NewUserService(userRepository).Create(krest_orm.WithTenant(context.Background(), uuid.New()), models.User{
+		Name:     "Jane",
+		Email:    "jane@example.com",
+		Password: "correct horse battery staple",
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return auth.NewAuthService(userRepository, sessionRepository, config)
+}
+
+func TestLogin(t *testing.T) {
+	service := setup(t, auth.DefaultConfig)
+
+	_, _, err := service.Login(context.Background(), "jane@example.com", "wrong password")
+	if err != auth.ErrInvalidCredentials {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	_, _, err = service.Login(context.Background(), "john@example.com", "correct horse battery staple")
+	if err != auth.ErrInvalidCredentials {
+		t.Fatalf("expected invalid credentials for unknown user, got %v", err)
+	}
+
+	user, token, err := service.Login(context.Background(), "jane@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	authenticated, newToken, err := service.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if authenticated.UUID != user.UUID || newToken != "" {
+		t.Errorf("Authenticate returned incorrect data: got %+v, %q", authenticated, newToken)
+	}
+
+	err = service.Logout(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+
+	_, _, err = service.Authenticate(context.Background(), token)
+	if err != auth.ErrUnauthenticated {
+		t.Fatalf("expected session to be ended, got %v", err)
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	config := auth.DefaultConfig
+	config.RotationInterval = 0
+	service := setup(t, config)
+
+	_, token, err := service.Login(context.Background(), "jane@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	_, newToken, err := service.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if newToken == "" || newToken == token {
+		t.Fatalf("expected the session to be rotated, got %q", newToken)
+	}
+
+	// Both tokens are valid during the grace period.
+	for _, token := range []string{token, newToken} {
+		if _, _, err := service.Authenticate(context.Background(), token); err != nil {
+			t.Errorf("Authenticate failed: %v", err)
+		}
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	config := auth.DefaultConfig
+	config.SessionLifetime = -time.Minute
+	service := setup(t, config)
+
+	_, token, err := service.Login(context.Background(), "jane@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	_, _, err = service.Authenticate(context.Background(), token)
+	if err != auth.ErrUnauthenticated {
+		t.Fatalf("expected the session to be expired, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+var scopePattern = regexp.MustCompile(`^(read|write):([a-z_-]+|\*)$`)
+
+// Scopes are the permissions granted to a personal access token, e.g. "read:projects" or "write:*".
+type Scopes []string
+
+// ParseScopes parses a space separated list of scopes.
+func ParseScopes(scopes string) (Scopes, error) {
+	parsed := Scopes{}
+	for _, scope := range strings.Fields(scopes) {
+		if !scopePattern.MatchString(scope) {
+			return nil, &krest.ValidationError{Errors: []krest.FieldError{{
+				Field:   "scopes",
+				Rule:    "scope",
+				Message: fmt.Sprintf("invalid scope %q, expected read:<resource> or write:<resource>", scope),
+			}}}
+		}
+		parsed = append(parsed, scope)
+	}
+
+	return parsed, nil
+}
+
+// Allows checks if the scopes grant an action (read or write) on a resource. Write access implies read access.
+func (s Scopes) Allows(action string, resource string) bool {
+	for _, scope := range s {
+		scopeAction, scopeResource, _ := strings.Cut(scope, ":")
+		if scopeResource != resource && scopeResource != "*" {
+			continue
+		}
+
+		if scopeAction == action || scopeAction == "write" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the action a request performs, safe methods read, everything else writes.
+func requestAction(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	default:
+		return "write"
+	}
+}
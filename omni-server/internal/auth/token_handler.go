@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TokenHandler implements the http api for personal access tokens. [/v1/users/{uuid}/tokens]
+// Users can only manage their own tokens.
+type TokenHandler struct {
+	service *TokenService
+}
+
+func NewTokenHandler(service *TokenService) *TokenHandler {
+	return &TokenHandler{service: service}
+}
+
+// Returns the user of the url, if it's the authenticated user. Writes an error response otherwise.
+func (h *TokenHandler) authorizedUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		krest.WriteErrorResponse(w, ErrUnauthenticated)
+		return uuid.Nil, false
+	}
+
+	if user.UUID != userID {
+		krest.WriteErrorResponse(w, krest.NewError(http.StatusNotFound, "users %s not found", userID))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// List lists the tokens of a user. [GET /v1/users/{uuid}/tokens]
+func (h *TokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Token](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the tokens
+	tokens, err := h.service.List(r.Context(), userID, query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, tokens, len(tokens), len(tokens), query, metaQuery)
+}
+
+// Create creates a token for a user, the response is the only time the token is shown. [POST /v1/users/{uuid}/tokens]
+func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the request body.
+	var token models.Token
+	err := krest.DecodeRequestBody(w, r, &token, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	krest.ClearReadOnlyFields(&token)
+
+	// Validate the token.
+	err = krest.ValidateResource(r.Context(), token, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Create the token.
+	createdToken, err := h.service.Create(r.Context(), userID, token)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusCreated, createdToken, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Delete revokes a token of a user. [DELETE /v1/users/{uuid}/tokens/{token}]
+func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID, err := uuid.Parse(chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Delete the token.
+	err = h.service.Delete(r.Context(), userID, tokenID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
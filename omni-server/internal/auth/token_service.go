@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Prefix of all personal access tokens, so they're easy to recognize, e.g. by secret scanners.
+const tokenPrefix = "omni_"
+
+// How often the last used timestamp of a token is written, to avoid a write on every request.
+const lastUsedResolution = time.Minute
+
+// TokenService manages personal access tokens, and resolves them to their user and scopes.
+type TokenService struct {
+	tokens  krest.Repository[models.Token]
+	changes krest.Service[models.Token]
+	users   krest.Repository[models.User]
+}
+
+// Tokens are created and revoked through changes, e.g. an audit.Service, and read and marked as used through tokens,
+// so using a token isn't recorded as a change.
+func NewTokenService(tokens krest.Repository[models.Token], changes krest.Service[models.Token], users krest.Repository[models.User]) *TokenService {
+	return &TokenService{tokens: tokens, changes: changes, users: users}
+}
+
+// List returns the tokens of a user.
+func (s *TokenService) List(ctx context.Context, userID uuid.UUID, query krest.CollectionQuery) ([]models.Token, error) {
+	query.Filters = append(query.Filters, krest.Filter{Field: "user_id", Operator: krest.FilterEqual, Value: userID})
+	return s.tokens.List(ctx, query)
+}
+
+// Create creates a new token for a user. The returned token is the only time the token itself is available.
+func (s *TokenService) Create(ctx context.Context, userID uuid.UUID, token models.Token) (models.Token, error) {
+	if _, err := ParseScopes(token.Scopes); err != nil {
+		return models.Token{}, err
+	}
+
+	now := time.Now().UTC()
+	if !token.ExpiresAt.IsZero() && !token.ExpiresAt.After(now) {
+		return models.Token{}, &krest.ValidationError{Errors: []krest.FieldError{
+			{Field: "expires_at", Rule: "future", Message: "must be in the future"},
+		}}
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return models.Token{}, err
+	}
+	secret = tokenPrefix + secret
+
+	token.UserID = userID
+	token.Prefix = secret[:len(tokenPrefix)+6]
+	token.TokenHash = hashToken(secret)
+	token.CreatedAt = now
+	token.LastUsedAt = time.Time{}
+
+	createdToken, err := s.changes.Create(ctx, token)
+	if err != nil {
+		return models.Token{}, err
+	}
+
+	createdToken.Token = secret
+	return createdToken, nil
+}
+
+// Delete revokes a token of a user.
+func (s *TokenService) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	token, err := s.tokens.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return err
+	}
+
+	// Tokens of other users are reported as not found, so their existence isn't revealed.
+	if token.UserID != userID {
+		return krest.NewError(http.StatusNotFound, "tokens %s not found", id)
+	}
+
+	return s.changes.Delete(ctx, id)
+}
+
+// Authenticate resolves a token to its user and scopes, and records that the token was used.
+func (s *TokenService) Authenticate(ctx context.Context, secret string) (models.User, Scopes, error) {
+	tokens, err := s.tokens.List(ctx, krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "token_hash", Operator: krest.FilterEqual, Value: hashToken(secret)}},
+	})
+	if err != nil {
+		return models.User{}, nil, err
+	}
+	if len(tokens) == 0 {
+		return models.User{}, nil, ErrUnauthenticated
+	}
+
+	token := tokens[0]
+	now := time.Now().UTC()
+	if !token.ExpiresAt.IsZero() && !now.Before(token.ExpiresAt) {
+		return models.User{}, nil, ErrUnauthenticated
+	}
+
+	scopes, err := ParseScopes(token.Scopes)
+	if err != nil {
+		return models.User{}, nil, fmt.Errorf("token %s has invalid scopes: %v", token.UUID, err)
+	}
+
+	user, err := s.users.Get(krest_orm.WithoutTenant(ctx), token.UserID, krest.ResourceQuery{})
+	if err != nil {
+		return models.User{}, nil, ErrUnauthenticated
+	}
+
+	if now.Sub(token.LastUsedAt) >= lastUsedResolution {
+		token.LastUsedAt = now
+		_, err = s.tokens.Update(ctx, token.UUID, token)
+		if err != nil {
+			return models.User{}, nil, fmt.Errorf("failed to update token: %v", err)
+		}
+	}
+
+	return user, scopes, nil
+}
@@ -0,0 +1,101 @@
+package auth_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTokens(t *testing.T) (*auth.TokenService, models.User) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	tokenRepository := krest_orm.NewGenericPostgresRepository[models.Token](db)
+
+	user, err := userRepository.Create(krest_orm.WithTenant(context.Background(), uuid.New()), models.User{Name: "Jane", Username: "jane", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return auth.NewTokenService(tokenRepository, krest_orm.NewGenericService(tokenRepository), userRepository), user
+}
+
+func TestTokenAuthenticate(t *testing.T) {
+	service, user := setupTokens(t)
+
+	token, err := service.Create(context.Background(), user.UUID, models.Token{Name: "CI", Scopes: "read:projects write:tasks"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(token.Token, token.Prefix) || token.TokenHash == token.Token {
+		t.Errorf("Create returned incorrect data: got %+v", token)
+	}
+
+	authenticated, scopes, err := service.Authenticate(context.Background(), token.Token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if authenticated.UUID != user.UUID {
+		t.Errorf("Authenticate returned incorrect user: got %+v, want %+v", authenticated, user)
+	}
+
+	allowed := map[string]bool{
+		"read:projects":  true,
+		"write:projects": false,
+		"read:tasks":     true,
+		"write:tasks":    true,
+		"read:users":     false,
+	}
+	for scope, expected := range allowed {
+		action, resource, _ := strings.Cut(scope, ":")
+		if scopes.Allows(action, resource) != expected {
+			t.Errorf("expected %s to be allowed: %v", scope, expected)
+		}
+	}
+
+	// The token itself is never stored.
+	tokens, err := service.List(context.Background(), user.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].Token != "" || tokens[0].LastUsedAt.IsZero() {
+		t.Errorf("List returned incorrect data: got %+v", tokens)
+	}
+}
+
+func TestTokenInvalid(t *testing.T) {
+	service, user := setupTokens(t)
+
+	_, err := service.Create(context.Background(), user.UUID, models.Token{Name: "CI", Scopes: "admin"})
+	if err == nil {
+		t.Fatalf("expected invalid scopes to be rejected")
+	}
+
+	token, err := service.Create(context.Background(), user.UUID, models.Token{Name: "CI", Scopes: "read:*", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	err = service.Delete(context.Background(), user.UUID, token.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	_, _, err = service.Authenticate(context.Background(), token.Token)
+	if err != auth.ErrUnauthenticated {
+		t.Fatalf("expected revoked token to be rejected, got %v", err)
+	}
+}
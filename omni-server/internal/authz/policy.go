@@ -0,0 +1,104 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Role of a user in a project, see models.Membership.
+type Role string
+
+const (
+	RoleNone   Role = ""
+	RoleViewer Role = "viewer"
+	RoleMember Role = "member"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleNone: 0, RoleViewer: 1, RoleMember: 2, RoleAdmin: 3}
+
+// Includes checks if the role grants at least the permissions of another role.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// Policy answers which projects a user can access, and with which role, based on their memberships.
+type Policy struct {
+	memberships krest.Repository[models.Membership]
+}
+
+func NewPolicy(memberships krest.Repository[models.Membership]) *Policy {
+	return &Policy{memberships: memberships}
+}
+
+// Role returns the role of a user in a project, RoleNone if the user is not a member.
+func (p *Policy) Role(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (Role, error) {
+	memberships, err := p.memberships.List(ctx, krest.CollectionQuery{
+		Limit: 1,
+		Filters: []krest.Filter{
+			{Field: "project_id", Operator: krest.FilterEqual, Value: projectID},
+			{Field: "user_id", Operator: krest.FilterEqual, Value: userID},
+		},
+	})
+	if err != nil {
+		return RoleNone, err
+	}
+
+	if len(memberships) == 0 {
+		return RoleNone, nil
+	}
+
+	return Role(memberships[0].Role), nil
+}
+
+// VisibleProjects returns the projects a user is a member of.
+func (p *Policy) VisibleProjects(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	memberships, err := p.memberships.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "user_id", Operator: krest.FilterEqual, Value: userID}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []uuid.UUID{}
+	for _, membership := range memberships {
+		projects = append(projects, membership.ProjectID)
+	}
+
+	return projects, nil
+}
+
+// Authorize checks that the authenticated user has at least the required role in a project.
+// Users without any role are told the resource doesn't exist, so resources in other projects can't be discovered.
+func (p *Policy) Authorize(ctx context.Context, projectID uuid.UUID, required Role, notFound error) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	role, err := p.Role(ctx, projectID, user.UUID)
+	if err != nil {
+		return err
+	}
+
+	if role == RoleNone {
+		return notFound
+	}
+
+	if !role.Includes(required) {
+		return krest.NewError(http.StatusForbidden, "requires the %s role in the project, you are a %s", required, role)
+	}
+
+	return nil
+}
+
+// AddMember grants a user a role in a project.
+func (p *Policy) AddMember(ctx context.Context, projectID uuid.UUID, userID uuid.UUID, role Role) error {
+	_, err := p.memberships.Create(ctx, models.Membership{ProjectID: projectID, UserID: userID, Role: string(role)})
+	return err
+}
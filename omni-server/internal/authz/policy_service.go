@@ -0,0 +1,154 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Rules define which project a resource belongs to, and the role required for each operation.
+type Rules[T any] struct {
+	// Returns the project a resource belongs to.
+	Project func(resource T) uuid.UUID
+	// JSON name of the field holding the project, used to scope collections to visible projects.
+	ProjectField string
+
+	Read   Role
+	Create Role
+	Update Role
+	Delete Role
+}
+
+// TaskRules authorize tasks by their project, viewers can read them and members can change them.
+var TaskRules = Rules[models.Task]{
+	Project:      func(task models.Task) uuid.UUID { return task.ProjectID },
+	ProjectField: "project_id",
+	Read:         RoleViewer,
+	Create:       RoleMember,
+	Update:       RoleMember,
+	Delete:       RoleMember,
+}
+
+// PolicyService wraps a krest.Service[T], authorizing every operation for the authenticated user.
+// Implements krest.Service[T]
+type PolicyService[T any] struct {
+	service krest.Service[T]
+	policy  *Policy
+	rules   Rules[T]
+}
+
+func NewPolicyService[T any](service krest.Service[T], policy *Policy, rules Rules[T]) *PolicyService[T] {
+	return &PolicyService[T]{service: service, policy: policy, rules: rules}
+}
+
+func (s *PolicyService[T]) Get(ctx context.Context, id uuid.UUID, query krest.ResourceQuery) (T, error) {
+	projectID, err := s.projectOf(ctx, id)
+	if err != nil {
+		return *new(T), err
+	}
+
+	err = s.policy.Authorize(ctx, projectID, s.rules.Read, notFound(id))
+	if err != nil {
+		return *new(T), err
+	}
+
+	return s.service.Get(ctx, id, query)
+}
+
+// Collections only contain resources in projects the user is a member of.
+func (s *PolicyService[T]) List(ctx context.Context, query krest.CollectionQuery) ([]T, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	projects, err := s.policy.VisibleProjects(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Filters = append(query.Filters, krest.Filter{Field: s.rules.ProjectField, Operator: krest.FilterIn, Value: projects})
+	return s.service.List(ctx, query)
+}
+
+func (s *PolicyService[T]) Create(ctx context.Context, resource T) (T, error) {
+	err := s.policy.Authorize(ctx, s.rules.Project(resource), s.rules.Create, projectNotFound(s.rules.Project(resource)))
+	if err != nil {
+		return *new(T), err
+	}
+
+	return s.service.Create(ctx, resource)
+}
+
+// Moving a resource to another project also requires permission to create resources in that project.
+func (s *PolicyService[T]) Update(ctx context.Context, id uuid.UUID, resource T) (T, error) {
+	projectID, err := s.projectOf(ctx, id)
+	if err != nil {
+		return *new(T), err
+	}
+
+	err = s.policy.Authorize(ctx, projectID, s.rules.Update, notFound(id))
+	if err != nil {
+		return *new(T), err
+	}
+
+	if newProjectID := s.rules.Project(resource); newProjectID != projectID {
+		err = s.policy.Authorize(ctx, newProjectID, s.rules.Create, projectNotFound(newProjectID))
+		if err != nil {
+			return *new(T), err
+		}
+	}
+
+	return s.service.Update(ctx, id, resource)
+}
+
+func (s *PolicyService[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	projectID, err := s.projectOf(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.policy.Authorize(ctx, projectID, s.rules.Delete, notFound(id))
+	if err != nil {
+		return err
+	}
+
+	return s.service.Delete(ctx, id)
+}
+
+// References are checked by the wrapped service, if it supports it.
+func (s *PolicyService[T]) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	checker, ok := s.service.(krest.ReferenceChecker)
+	if !ok {
+		return false, fmt.Errorf("service does not support reference checks")
+	}
+	return checker.ReferenceExists(ctx, table, id)
+}
+
+// Returns the project of a stored resource.
+func (s *PolicyService[T]) projectOf(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	if _, ok := auth.UserFromContext(ctx); !ok {
+		return uuid.Nil, auth.ErrUnauthenticated
+	}
+
+	// Only the project field is needed, it might not be selected by default.
+	resource, err := s.service.Get(ctx, id, krest.ResourceQuery{Expand: []string{s.rules.ProjectField}})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return s.rules.Project(resource), nil
+}
+
+func notFound(id uuid.UUID) error {
+	return krest.NewError(http.StatusNotFound, "resource %s not found", id)
+}
+
+func projectNotFound(id uuid.UUID) error {
+	return krest.NewError(http.StatusNotFound, "project %s not found", id)
+}
@@ -0,0 +1,114 @@
+package authz_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type fixture struct {
+	projects *authz.ProjectService
+	tasks    *authz.PolicyService[models.Task]
+	policy   *authz.Policy
+	ctx      context.Context
+	alice    context.Context
+	bob      context.Context
+	bobID    uuid.UUID
+}
+
+func setup(t *testing.T) fixture {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	policy := authz.NewPolicy(membershipRepository)
+
+	// Everything happens within one organization.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	alice, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	bob, err := userRepository.Create(ctx, models.User{Name: "Bob", Username: "bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return fixture{
+		projects: authz.NewProjectService(krest_orm.NewGenericService(projectRepository), policy, projectRepository),
+		tasks:    authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules),
+		policy:   policy,
+		ctx:      ctx,
+		alice:    auth.WithUser(ctx, alice),
+		bob:      auth.WithUser(ctx, bob),
+		bobID:    bob.UUID,
+	}
+}
+
+func TestPolicyScopesCollections(t *testing.T) {
+	f := setup(t)
+
+	project, err := f.projects.Create(f.alice, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	task, err := f.tasks.Create(f.alice, models.Task{Summary: "Task", ProjectID: project.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Bob is not a member, so the task doesn't exist for him.
+	tasks, err := f.tasks.List(f.bob, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("List returned tasks of another project: got %+v", tasks)
+	}
+
+	_, err = f.tasks.Get(f.bob, task.UUID, krest.ResourceQuery{})
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a task in another project, got %v", err)
+	}
+
+	// As a viewer, Bob can see the task, but not change it.
+	err = f.policy.AddMember(f.ctx, project.UUID, f.bobID, authz.RoleViewer)
+	if err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	tasks, err = f.tasks.List(f.bob, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("List returned incorrect number of tasks: got %d, want 1", len(tasks))
+	}
+
+	err = f.tasks.Delete(f.bob, task.UUID)
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer deleting a task, got %v", err)
+	}
+
+	// Unauthenticated callers can't do anything.
+	_, err = f.tasks.List(f.ctx, krest.CollectionQuery{})
+	if err != auth.ErrUnauthenticated {
+		t.Errorf("expected unauthenticated error, got %v", err)
+	}
+}
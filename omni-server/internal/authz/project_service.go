@@ -0,0 +1,53 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// ProjectService authorizes project operations.
+// Any authenticated user can create a project, and becomes its admin in the transaction of the create.
+// Implements krest.Service[models.Project]
+type ProjectService struct {
+	*PolicyService[models.Project]
+	transactions krest_orm.Transactor
+}
+
+// The transactor must run transactions of the database of the projects and memberships, e.g. the project repository.
+func NewProjectService(service krest.Service[models.Project], policy *Policy, transactions krest_orm.Transactor) *ProjectService {
+	return &ProjectService{NewPolicyService(service, policy, Rules[models.Project]{
+		Project:      func(project models.Project) uuid.UUID { return project.UUID },
+		ProjectField: "uuid",
+		Read:         RoleViewer,
+		Update:       RoleAdmin,
+		Delete:       RoleAdmin,
+	}), transactions}
+}
+
+func (s *ProjectService) Create(ctx context.Context, project models.Project) (models.Project, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Project{}, auth.ErrUnauthenticated
+	}
+
+	var createdProject models.Project
+	err := s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		createdProject, err = s.service.Create(ctx, project)
+		if err != nil {
+			return err
+		}
+
+		return s.policy.AddMember(ctx, createdProject.UUID, user.UUID, RoleAdmin)
+	})
+	if err != nil {
+		return models.Project{}, err
+	}
+
+	return createdProject, nil
+}
@@ -0,0 +1,225 @@
+package comment
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// CommentHandler implements the http api for comments, nested under their task. [/v1/tasks/{uuid}/comments]
+type CommentHandler struct {
+	service *CommentService
+}
+
+func NewCommentHandler(service *CommentService) *CommentHandler {
+	return &CommentHandler{service: service}
+}
+
+// Returns the task of the url, and the comment if the url has one. Writes an error response if they're invalid.
+func parseParams(w http.ResponseWriter, r *http.Request, withComment bool) (uuid.UUID, uuid.UUID, bool) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if !withComment {
+		return taskID, uuid.Nil, true
+	}
+
+	commentID, err := uuid.Parse(chi.URLParam(r, "comment"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return taskID, commentID, true
+}
+
+// List lists the comments of a task. [GET /v1/tasks/{uuid}/comments]
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Comment](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the comments
+	comments, err := h.service.List(r.Context(), taskID, query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, comments, len(comments), len(comments), query, metaQuery)
+}
+
+// Get returns a comment of a task. [GET /v1/tasks/{uuid}/comments/{comment}]
+func (h *CommentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	taskID, commentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Get the comment
+	comment, err := h.service.Get(r.Context(), taskID, commentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, comment, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Create adds a comment to a task. [POST /v1/tasks/{uuid}/comments]
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Parse the request body.
+	var comment models.Comment
+	err := krest.DecodeRequestBody(w, r, &comment, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	krest.ClearReadOnlyFields(&comment)
+
+	// Validate the comment.
+	err = krest.ValidateResource(r.Context(), comment, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Create the comment.
+	createdComment, err := h.service.Create(r.Context(), taskID, comment)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusCreated, createdComment, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Update edits a comment. [PATCH /v1/tasks/{uuid}/comments/{comment}]
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	taskID, commentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Get the current comment, the request body is applied on top of it.
+	comment, err := h.service.Get(r.Context(), taskID, commentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	current := comment
+
+	// Parse the request body.
+	err = krest.DecodeRequestBody(w, r, &comment, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields can't be changed.
+	krest.CopyReadOnlyFields(&comment, current)
+
+	// Validate the comment.
+	err = krest.ValidateResource(r.Context(), comment, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Update the comment.
+	updatedComment, err := h.service.Update(r.Context(), taskID, commentID, comment)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusOK, updatedComment, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Delete deletes a comment. [DELETE /v1/tasks/{uuid}/comments/{comment}]
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	taskID, commentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Delete the comment.
+	err := h.service.Delete(r.Context(), taskID, commentID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Revisions lists the edit history of a comment. [GET /v1/tasks/{uuid}/comments/{comment}/revisions]
+func (h *CommentHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	taskID, commentID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the revisions
+	revisions, err := h.service.Revisions(r.Context(), taskID, commentID, query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, revisions, len(revisions), len(revisions), query, metaQuery)
+}
@@ -0,0 +1,257 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var ErrNotAuthor = krest.NewError(http.StatusForbidden, "only the author or a project admin can change a comment")
+
+// CommentService manages the comments of tasks.
+// Comments are visible to everyone who can see the task, members of the project can comment.
+// Only the author or a project admin can edit or delete a comment. Changes are audited.
+type CommentService struct {
+	comments     krest.Repository[models.Comment]
+	revisions    krest_orm.RevisionRepository[models.Comment]
+	transactions krest_orm.Transactor
+	tasks        krest.Service[models.Task]
+	policy       *authz.Policy
+	auditLog     *audit.Log
+	hooks        []Hook
+}
+
+// Hook is called after a comment is created, with the task of the comment.
+// The comment is already stored, errors are logged rather than failing its creation.
+type Hook func(ctx context.Context, task models.Task, comment models.Comment) error
+
+// The task service must authorize reading tasks, comments are only visible through their task.
+func NewCommentService(comments *krest_orm.GenericPostgresRepository[models.Comment], tasks krest.Service[models.Task], policy *authz.Policy, auditLog *audit.Log) *CommentService {
+	return &CommentService{comments: comments, revisions: comments, transactions: comments, tasks: tasks, policy: policy, auditLog: auditLog}
+}
+
+// Returns a visible task, with its project.
+func (s *CommentService) task(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	return s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+}
+
+// OnCreate adds a hook called after comments are created, call it before serving requests.
+func (s *CommentService) OnCreate(hook Hook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// List lists the comments of a task, oldest first unless sorted otherwise.
+func (s *CommentService) List(ctx context.Context, taskID uuid.UUID, query krest.CollectionQuery) ([]models.Comment, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Filters = append(query.Filters, krest.Filter{Field: "task_id", Operator: krest.FilterEqual, Value: taskID})
+	if len(query.Sort) == 0 {
+		query.Sort = []krest.Sort{{Field: "created_at"}}
+	}
+	return s.comments.List(ctx, query)
+}
+
+func (s *CommentService) Get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Comment, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return s.get(ctx, taskID, id)
+}
+
+// Returns a comment of a task, without authorizing.
+func (s *CommentService) get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Comment, error) {
+	comment, err := s.comments.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	if comment.TaskID != taskID {
+		return models.Comment{}, krest.NewError(http.StatusNotFound, "comments %s not found", id)
+	}
+
+	return comment, nil
+}
+
+// Create adds a comment to a task, written by the authenticated user.
+func (s *CommentService) Create(ctx context.Context, taskID uuid.UUID, comment models.Comment) (models.Comment, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Comment{}, auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	comment.TaskID = task.UUID
+	comment.AuthorID = &user.UUID
+	comment.CreatedAt = time.Now().UTC()
+	comment.EditedAt = nil
+	var created models.Comment
+	err = s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		created, err = s.comments.Create(ctx, comment)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionCreate, task, created.UUID, nil, created)
+	})
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	for _, hook := range s.hooks {
+		err = hook(ctx, task, created)
+		if err != nil {
+			log.Printf("Failed to run a hook for comment %s: %v", created.UUID, err)
+		}
+	}
+
+	return created, nil
+}
+
+// Update edits the body of a comment, the previous body is kept as a revision.
+func (s *CommentService) Update(ctx context.Context, taskID uuid.UUID, id uuid.UUID, comment models.Comment) (models.Comment, error) {
+	task, current, err := s.authorizeChange(ctx, taskID, id)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	// Only the body can change.
+	edited := current
+	edited.Body = comment.Body
+	editedAt := time.Now().UTC()
+	edited.EditedAt = &editedAt
+	var updated models.Comment
+	err = s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		updated, err = s.comments.Update(ctx, id, edited)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionUpdate, task, id, current, updated)
+	})
+	if err != nil {
+		return models.Comment{}, err
+	}
+	return updated, nil
+}
+
+func (s *CommentService) Delete(ctx context.Context, taskID uuid.UUID, id uuid.UUID) error {
+	task, current, err := s.authorizeChange(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		err := s.comments.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionDelete, task, id, current, nil)
+	})
+}
+
+// Records a change of a comment in the audit log, pass nil as before or after for created and deleted comments.
+func (s *CommentService) record(ctx context.Context, action audit.Action, task models.Task, id uuid.UUID, before interface{}, after interface{}) error {
+	err := s.auditLog.Record(ctx, action, "comments", id, task.ProjectID, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+// Revisions lists the edit history of a comment.
+func (s *CommentService) Revisions(ctx context.Context, taskID uuid.UUID, id uuid.UUID, query krest.CollectionQuery) ([]krest_orm.Revision[models.Comment], error) {
+	_, err := s.Get(ctx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revisions.ListRevisions(ctx, id, query)
+}
+
+// Returns the task and the comment, if the authenticated user is its author or an admin of the project.
+func (s *CommentService) authorizeChange(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Task, models.Comment, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Task{}, models.Comment{}, auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Task{}, models.Comment{}, err
+	}
+
+	comment, err := s.get(ctx, taskID, id)
+	if err != nil {
+		return models.Task{}, models.Comment{}, err
+	}
+
+	if comment.AuthorID != nil && *comment.AuthorID == user.UUID {
+		return task, comment, nil
+	}
+
+	role, err := s.policy.Role(ctx, task.ProjectID, user.UUID)
+	if err != nil {
+		return models.Task{}, models.Comment{}, err
+	}
+	if !role.Includes(authz.RoleAdmin) {
+		return models.Task{}, models.Comment{}, ErrNotAuthor
+	}
+
+	return task, comment, nil
+}
+
+// TaskRelation loads the comments of tasks, when expanded with ?expand=comments.
+func TaskRelation(comments krest.Repository[models.Comment]) krest_orm.Relation[models.Task] {
+	return krest_orm.Relation[models.Task]{
+		Field: "comments",
+		Load: func(ctx context.Context, tasks []models.Task) error {
+			taskIDs := []uuid.UUID{}
+			for _, task := range tasks {
+				taskIDs = append(taskIDs, task.UUID)
+			}
+
+			taskComments, err := comments.List(ctx, krest.CollectionQuery{
+				Filters: []krest.Filter{{Field: "task_id", Operator: krest.FilterIn, Value: taskIDs}},
+				Sort:    []krest.Sort{{Field: "created_at"}},
+			})
+			if err != nil {
+				return err
+			}
+
+			byTask := map[uuid.UUID][]models.Comment{}
+			for _, comment := range taskComments {
+				byTask[comment.TaskID] = append(byTask[comment.TaskID], comment)
+			}
+			for i := range tasks {
+				tasks[i].Comments = byTask[tasks[i].UUID]
+				if tasks[i].Comments == nil {
+					tasks[i].Comments = []models.Comment{}
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,123 @@
+package comment_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCommentPermissions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	commentRepository := krest_orm.NewGenericPostgresRepository[models.Comment](db)
+	policy := authz.NewPolicy(membershipRepository)
+	tasks := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	service := comment.NewCommentService(commentRepository, tasks, policy, auditLog)
+
+	// Alice is an admin of the project, Bob and Carol are members.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	users := map[string]context.Context{}
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for name, role := range map[string]authz.Role{"alice": authz.RoleAdmin, "bob": authz.RoleMember, "carol": authz.RoleMember} {
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		err = policy.AddMember(ctx, project.UUID, user.UUID, role)
+		if err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+		users[name] = auth.WithUser(ctx, user)
+	}
+
+	task, err := tasks.Create(users["alice"], models.Task{Summary: "Task", ProjectID: project.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	created, err := service.Create(users["bob"], task.UUID, models.Comment{Body: "First"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Only the author or an admin can edit.
+	_, err = service.Update(users["carol"], task.UUID, created.UUID, models.Comment{Body: "Carol was here"})
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for editing a comment of another member, got %v", err)
+	}
+
+	updated, err := service.Update(users["bob"], task.UUID, created.UUID, models.Comment{Body: "Second"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Body != "Second" || updated.EditedAt == nil || updated.AuthorID == nil || *updated.AuthorID != *created.AuthorID {
+		t.Errorf("Update returned incorrect data: got %+v", updated)
+	}
+
+	revisions, err := service.Revisions(users["carol"], task.UUID, created.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	if len(revisions) != 2 || revisions[1].Changes["body"].Before != "First" {
+		t.Errorf("Revisions returned incorrect edit history: got %+v", revisions)
+	}
+
+	err = service.Delete(users["alice"], task.UUID, created.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	comments, err := service.List(users["bob"], task.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(comments) != 0 {
+		t.Errorf("List returned deleted comments: got %+v", comments)
+	}
+
+	// Creating, editing and deleting comments is audited.
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: created.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 3 || events[0].ResourceType != "comments" || events[0].ProjectID != project.UUID || events[1].Changes["body"].After != "Second" || events[2].Action != string(audit.ActionDelete) {
+		t.Errorf("expected the comment to be audited when created, edited and deleted, got %+v", events)
+	}
+
+	// Comments of deleted users have no author, only admins can edit them.
+	orphan, err := commentRepository.Create(ctx, models.Comment{TaskID: task.UUID, Body: "Orphan"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = service.Update(users["bob"], task.UUID, orphan.UUID, models.Comment{Body: "Adopted"})
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for editing a comment of a deleted user, got %v", err)
+	}
+	_, err = service.Update(users["alice"], task.UUID, orphan.UUID, models.Comment{Body: "Adopted"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package customfield
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// CustomFieldService wraps the task service, validating the custom field values of tasks against their task type.
+// New tasks, and tasks moving to another type, get the defaults of the fields they don't set.
+// Implements krest.Service[models.Task]
+type CustomFieldService struct {
+	krest.ServiceWrapper[models.Task]
+	types krest.Repository[models.TaskType]
+	users krest.Repository[models.User]
+}
+
+func NewCustomFieldService(service krest.Service[models.Task], types krest.Repository[models.TaskType], users krest.Repository[models.User]) *CustomFieldService {
+	return &CustomFieldService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, types: types, users: users}
+}
+
+// Filters on custom number fields compare numbers, the values of query parameters are strings.
+func (s *CustomFieldService) List(ctx context.Context, query krest.CollectionQuery) ([]models.Task, error) {
+	filters := make([]krest.Filter, len(query.Filters))
+	for i, filter := range query.Filters {
+		key, ok := strings.CutPrefix(filter.Field, "fields.")
+		if !ok {
+			filters[i] = filter
+			continue
+		}
+
+		fieldType, err := s.fieldType(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if fieldType == models.CustomFieldNumber {
+			filter.Value, err = parseNumbers(filter.Field, filter.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		filters[i] = filter
+	}
+	query.Filters = filters
+
+	return s.Service.List(ctx, query)
+}
+
+func (s *CustomFieldService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	taskType, err := s.taskType(ctx, task.TypeID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task.Fields, err = s.resolve(ctx, taskType, task.Fields, true, nil)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+func (s *CustomFieldService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"type_id", "fields"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	taskType, err := s.taskType(ctx, task.TypeID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Values of fields the new type doesn't have are dropped.
+	changedType := !sameType(current.TypeID, task.TypeID)
+	if changedType {
+		task.Fields = onlyFieldsOf(taskType, task.Fields)
+	}
+
+	task.Fields, err = s.resolve(ctx, taskType, task.Fields, changedType, current.Fields)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Update(ctx, id, task)
+}
+
+// Returns the values of a task, normalized and validated against the fields of its type. Null values unset a field.
+// With defaults, fields without a value get their default. Without, the values change the stored values of the task:
+// only changed values are validated, unchanged values the fields of the type don't accept anymore are dropped,
+// so changing a task type doesn't fail the updates of its tasks.
+func (s *CustomFieldService) resolve(ctx context.Context, taskType *models.TaskType, values models.CustomFieldValues, defaults bool, stored models.CustomFieldValues) (models.CustomFieldValues, error) {
+	resolved := models.CustomFieldValues{}
+	for key, value := range values {
+		if value != nil {
+			resolved[key] = value
+		}
+	}
+
+	var fields models.CustomFields
+	if taskType != nil {
+		fields = taskType.Fields
+	}
+
+	fieldErrors := []krest.FieldError{}
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.Key] = true
+		path := "fields." + field.Key
+
+		value, ok := resolved[field.Key]
+		if !ok && defaults && field.Default != nil {
+			value = field.Default
+		}
+		unchanged := !defaults && sameValue(stored[field.Key], value)
+
+		if isEmpty(value) {
+			delete(resolved, field.Key)
+			if field.Required && !unchanged {
+				fieldErrors = append(fieldErrors, krest.FieldError{Field: path, Rule: "required", Message: "is required"})
+			}
+			continue
+		}
+
+		normalized, message := checkValue(field, value)
+		if message != "" && unchanged {
+			delete(resolved, field.Key)
+			continue
+		}
+		if message != "" {
+			fieldErrors = append(fieldErrors, krest.FieldError{Field: path, Rule: field.Type, Message: message})
+			continue
+		}
+
+		// User fields must reference a user of the organization.
+		if field.Type == models.CustomFieldUser && !unchanged {
+			message, err := s.checkUser(ctx, normalized.(string))
+			if err != nil {
+				return nil, err
+			}
+			if message != "" {
+				fieldErrors = append(fieldErrors, krest.FieldError{Field: path, Rule: "ref", Message: message})
+				continue
+			}
+		}
+
+		resolved[field.Key] = normalized
+	}
+
+	for key, value := range resolved {
+		if known[key] {
+			continue
+		}
+		if !defaults && sameValue(stored[key], value) {
+			delete(resolved, key)
+			continue
+		}
+		fieldErrors = append(fieldErrors, krest.FieldError{Field: "fields." + key, Rule: "field", Message: "is not a field of the task type"})
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, &krest.ValidationError{Errors: fieldErrors}
+	}
+
+	return resolved, nil
+}
+
+// Returns whether a value is the stored value, both empty if the field has no value.
+func sameValue(stored interface{}, value interface{}) bool {
+	if isEmpty(stored) || isEmpty(value) {
+		return isEmpty(stored) && isEmpty(value)
+	}
+	return reflect.DeepEqual(stored, value)
+}
+
+// Returns the values of the fields of a task type.
+func onlyFieldsOf(taskType *models.TaskType, values models.CustomFieldValues) models.CustomFieldValues {
+	kept := models.CustomFieldValues{}
+	if taskType == nil {
+		return kept
+	}
+
+	for _, field := range taskType.Fields {
+		if value, ok := values[field.Key]; ok {
+			kept[field.Key] = value
+		}
+	}
+	return kept
+}
+
+// Returns a message if the user doesn't exist.
+func (s *CustomFieldService) checkUser(ctx context.Context, id string) (string, error) {
+	_, err := s.users.Get(ctx, uuid.MustParse(id), krest.ResourceQuery{})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return fmt.Sprintf("references a nonexistent resource: %s", id), nil
+	}
+	return "", err
+}
+
+// Returns the type of a task, nil for tasks without a type.
+func (s *CustomFieldService) taskType(ctx context.Context, id *uuid.UUID) (*models.TaskType, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	taskType, err := s.types.Get(ctx, *id, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+	return &taskType, nil
+}
+
+// Returns the type of the custom fields with a key, or an error if no task type has the field.
+// Fields of different task types with the same key are only compared as numbers if they all are.
+func (s *CustomFieldService) fieldType(ctx context.Context, key string) (string, error) {
+	taskTypes, err := s.types.List(ctx, krest.CollectionQuery{})
+	if err != nil {
+		return "", err
+	}
+
+	fieldType := ""
+	for _, taskType := range taskTypes {
+		for _, field := range taskType.Fields {
+			if field.Key != key {
+				continue
+			}
+			if fieldType != "" && fieldType != field.Type {
+				return models.CustomFieldText, nil
+			}
+			fieldType = field.Type
+		}
+	}
+
+	if fieldType == "" {
+		return "", krest.NewError(http.StatusBadRequest, "unknown field: fields.%s", key)
+	}
+	return fieldType, nil
+}
+
+// Parses the string values of a filter as numbers.
+func parseNumbers(field string, value interface{}) (interface{}, error) {
+	parse := func(text string) (float64, error) {
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, krest.NewError(http.StatusBadRequest, "invalid value for %s, expected a number: %s", field, text)
+		}
+		return number, nil
+	}
+
+	switch value := value.(type) {
+	case string:
+		return parse(value)
+	case []string:
+		numbers := []float64{}
+		for _, text := range value {
+			number, err := parse(text)
+			if err != nil {
+				return nil, err
+			}
+			numbers = append(numbers, number)
+		}
+		return numbers, nil
+	}
+	return value, nil
+}
+
+func sameType(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,165 @@
+package customfield_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/customfield"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Returns the fields of a validation error.
+func invalidFields(err error) []string {
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		return nil
+	}
+
+	fields := []string{}
+	for _, fieldError := range validationError.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	return fields
+}
+
+func TestCustomFields(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	taskTypeRepository := krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	types := customfield.NewTaskTypeService(krest_orm.NewGenericService(taskTypeRepository))
+	tasks := customfield.NewCustomFieldService(krest_orm.NewGenericService(taskRepository), taskTypeRepository, userRepository)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	user, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Select fields need options, and defaults must be valid.
+	_, err = types.Create(ctx, models.TaskType{ProjectID: project.UUID, Name: "Bug", Fields: models.CustomFields{
+		{Key: "severity", Name: "Severity", Type: models.CustomFieldSelect},
+		{Key: "points", Name: "Points", Type: models.CustomFieldNumber, Default: "three"},
+	}})
+	if fields := invalidFields(err); len(fields) != 2 {
+		t.Errorf("expected errors for the options and default, got %v", err)
+	}
+
+	bug, err := types.Create(ctx, models.TaskType{ProjectID: project.UUID, Name: "Bug", Fields: models.CustomFields{
+		{Key: "severity", Name: "Severity", Type: models.CustomFieldSelect, Options: []string{"low", "high"}, Default: "low"},
+		{Key: "points", Name: "Points", Type: models.CustomFieldNumber, Required: true},
+		{Key: "due", Name: "Due", Type: models.CustomFieldDate},
+		{Key: "platforms", Name: "Platforms", Type: models.CustomFieldMultiSelect, Options: []string{"linux", "macos", "windows"}},
+		{Key: "reviewer", Name: "Reviewer", Type: models.CustomFieldUser},
+	}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Values must match the fields of the type.
+	_, err = tasks.Create(ctx, models.Task{Summary: "Crash", ProjectID: project.UUID, TypeID: &bug.UUID, Fields: models.CustomFieldValues{
+		"severity": "critical",
+		"due":      "tomorrow",
+		"reviewer": uuid.New().String(),
+		"color":    "red",
+	}})
+	if fields := invalidFields(err); len(fields) != 5 {
+		t.Errorf("expected errors for severity, points, due, reviewer and color, got %v", err)
+	}
+
+	// Fields without a value get their default.
+	crash, err := tasks.Create(ctx, models.Task{Summary: "Crash", ProjectID: project.UUID, TypeID: &bug.UUID, Fields: models.CustomFieldValues{
+		"points":    float64(8),
+		"platforms": []interface{}{"linux", "linux", "windows"},
+		"reviewer":  user.UUID.String(),
+	}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if crash.Fields["severity"] != "low" {
+		t.Errorf("expected the default severity, got %v", crash.Fields["severity"])
+	}
+
+	_, err = tasks.Create(ctx, models.Task{Summary: "Typo", ProjectID: project.UUID, TypeID: &bug.UUID, Fields: models.CustomFieldValues{
+		"points":   float64(1),
+		"severity": "high",
+	}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Custom fields can be filtered and sorted by, numbers compare as numbers.
+	found, err := tasks.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "fields.points", Operator: krest.FilterGreaterOrEqual, Value: "2"}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(found) != 1 || found[0].UUID != crash.UUID {
+		t.Errorf("expected only the task with 8 points, got %+v", found)
+	}
+
+	found, err = tasks.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "fields.platforms", Operator: krest.FilterContains, Value: "windows"}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(found) != 1 || found[0].UUID != crash.UUID {
+		t.Errorf("expected only the task on windows, got %+v", found)
+	}
+
+	found, err = tasks.List(ctx, krest.CollectionQuery{Sort: []krest.Sort{{Field: "fields.points", Descending: true}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(found) != 2 || found[0].UUID != crash.UUID {
+		t.Errorf("expected the task with the most points first, got %+v", found)
+	}
+
+	_, err = tasks.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "fields.color", Operator: krest.FilterEqual, Value: "red"}}})
+	if krestError := (*krest.Error)(nil); !errors.As(err, &krestError) || krestError.Status != 400 {
+		t.Errorf("expected 400 for filtering by an unknown field, got %v", err)
+	}
+
+	// Changing the fields of a type doesn't fail updates of its tasks, values the fields don't accept anymore are dropped.
+	bug.Fields = models.CustomFields{
+		{Key: "severity", Name: "Severity", Type: models.CustomFieldNumber},
+		{Key: "points", Name: "Points", Type: models.CustomFieldNumber, Required: true},
+		{Key: "platforms", Name: "Platforms", Type: models.CustomFieldMultiSelect, Options: []string{"linux", "macos", "windows"}},
+		{Key: "sla", Name: "SLA", Type: models.CustomFieldText, Required: true},
+	}
+	_, err = types.Update(ctx, bug.UUID, bug)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	crash.Summary = "Crash on start"
+	crash, err = tasks.Update(ctx, crash.UUID, crash)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, ok := crash.Fields["severity"]; ok || crash.Fields["reviewer"] != nil || crash.Fields["points"] != float64(8) {
+		t.Errorf("expected the values of removed and changed fields to be dropped, got %v", crash.Fields)
+	}
+
+	// Values that change are still validated.
+	crash.Fields["severity"] = "high"
+	_, err = tasks.Update(ctx, crash.UUID, crash)
+	if fields := invalidFields(err); len(fields) != 1 || fields[0] != "fields.severity" {
+		t.Errorf("expected an error for the changed severity only, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package customfield
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var types = []string{
+	models.CustomFieldText,
+	models.CustomFieldNumber,
+	models.CustomFieldDate,
+	models.CustomFieldSelect,
+	models.CustomFieldMultiSelect,
+	models.CustomFieldUser,
+}
+
+// Returns the errors of the custom field definitions of a task type.
+func validateDefinitions(fields models.CustomFields) []krest.FieldError {
+	fieldErrors := []krest.FieldError{}
+	invalid := func(path string, rule string, message string) {
+		fieldErrors = append(fieldErrors, krest.FieldError{Field: path, Rule: rule, Message: message})
+	}
+
+	keys := map[string]bool{}
+	for i, field := range fields {
+		path := fmt.Sprintf("fields[%d]", i)
+
+		if !keyPattern.MatchString(field.Key) || len(field.Key) > 64 {
+			invalid(path+".key", "pattern", "must be at most 64 lowercase letters, digits and underscores, starting with a letter")
+		} else if keys[field.Key] {
+			invalid(path+".key", "unique", "must be unique within the task type")
+		}
+		keys[field.Key] = true
+
+		if field.Name == "" {
+			invalid(path+".name", "required", "is required")
+		}
+
+		if !slices.Contains(types, field.Type) {
+			invalid(path+".type", "oneof", fmt.Sprintf("must be one of %s", strings.Join(types, ", ")))
+			continue
+		}
+
+		hasOptions := field.Type == models.CustomFieldSelect || field.Type == models.CustomFieldMultiSelect
+		if hasOptions && len(field.Options) == 0 {
+			invalid(path+".options", "required", "is required for select fields")
+		}
+		if !hasOptions && len(field.Options) > 0 {
+			invalid(path+".options", "options", "are only supported for select fields")
+		}
+
+		if field.Default == nil {
+			continue
+		}
+		if field.Type == models.CustomFieldUser {
+			invalid(path+".default", "default", "is not supported for user fields")
+			continue
+		}
+		if _, message := checkValue(field, field.Default); message != "" {
+			invalid(path+".default", field.Type, message)
+		}
+	}
+
+	return fieldErrors
+}
+
+// Returns the normalized value of a custom field, or a message describing why the value is invalid.
+// Values are decoded from JSON, so numbers are float64 and arrays []interface{}.
+func checkValue(field models.CustomField, value interface{}) (interface{}, string) {
+	switch field.Type {
+	case models.CustomFieldText:
+		if text, ok := value.(string); ok {
+			return text, ""
+		}
+		return nil, "must be a string"
+	case models.CustomFieldNumber:
+		switch number := value.(type) {
+		case float64:
+			return number, ""
+		case int:
+			return float64(number), ""
+		}
+		return nil, "must be a number"
+	case models.CustomFieldDate:
+		text, ok := value.(string)
+		if _, err := time.Parse(time.DateOnly, text); !ok || err != nil {
+			return nil, "must be a date (YYYY-MM-DD)"
+		}
+		return text, ""
+	case models.CustomFieldSelect:
+		option, ok := value.(string)
+		if !ok || !slices.Contains(field.Options, option) {
+			return nil, fmt.Sprintf("must be one of %s", strings.Join(field.Options, ", "))
+		}
+		return option, ""
+	case models.CustomFieldMultiSelect:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, "must be a list of options"
+		}
+		options := []string{}
+		for _, item := range items {
+			option, ok := item.(string)
+			if !ok || !slices.Contains(field.Options, option) {
+				return nil, fmt.Sprintf("must only contain %s", strings.Join(field.Options, ", "))
+			}
+			if !slices.Contains(options, option) {
+				options = append(options, option)
+			}
+		}
+		return options, ""
+	case models.CustomFieldUser:
+		text, _ := value.(string)
+		id, err := uuid.Parse(text)
+		if err != nil {
+			return nil, "must be the uuid of a user"
+		}
+		return id.String(), ""
+	}
+
+	return nil, fmt.Sprintf("has an unknown type: %s", field.Type)
+}
+
+// Empty values count as not set.
+func isEmpty(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case []string:
+		return len(value) == 0
+	}
+	return false
+}
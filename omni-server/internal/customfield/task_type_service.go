@@ -0,0 +1,35 @@
+package customfield
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskTypeService wraps the task type service, validating the custom field definitions of task types.
+// Implements krest.Service[models.TaskType]
+type TaskTypeService struct {
+	krest.ServiceWrapper[models.TaskType]
+}
+
+func NewTaskTypeService(service krest.Service[models.TaskType]) *TaskTypeService {
+	return &TaskTypeService{ServiceWrapper: krest.ServiceWrapper[models.TaskType]{Service: service}}
+}
+
+func (s *TaskTypeService) Create(ctx context.Context, taskType models.TaskType) (models.TaskType, error) {
+	if fieldErrors := validateDefinitions(taskType.Fields); len(fieldErrors) > 0 {
+		return models.TaskType{}, &krest.ValidationError{Errors: fieldErrors}
+	}
+
+	return s.Service.Create(ctx, taskType)
+}
+
+func (s *TaskTypeService) Update(ctx context.Context, id uuid.UUID, taskType models.TaskType) (models.TaskType, error) {
+	if fieldErrors := validateDefinitions(taskType.Fields); len(fieldErrors) > 0 {
+		return models.TaskType{}, &krest.ValidationError{Errors: fieldErrors}
+	}
+
+	return s.Service.Update(ctx, id, taskType)
+}
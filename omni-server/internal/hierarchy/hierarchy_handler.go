@@ -0,0 +1,88 @@
+package hierarchy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// HierarchyHandler lists the sub-tasks of tasks. [GET /v1/tasks/{uuid}/children, GET /v1/tasks/{uuid}/descendants]
+// Sub-tasks are listed like tasks, with the same query parameters.
+type HierarchyHandler struct {
+	tasks     krest.Service[models.Task]
+	hierarchy *HierarchyRepository
+}
+
+func NewHierarchyHandler(tasks krest.Service[models.Task], hierarchy *HierarchyRepository) *HierarchyHandler {
+	return &HierarchyHandler{tasks: tasks, hierarchy: hierarchy}
+}
+
+// Children lists the direct sub-tasks of a task.
+func (h *HierarchyHandler) Children(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, func(ctx context.Context, id uuid.UUID) (krest.Filter, error) {
+		return krest.Filter{Field: "parent_id", Operator: krest.FilterEqual, Value: id}, nil
+	})
+}
+
+// Descendants lists the sub-tasks of a task at any depth.
+func (h *HierarchyHandler) Descendants(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, func(ctx context.Context, id uuid.UUID) (krest.Filter, error) {
+		return h.hierarchy.DescendantsFilter(id), nil
+	})
+}
+
+// Lists the tasks matching the filter for the task of the url.
+func (h *HierarchyHandler) list(w http.ResponseWriter, r *http.Request, filter func(ctx context.Context, id uuid.UUID) (krest.Filter, error)) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Task](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// The task must be visible.
+	_, err = h.tasks.Get(r.Context(), id, krest.ResourceQuery{})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the tasks
+	subTasks, err := filter(r.Context(), id)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	query.Filters = append(query.Filters, subTasks)
+	tasks, err := h.tasks.List(r.Context(), query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, tasks, len(tasks), len(tasks), query, metaQuery)
+}
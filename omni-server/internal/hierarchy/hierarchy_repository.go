@@ -0,0 +1,113 @@
+package hierarchy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// HierarchyRepository queries the task hierarchy using recursive common table expressions,
+// which Postgres and SQLite both support. The recursion uses UNION rather than UNION ALL,
+// so it ends even if the hierarchy has a cycle.
+type HierarchyRepository struct {
+	db *sql.DB
+}
+
+// NewHierarchyRepository queries the tasks table, create it first.
+func NewHierarchyRepository(db *sql.DB) *HierarchyRepository {
+	return &HierarchyRepository{db: db}
+}
+
+// The descendants of the task of the placeholder, its children, their children, and so on.
+const descendantsQuery = `
+	WITH RECURSIVE descendants(uuid) AS (
+		SELECT uuid FROM tasks WHERE parent_id = %s
+		UNION
+		SELECT tasks.uuid FROM tasks JOIN descendants ON tasks.parent_id = descendants.uuid
+	)
+	SELECT uuid FROM descendants`
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *HierarchyRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// DescendantsFilter filters tasks to the descendants of a task.
+func (r *HierarchyRepository) DescendantsFilter(id uuid.UUID) krest.Filter {
+	return krest_orm.ConditionFilter(func(table string, argIdx int) (string, []interface{}) {
+		return fmt.Sprintf("%s.uuid IN (%s)", table, fmt.Sprintf(descendantsQuery, fmt.Sprintf("$%d", argIdx))), []interface{}{id}
+	})
+}
+
+// IsDescendant returns whether a task is a descendant of another.
+func (r *HierarchyRepository) IsDescendant(ctx context.Context, id uuid.UUID, ancestorID uuid.UUID) (bool, error) {
+	// Only the descendants of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "tasks", 3)
+	if err != nil {
+		return false, err
+	}
+
+	var found bool
+	err = krest_orm.Conn(ctx, r.db).QueryRowContext(ctx,
+		fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM (%s) descendants JOIN tasks ON tasks.uuid = descendants.uuid WHERE descendants.uuid = $2 AND %s)",
+			fmt.Sprintf(descendantsQuery, "$1"), tenant),
+		append([]interface{}{ancestorID, id}, tenantArgs...)...,
+	).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("failed to query descendants: %v", err)
+	}
+	return found, nil
+}
+
+// Progress returns the progress of the descendants of each of the tasks, tasks without descendants are left out.
+func (r *HierarchyRepository) Progress(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Progress, error) {
+	progress := map[uuid.UUID]models.Progress{}
+	if len(ids) == 0 {
+		return progress, nil
+	}
+
+	placeholders, args := []string{}, []interface{}{}
+	for i, id := range ids {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, id)
+	}
+
+	// Only the descendants of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "tasks", len(args)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := krest_orm.Conn(ctx, r.db).QueryContext(ctx, fmt.Sprintf(`
+		WITH RECURSIVE descendants(root, uuid, completed) AS (
+			SELECT parent_id, uuid, CASE WHEN completed_at IS NULL THEN 0 ELSE 1 END FROM tasks WHERE parent_id IN (%[1]s) AND %[2]s
+			UNION
+			SELECT descendants.root, tasks.uuid, CASE WHEN tasks.completed_at IS NULL THEN 0 ELSE 1 END FROM tasks JOIN descendants ON tasks.parent_id = descendants.uuid WHERE %[2]s
+		)
+		SELECT root, COUNT(*), SUM(completed) FROM descendants GROUP BY root`,
+		strings.Join(placeholders, ", "), tenant),
+		append(args, tenantArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query progress: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var root uuid.UUID
+		var total, completed int
+		err = rows.Scan(&root, &total, &completed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query progress: %v", err)
+		}
+		progress[root] = models.Progress{Total: total, Completed: completed, Percent: completed * 100 / total}
+	}
+
+	return progress, rows.Err()
+}
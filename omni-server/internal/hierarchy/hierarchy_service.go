@@ -0,0 +1,182 @@
+package hierarchy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// HierarchyService wraps the task service, keeping the task hierarchy a forest within each project.
+// Parents belong to the project of their children, tasks can't be their own ancestor, and epics have no parent.
+// Deleting a task detaches its children.
+// Implements krest.Service[models.Task]
+type HierarchyService struct {
+	krest.ServiceWrapper[models.Task]
+	hierarchy *HierarchyRepository
+	types     krest.Repository[models.TaskType]
+}
+
+func NewHierarchyService(service krest.Service[models.Task], hierarchy *HierarchyRepository, types krest.Repository[models.TaskType]) *HierarchyService {
+	return &HierarchyService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, hierarchy: hierarchy, types: types}
+}
+
+func (s *HierarchyService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	err := s.validateParent(ctx, uuid.Nil, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+func (s *HierarchyService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"project_id", "parent_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Tasks move to another project without their parent, and their children.
+	if current.ProjectID != task.ProjectID {
+		children, err := s.children(ctx, id, 1)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if len(children) > 0 {
+			return models.Task{}, krest.NewError(http.StatusConflict, "task %s has sub-tasks, move or detach them first", current.Key)
+		}
+
+		if current.ParentID != nil && task.ParentID != nil && *current.ParentID == *task.ParentID {
+			task.ParentID = nil
+		}
+	}
+
+	err = s.validateParent(ctx, id, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Update(ctx, id, task)
+}
+
+func (s *HierarchyService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Not every database enforces foreign keys, detach the children explicitly.
+	// Children are detached through the task service in the transaction of the delete, so the changes are audited.
+	return s.hierarchy.Transaction(ctx, func(ctx context.Context) error {
+		expand, err := krest.ExpandableFieldNames[models.Task]()
+		if err != nil {
+			return err
+		}
+		children, err := s.Service.List(ctx, krest.CollectionQuery{
+			Expand:  expand,
+			Filters: []krest.Filter{{Field: "parent_id", Operator: krest.FilterEqual, Value: id}},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			child.ParentID = nil
+			_, err = s.Service.Update(ctx, child.UUID, child)
+			if err != nil {
+				return err
+			}
+		}
+		return s.Service.Delete(ctx, id)
+	})
+}
+
+// Returns the children of a task, up to the limit if it isn't 0.
+func (s *HierarchyService) children(ctx context.Context, id uuid.UUID, limit int) ([]models.Task, error) {
+	return s.Service.List(ctx, krest.CollectionQuery{
+		Limit:   limit,
+		Filters: []krest.Filter{{Field: "parent_id", Operator: krest.FilterEqual, Value: id}},
+	})
+}
+
+// Returns an error if the parent of a task, the task with the id or a new task for uuid.Nil, breaks the hierarchy.
+func (s *HierarchyService) validateParent(ctx context.Context, id uuid.UUID, task models.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+
+	if *task.ParentID == id {
+		return parentError("cycle", "must be another task")
+	}
+
+	parent, err := s.Service.Get(ctx, *task.ParentID, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return parentError("ref", fmt.Sprintf("references a nonexistent resource: %s", *task.ParentID))
+	}
+	if err != nil {
+		return err
+	}
+	if parent.ProjectID != task.ProjectID {
+		return parentError("project", "must be a task of the same project")
+	}
+
+	if task.TypeID != nil {
+		taskType, err := s.types.Get(ctx, *task.TypeID, krest.ResourceQuery{})
+		if err != nil {
+			return err
+		}
+		if taskType.Epic {
+			return parentError("epic", "epics can't have a parent")
+		}
+	}
+
+	if id == uuid.Nil {
+		return nil
+	}
+
+	cycle, err := s.hierarchy.IsDescendant(ctx, parent.UUID, id)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return parentError("cycle", "must not be a sub-task of the task")
+	}
+
+	return nil
+}
+
+func parentError(rule string, message string) error {
+	return &krest.ValidationError{Errors: []krest.FieldError{{Field: "parent_id", Rule: rule, Message: message}}}
+}
+
+// ParentRelation loads the parent of tasks, when expanded with ?expand=parent.
+func ParentRelation(tasks krest.Repository[models.Task]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("parent", "parent_id", tasks,
+		func(task models.Task) *uuid.UUID { return task.ParentID },
+		func(task *models.Task, parent *models.Task) { task.Parent = parent },
+	)
+}
+
+// ProgressRelation rolls up the progress of the descendants of tasks, when expanded with ?expand=progress.
+func ProgressRelation(hierarchy *HierarchyRepository) krest_orm.Relation[models.Task] {
+	return krest_orm.Relation[models.Task]{
+		Field: "progress",
+		Load: func(ctx context.Context, tasks []models.Task) error {
+			ids := []uuid.UUID{}
+			for _, task := range tasks {
+				ids = append(ids, task.UUID)
+			}
+
+			progress, err := hierarchy.Progress(ctx, ids)
+			if err != nil {
+				return err
+			}
+
+			for i := range tasks {
+				taskProgress := progress[tasks[i].UUID]
+				tasks[i].Progress = &taskProgress
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package hierarchy_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/hierarchy"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHierarchy(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	taskTypeRepository := krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	hierarchyRepository := hierarchy.NewHierarchyRepository(db)
+	tasks := krest_orm.NewRelationService[models.Task](
+		hierarchy.NewHierarchyService(krest_orm.NewGenericService(taskRepository), hierarchyRepository, taskTypeRepository),
+		hierarchy.ProgressRelation(hierarchyRepository),
+	)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other, err := projectRepository.Create(ctx, models.Project{Name: "Web", Key: "WEB"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	epicType, err := taskTypeRepository.Create(ctx, models.TaskType{ProjectID: project.UUID, Name: "Epic", Epic: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	create := func(summary string, parentID *uuid.UUID, completed bool) models.Task {
+		task := models.Task{Summary: summary, ProjectID: project.UUID, ParentID: parentID}
+		if completed {
+			now := time.Now().UTC()
+			task.CompletedAt = &now
+		}
+		created, err := tasks.Create(ctx, task)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return created
+	}
+
+	// An epic with a story, the story with two sub-tasks, one of them completed.
+	epic, err := tasks.Create(ctx, models.Task{Summary: "Epic", ProjectID: project.UUID, TypeID: &epicType.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	story := create("Story", &epic.UUID, false)
+	create("Done", &story.UUID, true)
+	open := create("Open", &story.UUID, false)
+
+	descendants, err := tasks.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{hierarchyRepository.DescendantsFilter(epic.UUID)}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(descendants) != 3 {
+		t.Errorf("expected 3 descendants of the epic, got %d", len(descendants))
+	}
+
+	epic, err = tasks.Get(ctx, epic.UUID, krest.ResourceQuery{Expand: []string{"progress"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if epic.Progress == nil || epic.Progress.Total != 3 || epic.Progress.Completed != 1 || epic.Progress.Percent != 33 {
+		t.Errorf("expected 1 of 3 descendants completed, got %+v", epic.Progress)
+	}
+
+	var validationError *krest.ValidationError
+
+	// Tasks can't become a sub-task of their own sub-tasks.
+	epic, err = tasks.Get(ctx, epic.UUID, krest.ResourceQuery{Expand: []string{"summary", "project_id", "type_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	story.ParentID = &open.UUID
+	_, err = tasks.Update(ctx, story.UUID, story)
+	if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "cycle" {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+
+	// Epics have no parent.
+	epic.ParentID = &story.UUID
+	_, err = tasks.Update(ctx, epic.UUID, epic)
+	if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "epic" {
+		t.Errorf("expected an epic error, got %v", err)
+	}
+
+	// Parents belong to the project of their children.
+	_, err = tasks.Create(ctx, models.Task{Summary: "Elsewhere", ProjectID: other.UUID, ParentID: &epic.UUID})
+	if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "project" {
+		t.Errorf("expected a project error, got %v", err)
+	}
+
+	// Tasks with sub-tasks can't move to another project.
+	story, err = tasks.Get(ctx, story.UUID, krest.ResourceQuery{Expand: []string{"summary", "project_id", "parent_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	story.ProjectID = other.UUID
+	_, err = tasks.Update(ctx, story.UUID, story)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for moving a task with sub-tasks, got %v", err)
+	}
+
+	// Deleting a task detaches its sub-tasks.
+	err = tasks.Delete(ctx, story.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	open, err = tasks.Get(ctx, open.UUID, krest.ResourceQuery{Expand: []string{"parent_id", "progress"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if open.ParentID != nil {
+		t.Errorf("expected the sub-task to be detached, got parent %v", open.ParentID)
+	}
+	revisions, err := taskRepository.ListRevisions(ctx, open.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if change, ok := revisions[len(revisions)-1].Changes["parent_id"]; !ok || change.After != nil {
+		t.Errorf("expected detaching the sub-task to be kept as a revision, got %+v", revisions)
+	}
+	if open.Progress == nil || open.Progress.Total != 0 {
+		t.Errorf("expected no progress for a task without sub-tasks, got %+v", open.Progress)
+	}
+}
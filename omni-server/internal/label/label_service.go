@@ -0,0 +1,159 @@
+package label
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// LabelService wraps the label service, authorizing labels by their project.
+// Labels without a project are visible to, and managed by, everyone in the organization.
+// Project labels are visible to viewers of the project, and managed by its members.
+// Deleting a label removes it from its tasks.
+// Implements krest.Service[models.Label]
+type LabelService struct {
+	krest.ServiceWrapper[models.Label]
+	taskLabels *TaskLabelRepository
+	policy     *authz.Policy
+}
+
+func NewLabelService(service krest.Service[models.Label], taskLabels *TaskLabelRepository, policy *authz.Policy) *LabelService {
+	return &LabelService{ServiceWrapper: krest.ServiceWrapper[models.Label]{Service: service}, taskLabels: taskLabels, policy: policy}
+}
+
+func (s *LabelService) Get(ctx context.Context, id uuid.UUID, query krest.ResourceQuery) (models.Label, error) {
+	label, err := s.Service.Get(ctx, id, query)
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	err = s.authorize(ctx, label, authz.RoleViewer)
+	if err != nil {
+		return models.Label{}, err
+	}
+	return label, nil
+}
+
+// Lists the labels of the organization, and of the projects visible to the authenticated user.
+func (s *LabelService) List(ctx context.Context, query krest.CollectionQuery) ([]models.Label, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	projects, err := s.policy.VisibleProjects(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []*uuid.UUID{nil}
+	for i := range projects {
+		scopes = append(scopes, &projects[i])
+	}
+	query.Filters = append(query.Filters, krest.Filter{Field: "project_id", Operator: krest.FilterIn, Value: scopes})
+	return s.Service.List(ctx, query)
+}
+
+func (s *LabelService) Create(ctx context.Context, label models.Label) (models.Label, error) {
+	err := s.authorize(ctx, label, authz.RoleMember)
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	err = s.checkName(ctx, uuid.Nil, label)
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	return s.Service.Create(ctx, label)
+}
+
+func (s *LabelService) Update(ctx context.Context, id uuid.UUID, label models.Label) (models.Label, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	err = s.authorize(ctx, current, authz.RoleMember)
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	// Labels stay in their scope, they may label tasks that other scopes can't.
+	if !sameProject(current.ProjectID, label.ProjectID) {
+		return models.Label{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "project_id", Rule: "readonly", Message: "labels can't move to another project"}}}
+	}
+
+	err = s.checkName(ctx, id, label)
+	if err != nil {
+		return models.Label{}, err
+	}
+
+	return s.Service.Update(ctx, id, label)
+}
+
+func (s *LabelService) Delete(ctx context.Context, id uuid.UUID) error {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return err
+	}
+
+	err = s.authorize(ctx, current, authz.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	err = s.taskLabels.DetachAll(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.Service.Delete(ctx, id)
+}
+
+// Checks that the authenticated user has the role in the project of a label, labels without a project only require a user.
+func (s *LabelService) authorize(ctx context.Context, label models.Label, role authz.Role) error {
+	if label.ProjectID == nil {
+		if _, ok := auth.UserFromContext(ctx); !ok {
+			return auth.ErrUnauthenticated
+		}
+		return nil
+	}
+
+	notFound := krest.NewError(http.StatusNotFound, "labels %s not found", label.UUID)
+	if label.UUID == uuid.Nil {
+		notFound = krest.NewError(http.StatusNotFound, "projects %s not found", *label.ProjectID)
+	}
+	return s.policy.Authorize(ctx, *label.ProjectID, role, notFound)
+}
+
+// Returns a 409 if another label of the same scope has the name of the label.
+func (s *LabelService) checkName(ctx context.Context, id uuid.UUID, label models.Label) error {
+	existing, err := s.Service.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{
+			{Field: "name", Operator: krest.FilterEqual, Value: label.Name},
+			{Field: "project_id", Operator: krest.FilterIn, Value: []*uuid.UUID{label.ProjectID}},
+			{Field: "uuid", Operator: krest.FilterNotEqual, Value: id},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 {
+		return krest.NewError(http.StatusConflict, "label %q already exists", label.Name)
+	}
+	return nil
+}
+
+func sameProject(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,183 @@
+package label_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/label"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestLabels(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	labelRepository := krest_orm.NewGenericPostgresRepository[models.Label](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	taskLabelRepository := label.NewTaskLabelRepository(db)
+	policy := authz.NewPolicy(membershipRepository)
+	authorized := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	tasks := krest_orm.NewRelationService[models.Task](label.NewTaskService(authorized, labelRepository),
+		label.TaskRelation(taskLabelRepository, labelRepository),
+	)
+	labels := label.NewLabelService(krest_orm.NewGenericService(labelRepository), taskLabelRepository, policy)
+	taskLabels := label.NewTaskLabelService(taskLabelRepository, labelRepository, authorized, policy, auditLog)
+
+	// Alice is a member of Omni, Bob of Web.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	users, projects := map[string]context.Context{}, map[string]models.Project{}
+	for name, key := range map[string]string{"alice": "OMNI", "bob": "WEB"} {
+		project, err := projectRepository.Create(ctx, models.Project{Name: key, Key: key})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleMember)
+		if err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+		users[name], projects[key] = auth.WithUser(ctx, user), project
+	}
+	alice, omni, web := users["alice"], projects["OMNI"], projects["WEB"]
+
+	backend, err := labels.Create(alice, models.Label{Name: "backend", Color: "#0052cc"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	regression, err := labels.Create(alice, models.Label{Name: "regression", Color: "#d73a4a", ProjectID: &omni.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	private, err := labels.Create(users["bob"], models.Label{Name: "private", Color: "#000000", ProjectID: &web.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	_, err = labels.Create(alice, models.Label{Name: "backend", Color: "#ffffff"})
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate label, got %v", err)
+	}
+	_, err = labels.Create(alice, models.Label{Name: "web", Color: "#ffffff", ProjectID: &web.UUID})
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a label of another project, got %v", err)
+	}
+
+	// Labels of the organization and visible projects are listed.
+	visible, err := labels.List(alice, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(visible) != 2 {
+		t.Errorf("expected the backend and regression labels, got %v", visible)
+	}
+
+	ids := map[string]uuid.UUID{}
+	for _, summary := range []string{"both", "backend", "none"} {
+		task, err := authorized.Create(alice, models.Task{Summary: summary, ProjectID: omni.UUID})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids[summary] = task.UUID
+	}
+	for summary, attached := range map[string][]uuid.UUID{"both": {backend.UUID, regression.UUID}, "backend": {backend.UUID}} {
+		for _, labelID := range attached {
+			_, err = taskLabels.Attach(alice, ids[summary], labelID)
+			if err != nil {
+				t.Fatalf("Attach failed: %v", err)
+			}
+		}
+	}
+
+	// Labels of other projects can't be attached.
+	_, err = taskLabels.Attach(alice, ids["none"], private.UUID)
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for a label of another project, got %v", err)
+	}
+
+	// Attaching labels is audited as a change of the labels of the task, attaching them again changes nothing.
+	_, err = taskLabels.Attach(alice, ids["backend"], backend.UUID)
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: ids["both"]}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != string(audit.ActionUpdate) || events[0].ResourceType != "tasks" || events[0].ProjectID != omni.UUID {
+		t.Fatalf("expected an event for each label attached, got %+v", events)
+	}
+	events, err = auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: ids["backend"]}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Changes["labels"].After == nil {
+		t.Errorf("expected a single event with the labels, got %+v", events)
+	}
+
+	task, err := tasks.Get(alice, ids["both"], krest.ResourceQuery{Expand: []string{"labels"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(task.Labels) != 2 || task.Labels[0].Name != "backend" {
+		t.Errorf("expected the backend and regression labels, got %v", task.Labels)
+	}
+
+	summaries := func(filter krest.Filter) map[string]bool {
+		list, err := tasks.List(alice, krest.CollectionQuery{Filters: []krest.Filter{filter}, Expand: []string{"summary"}})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		found := map[string]bool{}
+		for _, task := range list {
+			found[task.Summary] = true
+		}
+		return found
+	}
+
+	all := summaries(krest.Filter{Field: "labels", Operator: krest.FilterAll, Value: []string{"backend", regression.UUID.String()}})
+	if len(all) != 1 || !all["both"] {
+		t.Errorf("expected the task with both labels, got %v", all)
+	}
+	either := summaries(krest.Filter{Field: "labels", Operator: krest.FilterIn, Value: []string{"backend", "regression"}})
+	if len(either) != 2 || !either["both"] || !either["backend"] {
+		t.Errorf("expected the tasks with either label, got %v", either)
+	}
+
+	// Deleting a label detaches it from its tasks.
+	err = labels.Delete(alice, backend.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	remaining, err := taskLabels.List(alice, ids["both"])
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].UUID != regression.UUID {
+		t.Errorf("expected only the regression label, got %v", remaining)
+	}
+	backendTasks := summaries(krest.Filter{Field: "labels", Operator: krest.FilterEqual, Value: backend.UUID.String()})
+	if len(backendTasks) != 0 {
+		t.Errorf("expected no tasks with the deleted label, got %v", backendTasks)
+	}
+}
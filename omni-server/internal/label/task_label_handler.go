@@ -0,0 +1,88 @@
+package label
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// TaskLabelHandler implements the http api for the labels of tasks. [/v1/tasks/{uuid}/labels]
+type TaskLabelHandler struct {
+	service *TaskLabelService
+}
+
+func NewTaskLabelHandler(service *TaskLabelService) *TaskLabelHandler {
+	return &TaskLabelHandler{service: service}
+}
+
+// List lists the labels of a task. [GET /v1/tasks/{uuid}/labels]
+func (h *TaskLabelHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the labels
+	labels, err := h.service.List(r.Context(), taskID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, labels, len(labels), len(labels), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Attach attaches a label to a task, e.g. {"label_id": "<uuid>"}. [POST /v1/tasks/{uuid}/labels]
+func (h *TaskLabelHandler) Attach(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the request body.
+	var request AttachRequest
+	err = krest.DecodeRequestBody(w, r, &request, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Attach the label
+	labels, err := h.service.Attach(r.Context(), taskID, request.LabelID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, labels, len(labels), len(labels), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Detach removes a label from a task. [DELETE /v1/tasks/{uuid}/labels/{label}]
+func (h *TaskLabelHandler) Detach(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	labelID, err := uuid.Parse(chi.URLParam(r, "label"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Detach the label
+	labels, err := h.service.Detach(r.Context(), taskID, labelID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, labels, len(labels), len(labels), krest.CollectionQuery{}, krest.MetaQuery{})
+}
@@ -0,0 +1,115 @@
+package label
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+)
+
+// TaskLabelRepository stores the labels of tasks.
+// Labels are only reachable through their task, authorize access to the task first.
+type TaskLabelRepository struct {
+	db *sql.DB
+}
+
+// NewTaskLabelRepository creates the table of the repository, after the tasks and labels tables.
+func NewTaskLabelRepository(db *sql.DB) *TaskLabelRepository {
+	query := "CREATE TABLE IF NOT EXISTS task_labels (task_id UUID NOT NULL REFERENCES tasks(uuid) ON DELETE CASCADE, label_id UUID NOT NULL REFERENCES labels(uuid) ON DELETE CASCADE, PRIMARY KEY (task_id, label_id));"
+	log.Printf("creating table: %s", query)
+	_, err := db.Exec(query)
+	if err != nil {
+		log.Fatalf("failed to create table: %v", err)
+	}
+
+	return &TaskLabelRepository{db: db}
+}
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *TaskLabelRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// Attach labels a task, labels already on the task are left as is.
+func (r *TaskLabelRepository) Attach(ctx context.Context, taskID uuid.UUID, labelID uuid.UUID) error {
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO task_labels (task_id, label_id) VALUES ($1, $2) ON CONFLICT (task_id, label_id) DO NOTHING",
+		taskID, labelID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to attach label: %v", err)
+	}
+	return nil
+}
+
+// Detach removes a label from a task.
+func (r *TaskLabelRepository) Detach(ctx context.Context, taskID uuid.UUID, labelID uuid.UUID) error {
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM task_labels WHERE task_id = $1 AND label_id = $2", taskID, labelID)
+	if err != nil {
+		return fmt.Errorf("failed to detach label: %v", err)
+	}
+	return nil
+}
+
+// DetachAll removes a label from all of its tasks.
+// Not every database enforces foreign keys, call it before deleting the label.
+func (r *TaskLabelRepository) DetachAll(ctx context.Context, labelID uuid.UUID) error {
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM task_labels WHERE label_id = $1", labelID)
+	if err != nil {
+		return fmt.Errorf("failed to detach label: %v", err)
+	}
+	return nil
+}
+
+// ByTask returns the labels of each of the tasks.
+func (r *TaskLabelRepository) ByTask(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	return r.query(ctx, "task_id", taskIDs, func(taskID, labelID uuid.UUID) (uuid.UUID, uuid.UUID) { return taskID, labelID })
+}
+
+// Returns the rows where the column is one of the ids, grouped by the key of the pair.
+func (r *TaskLabelRepository) query(ctx context.Context, column string, ids []uuid.UUID, pair func(taskID, labelID uuid.UUID) (uuid.UUID, uuid.UUID)) (map[uuid.UUID][]uuid.UUID, error) {
+	grouped := map[uuid.UUID][]uuid.UUID{}
+	if len(ids) == 0 {
+		return grouped, nil
+	}
+
+	placeholders, args := []string{}, []interface{}{}
+	for i, id := range ids {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, id)
+	}
+
+	// Only the labels of tasks of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "tasks", len(args)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := krest_orm.Conn(ctx, r.db).QueryContext(ctx,
+		fmt.Sprintf(`
+			SELECT task_labels.task_id, task_labels.label_id FROM task_labels JOIN tasks ON tasks.uuid = task_labels.task_id
+			WHERE task_labels.%s IN (%s) AND %s`,
+			column, strings.Join(placeholders, ", "), tenant),
+		append(args, tenantArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task labels: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, labelID uuid.UUID
+		err = rows.Scan(&taskID, &labelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list task labels: %v", err)
+		}
+		key, value := pair(taskID, labelID)
+		grouped[key] = append(grouped[key], value)
+	}
+
+	return grouped, rows.Err()
+}
@@ -0,0 +1,219 @@
+package label
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// AttachRequest attaches a label to a task.
+type AttachRequest struct {
+	LabelID uuid.UUID `json:"label_id"`
+}
+
+// TaskLabelService manages the labels of tasks.
+// Labels are visible to everyone who can see the task, members of the project can attach and detach them.
+// Attaching and detaching labels is recorded in the audit log as a change of the labels of the task.
+type TaskLabelService struct {
+	taskLabels *TaskLabelRepository
+	labels     krest.Repository[models.Label]
+	tasks      krest.Service[models.Task]
+	policy     *authz.Policy
+	auditLog   *audit.Log
+}
+
+// The task service must authorize reading tasks, labels are only attached through their task.
+func NewTaskLabelService(taskLabels *TaskLabelRepository, labels krest.Repository[models.Label], tasks krest.Service[models.Task], policy *authz.Policy, auditLog *audit.Log) *TaskLabelService {
+	return &TaskLabelService{taskLabels: taskLabels, labels: labels, tasks: tasks, policy: policy, auditLog: auditLog}
+}
+
+// The labels of a task as recorded in the audit log.
+type taskLabels struct {
+	Labels []string `json:"labels"`
+}
+
+// List lists the labels of a task.
+func (s *TaskLabelService) List(ctx context.Context, taskID uuid.UUID) ([]models.Label, error) {
+	_, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.list(ctx, taskID)
+}
+
+// Attach attaches a label of the organization, or of the project of the task, and returns the labels of the task.
+func (s *TaskLabelService) Attach(ctx context.Context, taskID uuid.UUID, labelID uuid.UUID) ([]models.Label, error) {
+	task, err := s.authorize(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := s.labels.Get(ctx, labelID, krest.ResourceQuery{})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return nil, &krest.ValidationError{Errors: []krest.FieldError{{Field: "label_id", Rule: "ref", Message: fmt.Sprintf("references a nonexistent resource: %s", labelID)}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if label.ProjectID != nil && *label.ProjectID != task.ProjectID {
+		return nil, &krest.ValidationError{Errors: []krest.FieldError{{Field: "label_id", Rule: "project", Message: "must be a label of the organization or the project of the task"}}}
+	}
+
+	return s.change(ctx, task, func(ctx context.Context) error {
+		return s.taskLabels.Attach(ctx, taskID, labelID)
+	})
+}
+
+// Detach removes a label from a task, and returns the labels of the task.
+func (s *TaskLabelService) Detach(ctx context.Context, taskID uuid.UUID, labelID uuid.UUID) ([]models.Label, error) {
+	task, err := s.authorize(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.change(ctx, task, func(ctx context.Context) error {
+		return s.taskLabels.Detach(ctx, taskID, labelID)
+	})
+}
+
+// Changes the labels of a task and records the change in one transaction, and returns the labels of the task.
+func (s *TaskLabelService) change(ctx context.Context, task models.Task, fn func(ctx context.Context) error) ([]models.Label, error) {
+	var labels []models.Label
+	err := s.taskLabels.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.labelIDs(ctx, task.UUID)
+		if err != nil {
+			return err
+		}
+		err = fn(ctx)
+		if err != nil {
+			return err
+		}
+		after, err := s.labelIDs(ctx, task.UUID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Equal(before.Labels, after.Labels) {
+			err = s.auditLog.Record(ctx, audit.ActionUpdate, "tasks", task.UUID, task.ProjectID, before, after)
+			if err != nil {
+				return fmt.Errorf("failed to record audit event: %v", err)
+			}
+		}
+
+		labels, err = s.list(ctx, task.UUID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// Returns the ids of the labels of a task, sorted so changes can be compared.
+func (s *TaskLabelService) labelIDs(ctx context.Context, taskID uuid.UUID) (taskLabels, error) {
+	byTask, err := s.taskLabels.ByTask(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return taskLabels{}, err
+	}
+
+	ids := []string{}
+	for _, id := range byTask[taskID] {
+		ids = append(ids, id.String())
+	}
+	slices.Sort(ids)
+	return taskLabels{Labels: ids}, nil
+}
+
+// Returns the task, if the authenticated user can change it.
+func (s *TaskLabelService) authorize(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	task, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// Returns the labels of a task, without authorizing.
+func (s *TaskLabelService) list(ctx context.Context, taskID uuid.UUID) ([]models.Label, error) {
+	byTask, err := s.taskLabels.ByTask(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	return labelsByID(ctx, s.labels, byTask[taskID])
+}
+
+// Returns the labels with the ids, by name.
+func labelsByID(ctx context.Context, labels krest.Repository[models.Label], ids []uuid.UUID) ([]models.Label, error) {
+	if len(ids) == 0 {
+		return []models.Label{}, nil
+	}
+
+	return labels.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: ids}},
+		Sort:    []krest.Sort{{Field: "name"}},
+	})
+}
+
+// TaskRelation loads the labels of tasks, when expanded with ?expand=labels.
+func TaskRelation(taskLabels *TaskLabelRepository, labels krest.Repository[models.Label]) krest_orm.Relation[models.Task] {
+	return krest_orm.Relation[models.Task]{
+		Field: "labels",
+		Load: func(ctx context.Context, tasks []models.Task) error {
+			taskIDs := []uuid.UUID{}
+			for _, task := range tasks {
+				taskIDs = append(taskIDs, task.UUID)
+			}
+
+			byTask, err := taskLabels.ByTask(ctx, taskIDs)
+			if err != nil {
+				return err
+			}
+
+			labelIDs, seen := []uuid.UUID{}, map[uuid.UUID]bool{}
+			for _, ids := range byTask {
+				for _, id := range ids {
+					if !seen[id] {
+						seen[id] = true
+						labelIDs = append(labelIDs, id)
+					}
+				}
+			}
+			found, err := labelsByID(ctx, labels, labelIDs)
+			if err != nil {
+				return err
+			}
+
+			// Keep the labels of each task sorted by name.
+			for i := range tasks {
+				attached := map[uuid.UUID]bool{}
+				for _, id := range byTask[tasks[i].UUID] {
+					attached[id] = true
+				}
+
+				tasks[i].Labels = []models.Label{}
+				for _, label := range found {
+					if attached[label.UUID] {
+						tasks[i].Labels = append(tasks[i].Labels, label)
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package label
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, filtering tasks by their labels.
+// Labels are given by uuid or name, e.g. filter[labels][all]=backend,regression for tasks having both labels,
+// filter[labels][in]=backend,regression for tasks having either, and filter[labels]=backend for a single label.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+	labels krest.Repository[models.Label]
+}
+
+// Filters query the task_labels table of the TaskLabelRepository, create it first.
+func NewTaskService(service krest.Service[models.Task], labels krest.Repository[models.Label]) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, labels: labels}
+}
+
+func (s *TaskService) List(ctx context.Context, query krest.CollectionQuery) ([]models.Task, error) {
+	filters := []krest.Filter{}
+	for _, filter := range query.Filters {
+		if filter.Field != "labels" {
+			filters = append(filters, filter)
+			continue
+		}
+
+		labelFilter, err := s.labelFilter(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, labelFilter)
+	}
+
+	query.Filters = filters
+	return s.Service.List(ctx, query)
+}
+
+// Returns the filter of the tasks matching a filter on labels, a subquery of the labels of the tasks.
+func (s *TaskService) labelFilter(ctx context.Context, filter krest.Filter) (krest.Filter, error) {
+	values := []string{}
+	switch value := filter.Value.(type) {
+	case string:
+		values = append(values, value)
+	case uuid.UUID:
+		values = append(values, value.String())
+	case []string:
+		values = value
+	case []uuid.UUID:
+		for _, id := range value {
+			values = append(values, id.String())
+		}
+	default:
+		return krest.Filter{}, fmt.Errorf("invalid value of labels filter: %v", filter.Value)
+	}
+
+	all := true
+	switch filter.Operator {
+	case krest.FilterEqual, krest.FilterContains, krest.FilterAll:
+	case krest.FilterIn:
+		all = false
+	default:
+		return krest.Filter{}, krest.NewError(http.StatusBadRequest, "labels can only be filtered with the eq, contains, in and all operators, not %s", filter.Operator)
+	}
+
+	// Each value matches the labels with the uuid or name, names may be used by labels of several projects.
+	groups := [][]uuid.UUID{}
+	for _, value := range values {
+		if id, err := uuid.Parse(value); err == nil {
+			groups = append(groups, []uuid.UUID{id})
+			continue
+		}
+
+		named, err := s.labels.List(ctx, krest.CollectionQuery{
+			Filters: []krest.Filter{{Field: "name", Operator: krest.FilterEqual, Value: value}},
+		})
+		if err != nil {
+			return krest.Filter{}, err
+		}
+		group := []uuid.UUID{}
+		for _, label := range named {
+			group = append(group, label.UUID)
+		}
+		groups = append(groups, group)
+	}
+
+	// Each value matches the tasks with one of the labels of its group.
+	return krest_orm.ConditionFilter(func(table string, argIdx int) (string, []interface{}) {
+		conditions, args := []string{}, []interface{}{}
+		for _, group := range groups {
+			if len(group) == 0 {
+				conditions = append(conditions, "1 = 0")
+				continue
+			}
+			placeholders, groupArgs := krest_orm.Placeholders(group, argIdx+len(args))
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM task_labels WHERE task_labels.task_id = %s.uuid AND task_labels.label_id IN (%s))", table, placeholders))
+			args = append(args, groupArgs...)
+		}
+
+		if len(conditions) == 0 {
+			return "1 = 0", nil
+		}
+		operator := " OR "
+		if all {
+			operator = " AND "
+		}
+		return strings.Join(conditions, operator), args
+	}), nil
+}
@@ -0,0 +1,99 @@
+package link
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// LinkHandler implements the http api for the links of tasks. [/v1/tasks/{uuid}/links]
+type LinkHandler struct {
+	service *LinkService
+}
+
+func NewLinkHandler(service *LinkService) *LinkHandler {
+	return &LinkHandler{service: service}
+}
+
+// List lists the links of a task, grouped by type. [GET /v1/tasks/{uuid}/links]
+func (h *LinkHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the links
+	links, err := h.service.List(r.Context(), taskID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, links, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Create links a task to another, e.g. {"target_id": "<uuid>", "type": "blocks"}. [POST /v1/tasks/{uuid}/links]
+func (h *LinkHandler) Create(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the request body.
+	var link models.TaskLink
+	err = krest.DecodeRequestBody(w, r, &link, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	krest.ClearReadOnlyFields(&link)
+
+	// Validate the link.
+	err = krest.ValidateResource(r.Context(), link, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Create the link.
+	createdLink, err := h.service.Create(r.Context(), taskID, link)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusCreated, createdLink, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Delete removes a link of a task, along with its inverse. [DELETE /v1/tasks/{uuid}/links/{link}]
+func (h *LinkHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	linkID, err := uuid.Parse(chi.URLParam(r, "link"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Delete the link.
+	err = h.service.Delete(r.Context(), taskID, linkID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
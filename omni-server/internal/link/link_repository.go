@@ -0,0 +1,82 @@
+package link
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// LinkRepository queries the blocking links between tasks, the links themselves are stored by the generic repository.
+type LinkRepository struct {
+	db *sql.DB
+}
+
+// NewLinkRepository queries the task_links and tasks tables, create them first.
+func NewLinkRepository(db *sql.DB) *LinkRepository {
+	return &LinkRepository{db: db}
+}
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *LinkRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// Blocks returns whether a task blocks another, directly or through the tasks it blocks.
+func (r *LinkRepository) Blocks(ctx context.Context, blocker uuid.UUID, blocked uuid.UUID) (bool, error) {
+	// Only follow the links of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "task_links", 3)
+	if err != nil {
+		return false, err
+	}
+
+	var found int
+	err = krest_orm.Conn(ctx, r.db).QueryRowContext(ctx, fmt.Sprintf(`
+		WITH RECURSIVE blocked(uuid) AS (
+			SELECT target_id FROM task_links WHERE source_id = $1 AND type = $2 AND %[1]s
+			UNION
+			SELECT task_links.target_id FROM task_links JOIN blocked ON task_links.source_id = blocked.uuid WHERE task_links.type = $2 AND %[1]s
+		)
+		SELECT COUNT(*) FROM blocked WHERE uuid = $4`, tenant),
+		append(append([]interface{}{blocker, models.LinkBlocks}, tenantArgs...), blocked)...,
+	).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("failed to query blocked tasks: %v", err)
+	}
+	return found > 0, nil
+}
+
+// UnresolvedBlockers returns the keys of the tasks blocking a task that aren't completed.
+func (r *LinkRepository) UnresolvedBlockers(ctx context.Context, taskID uuid.UUID) ([]string, error) {
+	// Only the blockers of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "tasks", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := krest_orm.Conn(ctx, r.db).QueryContext(ctx, fmt.Sprintf(`
+		SELECT tasks.key FROM task_links JOIN tasks ON tasks.uuid = task_links.target_id
+		WHERE task_links.source_id = $1 AND task_links.type = $2 AND tasks.completed_at IS NULL AND %s
+		ORDER BY tasks.key`, tenant),
+		append([]interface{}{taskID, models.LinkBlockedBy}, tenantArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blockers: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query blockers: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
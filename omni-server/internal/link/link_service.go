@@ -0,0 +1,314 @@
+package link
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/workflow"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// The fields of the target tasks listed with links.
+var targetFields = []string{"summary", "project_id", "status_id", "completed_at"}
+
+// LinkService manages the links between tasks, storing the inverse of every link along with it.
+// Links are visible to everyone who can see both tasks, members of the project can link its tasks to the tasks they can see.
+// Tasks can't block themselves, directly or through the tasks they block.
+// Links and their inverses are written in one transaction, and links users create or delete are audited.
+type LinkService struct {
+	links      krest.Repository[models.TaskLink]
+	repository *LinkRepository
+	tasks      krest.Service[models.Task]
+	policy     *authz.Policy
+	auditLog   *audit.Log
+}
+
+// The task service must authorize reading tasks, links are only visible through their tasks.
+func NewLinkService(links krest.Repository[models.TaskLink], repository *LinkRepository, tasks krest.Service[models.Task], policy *authz.Policy, auditLog *audit.Log) *LinkService {
+	return &LinkService{links: links, repository: repository, tasks: tasks, policy: policy, auditLog: auditLog}
+}
+
+// List lists the links of a task grouped by type, with their target tasks.
+func (s *LinkService) List(ctx context.Context, taskID uuid.UUID) (models.TaskLinks, error) {
+	_, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{})
+	if err != nil {
+		return models.TaskLinks{}, err
+	}
+
+	links, err := s.links.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "source_id", Operator: krest.FilterEqual, Value: taskID}},
+	})
+	if err != nil {
+		return models.TaskLinks{}, err
+	}
+
+	// Load the targets, links to tasks that aren't visible are left out.
+	targetIDs := []uuid.UUID{}
+	for _, link := range links {
+		targetIDs = append(targetIDs, link.TargetID)
+	}
+	targets := map[uuid.UUID]models.Task{}
+	if len(targetIDs) > 0 {
+		tasks, err := s.tasks.List(ctx, krest.CollectionQuery{
+			Expand:  targetFields,
+			Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: targetIDs}},
+			Sort:    []krest.Sort{{Field: "key"}},
+		})
+		if err != nil {
+			return models.TaskLinks{}, err
+		}
+		for _, task := range tasks {
+			targets[task.UUID] = task
+		}
+	}
+
+	grouped := models.TaskLinks{
+		Blocks:       []models.TaskLink{},
+		BlockedBy:    []models.TaskLink{},
+		RelatesTo:    []models.TaskLink{},
+		Duplicates:   []models.TaskLink{},
+		DuplicatedBy: []models.TaskLink{},
+		Mentions:     []models.TaskLink{},
+		MentionedIn:  []models.TaskLink{},
+	}
+	for _, link := range links {
+		target, ok := targets[link.TargetID]
+		if !ok {
+			continue
+		}
+		link.Target = &target
+
+		switch link.Type {
+		case models.LinkBlocks:
+			grouped.Blocks = append(grouped.Blocks, link)
+		case models.LinkBlockedBy:
+			grouped.BlockedBy = append(grouped.BlockedBy, link)
+		case models.LinkRelatesTo:
+			grouped.RelatesTo = append(grouped.RelatesTo, link)
+		case models.LinkDuplicates:
+			grouped.Duplicates = append(grouped.Duplicates, link)
+		case models.LinkDuplicatedBy:
+			grouped.DuplicatedBy = append(grouped.DuplicatedBy, link)
+		case models.LinkMentions:
+			grouped.Mentions = append(grouped.Mentions, link)
+		case models.LinkMentionedIn:
+			grouped.MentionedIn = append(grouped.MentionedIn, link)
+		}
+	}
+
+	return grouped, nil
+}
+
+// Create links a task to another, and returns the link.
+func (s *LinkService) Create(ctx context.Context, taskID uuid.UUID, link models.TaskLink) (models.TaskLink, error) {
+	task, err := s.authorize(ctx, taskID)
+	if err != nil {
+		return models.TaskLink{}, err
+	}
+
+	// Mentions are only linked by the references of descriptions and comments.
+	inverseType, ok := models.InverseLinkTypes[link.Type]
+	if !ok || !slices.Contains(linkTypes(), link.Type) {
+		return models.TaskLink{}, linkError("type", "oneof", fmt.Sprintf("must be one of %s", strings.Join(linkTypes(), ", ")))
+	}
+
+	if link.TargetID == taskID {
+		return models.TaskLink{}, linkError("target_id", "self", "must be another task")
+	}
+	target, err := s.tasks.Get(ctx, link.TargetID, krest.ResourceQuery{Expand: targetFields})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return models.TaskLink{}, linkError("target_id", "ref", fmt.Sprintf("references a nonexistent resource: %s", link.TargetID))
+	}
+	if err != nil {
+		return models.TaskLink{}, err
+	}
+
+	var created models.TaskLink
+	err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+		// Tasks are linked once per type.
+		existing, err := s.links.List(ctx, krest.CollectionQuery{
+			Limit: 1,
+			Filters: []krest.Filter{
+				{Field: "source_id", Operator: krest.FilterEqual, Value: taskID},
+				{Field: "target_id", Operator: krest.FilterEqual, Value: link.TargetID},
+				{Field: "type", Operator: krest.FilterEqual, Value: link.Type},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return krest.NewError(http.StatusConflict, "task is already linked to %s with type %s", target.Key, link.Type)
+		}
+
+		// A task blocking another can't be blocked by it, directly or through the tasks it blocks.
+		blocker, blocked := uuid.Nil, uuid.Nil
+		switch link.Type {
+		case models.LinkBlocks:
+			blocker, blocked = taskID, link.TargetID
+		case models.LinkBlockedBy:
+			blocker, blocked = link.TargetID, taskID
+		}
+		if blocker != uuid.Nil {
+			cycle, err := s.repository.Blocks(ctx, blocked, blocker)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return linkError("target_id", "cycle", "must not block a task that blocks it")
+			}
+		}
+
+		link.SourceID = taskID
+		created, err = s.links.Create(ctx, link)
+		if err != nil {
+			return err
+		}
+		_, err = s.links.Create(ctx, models.TaskLink{SourceID: link.TargetID, TargetID: taskID, Type: inverseType})
+		if err != nil {
+			return err
+		}
+
+		err = s.auditLog.Record(ctx, audit.ActionCreate, "task_links", created.UUID, task.ProjectID, nil, created)
+		if err != nil {
+			return fmt.Errorf("failed to record audit event: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.TaskLink{}, err
+	}
+
+	created.Target = &target
+	return created, nil
+}
+
+// Delete removes a link of a task, along with its inverse.
+func (s *LinkService) Delete(ctx context.Context, taskID uuid.UUID, id uuid.UUID) error {
+	task, err := s.authorize(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Transaction(ctx, func(ctx context.Context) error {
+		link, err := s.links.Get(ctx, id, krest.ResourceQuery{})
+		if err != nil {
+			return err
+		}
+		if link.SourceID != taskID {
+			return krest.NewError(http.StatusNotFound, "task_links %s not found", id)
+		}
+
+		inverses, err := s.links.List(ctx, krest.CollectionQuery{
+			Filters: []krest.Filter{
+				{Field: "source_id", Operator: krest.FilterEqual, Value: link.TargetID},
+				{Field: "target_id", Operator: krest.FilterEqual, Value: taskID},
+				{Field: "type", Operator: krest.FilterEqual, Value: models.InverseLinkTypes[link.Type]},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		for _, inverse := range inverses {
+			err = s.links.Delete(ctx, inverse.UUID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.links.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = s.auditLog.Record(ctx, audit.ActionDelete, "task_links", id, task.ProjectID, link, nil)
+		if err != nil {
+			return fmt.Errorf("failed to record audit event: %v", err)
+		}
+		return nil
+	})
+}
+
+// Mention links a task to the tasks its description or comments reference, so they list it as mentioned-in.
+// Tasks already linked are skipped. The caller checks the visibility of the tasks, mentions don't need membership.
+func (s *LinkService) Mention(ctx context.Context, taskID uuid.UUID, targetIDs []uuid.UUID) error {
+	existing, err := s.links.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{
+			{Field: "source_id", Operator: krest.FilterEqual, Value: taskID},
+			{Field: "type", Operator: krest.FilterEqual, Value: models.LinkMentions},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	linked := map[uuid.UUID]bool{taskID: true}
+	for _, link := range existing {
+		linked[link.TargetID] = true
+	}
+
+	for _, targetID := range targetIDs {
+		if linked[targetID] {
+			continue
+		}
+		linked[targetID] = true
+
+		err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+			_, err := s.links.Create(ctx, models.TaskLink{SourceID: taskID, TargetID: targetID, Type: models.LinkMentions})
+			if err != nil {
+				return err
+			}
+			_, err = s.links.Create(ctx, models.TaskLink{SourceID: targetID, TargetID: taskID, Type: models.LinkMentionedIn})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the task, if the authenticated user can change it.
+func (s *LinkService) authorize(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	task, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+func linkError(field string, rule string, message string) error {
+	return &krest.ValidationError{Errors: []krest.FieldError{{Field: field, Rule: rule, Message: message}}}
+}
+
+// Returns the link types users can create, in the order they're listed.
+func linkTypes() []string {
+	return []string{models.LinkBlocks, models.LinkBlockedBy, models.LinkRelatesTo, models.LinkDuplicates, models.LinkDuplicatedBy}
+}
+
+// BlockersResolvedGuard requires the tasks blocking a task to be completed, e.g. for transitions to done statuses.
+// Add it to the guards of the workflow and task type services to use it in workflows.
+func BlockersResolvedGuard(repository *LinkRepository) workflow.Guard {
+	return func(ctx context.Context, task models.Task) (string, error) {
+		blockers, err := repository.UnresolvedBlockers(ctx, task.UUID)
+		if err != nil {
+			return "", err
+		}
+		if len(blockers) > 0 {
+			return fmt.Sprintf("is blocked by %s", strings.Join(blockers, ", ")), nil
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,172 @@
+package link_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/link"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestLinks(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	taskLinkRepository := krest_orm.NewGenericPostgresRepository[models.TaskLink](db)
+	linkRepository := link.NewLinkRepository(db)
+	policy := authz.NewPolicy(membershipRepository)
+	tasks := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	links := link.NewLinkService(taskLinkRepository, linkRepository, tasks, policy, auditLog)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	user, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleMember)
+	if err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+	alice := auth.WithUser(ctx, user)
+
+	created := []models.Task{}
+	for i := 1; i <= 3; i++ {
+		task, err := tasks.Create(alice, models.Task{Summary: fmt.Sprintf("Task %d", i), Key: fmt.Sprintf("OMNI-%d", i), ProjectID: project.UUID})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		created = append(created, task)
+	}
+	first, second, third := created[0], created[1], created[2]
+
+	// OMNI-1 blocks OMNI-2, which blocks OMNI-3.
+	blocks, err := links.Create(alice, first.UUID, models.TaskLink{TargetID: second.UUID, Type: models.LinkBlocks})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = links.Create(alice, third.UUID, models.TaskLink{TargetID: second.UUID, Type: models.LinkBlockedBy})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = links.Create(alice, first.UUID, models.TaskLink{TargetID: third.UUID, Type: models.LinkRelatesTo})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The inverse links are listed with the other task.
+	grouped, err := links.List(alice, second.UUID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(grouped.BlockedBy) != 1 || grouped.BlockedBy[0].TargetID != first.UUID {
+		t.Errorf("expected OMNI-2 to be blocked by OMNI-1, got %+v", grouped.BlockedBy)
+	}
+	if len(grouped.Blocks) != 1 || grouped.Blocks[0].Target == nil || grouped.Blocks[0].Target.Key != "OMNI-3" {
+		t.Errorf("expected OMNI-2 to block OMNI-3, got %+v", grouped.Blocks)
+	}
+	grouped, err = links.List(alice, third.UUID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(grouped.RelatesTo) != 1 || grouped.RelatesTo[0].TargetID != first.UUID {
+		t.Errorf("expected OMNI-3 to relate to OMNI-1, got %+v", grouped.RelatesTo)
+	}
+
+	var validationError *krest.ValidationError
+
+	// OMNI-3 can't block OMNI-1, which blocks it through OMNI-2.
+	_, err = links.Create(alice, third.UUID, models.TaskLink{TargetID: first.UUID, Type: models.LinkBlocks})
+	if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "cycle" {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+	_, err = links.Create(alice, first.UUID, models.TaskLink{TargetID: third.UUID, Type: models.LinkBlockedBy})
+	if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "cycle" {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+	_, err = links.Create(alice, first.UUID, models.TaskLink{TargetID: second.UUID, Type: models.LinkBlocks})
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate link, got %v", err)
+	}
+
+	// Mentions are only linked by references.
+	for _, linkType := range []string{models.LinkMentions, models.LinkMentionedIn} {
+		_, err = links.Create(alice, first.UUID, models.TaskLink{TargetID: third.UUID, Type: linkType})
+		if !errors.As(err, &validationError) || validationError.Errors[0].Rule != "oneof" {
+			t.Errorf("expected a validation error for a %s link, got %v", linkType, err)
+		}
+	}
+
+	// Tasks with incomplete blockers don't meet the guard.
+	guard := link.BlockersResolvedGuard(linkRepository)
+	message, err := guard(alice, third)
+	if err != nil {
+		t.Fatalf("guard failed: %v", err)
+	}
+	if message != "is blocked by OMNI-2" {
+		t.Errorf("expected OMNI-3 to be blocked by OMNI-2, got %q", message)
+	}
+
+	second, err = taskRepository.Get(ctx, second.UUID, krest.ResourceQuery{Expand: []string{"summary", "project_id", "completed_at"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	now := time.Now().UTC()
+	second.CompletedAt = &now
+	_, err = taskRepository.Update(ctx, second.UUID, second)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	message, err = guard(alice, third)
+	if err != nil {
+		t.Fatalf("guard failed: %v", err)
+	}
+	if message != "" {
+		t.Errorf("expected the blockers of OMNI-3 to be resolved, got %q", message)
+	}
+
+	// Deleting a link deletes its inverse.
+	err = links.Delete(alice, first.UUID, blocks.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	grouped, err = links.List(alice, second.UUID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(grouped.BlockedBy) != 0 {
+		t.Errorf("expected the inverse link to be deleted, got %+v", grouped.BlockedBy)
+	}
+
+	// Creating and deleting links is audited.
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: blocks.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != string(audit.ActionCreate) || events[1].Action != string(audit.ActionDelete) || events[0].ResourceType != "task_links" || events[0].ProjectID != project.UUID {
+		t.Errorf("expected the link to be audited when created and deleted, got %+v", events)
+	}
+}
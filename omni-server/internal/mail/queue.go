@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Queue stores emails to send them in the background, so failing to send them doesn't fail the request they're for.
+// Emails failing to send are retried with exponential backoff, until they run out of attempts.
+type Queue struct {
+	emails      krest.Repository[models.Email]
+	mailer      Mailer
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// DefaultMaxAttempts retries emails for about a day and a half with the default backoff of a minute,
+// the backoff doubles after each attempt, so the last attempt is 1+2+...+1024 minutes, about 34 hours, after the first.
+const DefaultMaxAttempts = 12
+
+func NewQueue(emails krest.Repository[models.Email], mailer Mailer, maxAttempts int, backoff time.Duration) *Queue {
+	return &Queue{emails: emails, mailer: mailer, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+// Enqueue stores an email to send.
+func (q *Queue) Enqueue(ctx context.Context, message Message) error {
+	now := time.Now().UTC()
+	_, err := q.emails.Create(ctx, models.Email{
+		Recipient:     message.To,
+		Subject:       message.Subject,
+		Text:          message.Text,
+		HTML:          message.HTML,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+	return err
+}
+
+// Run sends the queued emails at an interval, until the context is done.
+func (q *Queue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := q.Flush(ctx)
+			if err != nil {
+				log.Printf("Failed to send queued emails: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent %d queued emails", sent)
+			}
+		}
+	}
+}
+
+// Flush sends the emails due for an attempt, and returns the number of sent emails.
+// Failing to send an email schedules the next attempt, it doesn't stop the others.
+func (q *Queue) Flush(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	due, err := q.emails.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{
+			{Field: "sent_at", Operator: krest.FilterIn, Value: []*time.Time{nil}},
+			{Field: "next_attempt_at", Operator: krest.FilterLessOrEqual, Value: now},
+			{Field: "attempts", Operator: krest.FilterLessThan, Value: q.maxAttempts},
+		},
+		Sort: []krest.Sort{{Field: "created_at"}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range due {
+		email.Attempts++
+		err = q.mailer.Send(ctx, Message{To: email.Recipient, Subject: email.Subject, Text: email.Text, HTML: email.HTML})
+		if err != nil {
+			email.LastError = err.Error()
+			email.NextAttemptAt = time.Now().UTC().Add(q.backoff << (email.Attempts - 1))
+			if email.Attempts >= q.maxAttempts {
+				log.Printf("Giving up on email %s to %s after %d attempts: %v", email.UUID, email.Recipient, email.Attempts, err)
+			}
+		} else {
+			sentAt := time.Now().UTC()
+			email.SentAt = &sentAt
+			email.LastError = ""
+			sent++
+		}
+
+		_, err = q.emails.Update(ctx, email.UUID, email)
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
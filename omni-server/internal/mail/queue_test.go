@@ -0,0 +1,103 @@
+package mail_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/mail"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestQueue(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	emailRepository := krest_orm.NewGenericPostgresRepository[models.Email](db)
+	mailer := mail.NewMemoryMailer()
+	queue := mail.NewQueue(emailRepository, mailer, 2, 0)
+	ctx := context.Background()
+
+	err = queue.Enqueue(ctx, mail.Message{To: "bob@example.com", Subject: "Hello", Text: "Hi", HTML: "<p>Hi</p>"})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// The mail server is down, the email stays queued.
+	mailer.Err = errors.New("connection refused")
+	sent, err := queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("expected no email to be sent, got %d", sent)
+	}
+	emails, err := emailRepository.List(ctx, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(emails) != 1 || emails[0].Attempts != 1 || emails[0].LastError != "connection refused" || emails[0].SentAt != nil {
+		t.Fatalf("expected the failed attempt to be recorded, got %+v", emails)
+	}
+
+	// The mail server is back, the email is retried.
+	mailer.Err = nil
+	sent, err = queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if sent != 1 || len(mailer.Messages()) != 1 || mailer.Messages()[0].To != "bob@example.com" {
+		t.Fatalf("expected the email to be sent, got %d sent and %+v", sent, mailer.Messages())
+	}
+
+	// Sent emails aren't sent again.
+	sent, err = queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("expected no email to be sent again, got %d", sent)
+	}
+
+	// Emails are given up on after the maximum attempts.
+	err = queue.Enqueue(ctx, mail.Message{To: "alice@example.com", Subject: "Hello"})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	mailer.Err = errors.New("connection refused")
+	for range 3 {
+		_, err = queue.Flush(ctx)
+		if err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+	}
+	emails, err = emailRepository.List(ctx, krest.CollectionQuery{Sort: []krest.Sort{{Field: "created_at"}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(emails) != 2 || emails[1].Attempts != 2 {
+		t.Errorf("expected the email to be attempted twice, got %+v", emails)
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	message := mail.Message{To: "bob@example.com", Subject: "Déjà vu", Text: "Hi", HTML: "<p>Hi</p>"}
+	data, err := message.Bytes("omni@example.com")
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	for _, expected := range []string{"To: bob@example.com\r\n", "Subject: =?utf-8?q?D=C3=A9j=C3=A0_vu?=\r\n", "multipart/alternative", "text/plain", "<p>Hi</p>"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected the message to contain %q, got %s", expected, data)
+		}
+	}
+}
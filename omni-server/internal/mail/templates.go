@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/fs"
+	texttemplate "text/template"
+)
+
+// Templates render the text and HTML bodies of emails, from name.txt and name.html templates.
+// HTML templates escape their data, text templates don't.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// ParseTemplates parses the *.txt and *.html templates of a file system.
+func ParseTemplates(fsys fs.FS) (*Templates, error) {
+	text, err := texttemplate.ParseFS(fsys, "*.txt")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{text: text, html: html}, nil
+}
+
+// Render renders the text and HTML templates of a name into a message.
+func (t *Templates) Render(name string, to string, subject string, data any) (Message, error) {
+	var text, html bytes.Buffer
+	err := t.text.ExecuteTemplate(&text, name+".txt", data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = t.html.ExecuteTemplate(&html, name+".html", data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,333 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Document is markdown rendered to HTML, along with the users and tasks it references.
+// Raw HTML isn't supported, everything is escaped so the HTML is safe to embed as is.
+type Document struct {
+	HTML     string
+	Mentions []string // Usernames mentioned with @username, in order of appearance.
+	TaskKeys []string // Keys of the tasks referenced, e.g. OMNI-42, in order of appearance.
+}
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fencePattern       = regexp.MustCompile("^(```|~~~)\\s*([A-Za-z0-9_+-]*)")
+	rulePattern        = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	unorderedPattern   = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	mentionPattern     = regexp.MustCompile(`^@([A-Za-z0-9][A-Za-z0-9_.-]*[A-Za-z0-9_]|[A-Za-z0-9])`)
+	taskKeyPattern     = regexp.MustCompile(`^[A-Z][A-Z0-9]*-[1-9][0-9]*`)
+	autolinkPattern    = regexp.MustCompile(`^https?://[^\s<>]+`)
+	safeURLPattern     = regexp.MustCompile(`(?i)^(https?://|mailto:)`)
+	trailingURLPattern = regexp.MustCompile(`[.,:;!?)]+$`)
+)
+
+// Parse renders markdown to HTML, and extracts the mentions and task references outside of code.
+// Supports headings, paragraphs, emphasis, code spans and blocks, block quotes, lists, rules, and links.
+// Links other than http, https and mailto are rendered as text.
+func Parse(source string) Document {
+	r := &renderer{}
+	r.blocks(strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n"))
+	return Document{HTML: r.out.String(), Mentions: r.mentions, TaskKeys: r.taskKeys}
+}
+
+type renderer struct {
+	out      strings.Builder
+	mentions []string
+	taskKeys []string
+}
+
+// Renders block elements, lines are consumed by the first block they match.
+func (r *renderer) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimLeft(line, " \t")
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fencePattern.MatchString(trimmed):
+			match := fencePattern.FindStringSubmatch(trimmed)
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), match[1]) {
+				end++
+			}
+			if match[2] != "" {
+				r.out.WriteString(`<pre><code class="language-` + html.EscapeString(match[2]) + `">`)
+			} else {
+				r.out.WriteString("<pre><code>")
+			}
+			for _, code := range lines[i+1 : end] {
+				r.out.WriteString(html.EscapeString(code) + "\n")
+			}
+			r.out.WriteString("</code></pre>\n")
+			i = end + 1
+
+		case headingPattern.MatchString(trimmed):
+			match := headingPattern.FindStringSubmatch(trimmed)
+			tag := "h" + string(rune('0'+len(match[1])))
+			r.out.WriteString("<" + tag + ">")
+			r.inline(match[2])
+			r.out.WriteString("</" + tag + ">\n")
+			i++
+
+		case rulePattern.MatchString(trimmed):
+			r.out.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			quoted := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(quote, " "))
+			}
+			r.out.WriteString("<blockquote>\n")
+			r.blocks(quoted)
+			r.out.WriteString("</blockquote>\n")
+
+		case unorderedPattern.MatchString(trimmed), orderedPattern.MatchString(trimmed):
+			pattern, tag := unorderedPattern, "ul"
+			if !unorderedPattern.MatchString(trimmed) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			r.out.WriteString("<" + tag + ">\n")
+			for i < len(lines) {
+				match := pattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				if match == nil {
+					break
+				}
+				item := []string{match[1]}
+				// Indented lines continue the item.
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.HasPrefix(lines[i], " ") && !pattern.MatchString(strings.TrimSpace(lines[i])); i++ {
+					item = append(item, strings.TrimSpace(lines[i]))
+				}
+				r.out.WriteString("<li>")
+				r.inline(strings.Join(item, "\n"))
+				r.out.WriteString("</li>\n")
+			}
+			r.out.WriteString("</" + tag + ">\n")
+
+		default:
+			paragraph := []string{}
+			for ; i < len(lines) && !r.interrupts(lines[i]); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			r.out.WriteString("<p>")
+			r.inline(strings.Join(paragraph, "\n"))
+			r.out.WriteString("</p>\n")
+		}
+	}
+}
+
+// Returns whether a line ends a paragraph, by being blank or starting another block.
+func (r *renderer) interrupts(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || fencePattern.MatchString(trimmed) || headingPattern.MatchString(trimmed) ||
+		strings.HasPrefix(trimmed, ">") || unorderedPattern.MatchString(trimmed) || orderedPattern.MatchString(trimmed)
+}
+
+// Renders inline elements, escaping everything else.
+func (r *renderer) inline(text string) {
+	r.span(newSpans(text), 0, len(text))
+}
+
+// spans locates the delimiters of inline elements up front, so rendering never scans ahead for them.
+type spans struct {
+	text     string
+	brackets []int            // The position of the ] closing the [ at each position, or -1.
+	parens   []int            // The position of the ) closing the ( at each position without whitespace in between, or -1.
+	ticks    map[int][]int    // The positions of the runs of backticks of each length.
+	emphasis map[string][]int // The position of the next emphasis delimiter at or after each position.
+}
+
+var emphasisDelimiters = []string{"*", "**", "_", "__"}
+
+func newSpans(text string) *spans {
+	s := &spans{
+		text:     text,
+		brackets: make([]int, len(text)),
+		parens:   make([]int, len(text)),
+		ticks:    map[int][]int{},
+		emphasis: map[string][]int{},
+	}
+
+	for i := range text {
+		s.brackets[i], s.parens[i] = -1, -1
+	}
+
+	brackets, parens := []int{}, []int{}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '[':
+			brackets = append(brackets, i)
+		case ']':
+			if len(brackets) > 0 {
+				s.brackets[brackets[len(brackets)-1]] = i
+				brackets = brackets[:len(brackets)-1]
+			}
+		case '(':
+			parens = append(parens, i)
+		case ')':
+			if len(parens) > 0 {
+				s.parens[parens[len(parens)-1]] = i
+				parens = parens[:len(parens)-1]
+			}
+		case ' ', '\n':
+			parens = parens[:0]
+		case '`':
+			start := i
+			for i+1 < len(text) && text[i+1] == '`' {
+				i++
+			}
+			s.ticks[i+1-start] = append(s.ticks[i+1-start], start)
+		}
+	}
+
+	// Positions without a next delimiter refer to the end of the text.
+	for _, delimiter := range emphasisDelimiters {
+		next := make([]int, len(text)+1)
+		next[len(text)] = len(text)
+		for i := len(text) - 1; i >= 0; i-- {
+			next[i] = next[i+1]
+			if strings.HasPrefix(text[i:], delimiter) {
+				next[i] = i
+			}
+		}
+		s.emphasis[delimiter] = next
+	}
+
+	return s
+}
+
+// Parses a link, [label](url) at a position, returning the end of its label, its url and its end.
+func (s *spans) link(i int, end int) (int, string, int, bool) {
+	label := s.brackets[i]
+	if label < 0 || label+1 >= end || s.text[label+1] != '(' {
+		return 0, "", 0, false
+	}
+	// Parentheses in the url must be balanced.
+	url := s.parens[label+1]
+	if url < 0 || url >= end {
+		return 0, "", 0, false
+	}
+	return label, s.text[label+2 : url], url + 1, true
+}
+
+// Returns the start of the closing run of a code span, a run of as many backticks after a position.
+func (s *spans) code(ticks int, from int, end int) (int, bool) {
+	runs := s.ticks[ticks]
+	i, _ := slices.BinarySearch(runs, from)
+	if i == len(runs) || runs[i]+ticks > end {
+		return 0, false
+	}
+	return runs[i], true
+}
+
+// Renders the inline elements between two positions of the text.
+func (r *renderer) span(s *spans, start int, end int) {
+	text := s.text
+	for i := start; i < end; {
+		rest := text[i:end]
+		wordStart := i == start || !isWordByte(text[i-1])
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!@>", rune(rest[1])):
+			r.out.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			// Unclosed runs of backticks are text.
+			ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if close, ok := s.code(ticks, i+ticks, end); ok {
+				code := strings.TrimSpace(text[i+ticks : close])
+				r.out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i = close + ticks
+			} else {
+				r.out.WriteString(rest[:ticks])
+				i += ticks
+			}
+			continue
+
+		case rest[0] == '[':
+			if label, url, next, ok := s.link(i, end); ok {
+				if safeURLPattern.MatchString(url) {
+					r.out.WriteString(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener noreferrer">`)
+					r.span(s, i+1, label)
+					r.out.WriteString("</a>")
+				} else {
+					r.span(s, i+1, label)
+				}
+				i = next
+				continue
+			}
+
+		case rest[0] == '*' || (rest[0] == '_' && wordStart):
+			delimiter, tag := rest[:1], "em"
+			if strings.HasPrefix(rest[1:], delimiter) {
+				delimiter, tag = rest[:2], "strong"
+			}
+			inner := i + len(delimiter)
+			close := s.emphasis[delimiter][inner]
+			if close > inner && close+len(delimiter) <= end && !unicode.IsSpace(rune(text[inner])) && !unicode.IsSpace(rune(text[close-1])) {
+				r.out.WriteString("<" + tag + ">")
+				r.span(s, inner, close)
+				r.out.WriteString("</" + tag + ">")
+				i = close + len(delimiter)
+				continue
+			}
+
+		case rest[0] == '@' && wordStart:
+			if match := mentionPattern.FindStringSubmatch(rest); match != nil {
+				username := match[1]
+				r.out.WriteString(`<span class="mention" data-username="` + html.EscapeString(username) + `">@` + html.EscapeString(username) + "</span>")
+				if !slices.Contains(r.mentions, username) {
+					r.mentions = append(r.mentions, username)
+				}
+				i += len(match[0])
+				continue
+			}
+
+		case rest[0] >= 'A' && rest[0] <= 'Z' && wordStart:
+			if key := taskKeyPattern.FindString(rest); key != "" && (len(key) == len(rest) || !isWordByte(rest[len(key)])) {
+				r.out.WriteString(`<span class="task-ref" data-key="` + key + `">` + key + "</span>")
+				if !slices.Contains(r.taskKeys, key) {
+					r.taskKeys = append(r.taskKeys, key)
+				}
+				i += len(key)
+				continue
+			}
+
+		case rest[0] == 'h' && wordStart:
+			if url := autolinkPattern.FindString(rest); url != "" {
+				url = trailingURLPattern.ReplaceAllString(url, "")
+				r.out.WriteString(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener noreferrer">` + html.EscapeString(url) + "</a>")
+				i += len(url)
+				continue
+			}
+
+		case rest[0] == '\n':
+			r.out.WriteString("<br>\n")
+			i++
+			continue
+		}
+
+		// Anything else is text, one character at a time.
+		_, size := utf8.DecodeRuneInString(rest)
+		r.out.WriteString(html.EscapeString(rest[:size]))
+		i += size
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
@@ -0,0 +1,63 @@
+package markdown_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/markdown"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"paragraph", "Hello\nworld", "<p>Hello<br>\nworld</p>\n"},
+		{"heading", "## Steps ##", "<h2>Steps</h2>\n"},
+		{"emphasis", "**bold** and *italic* and snake_case_name", "<p><strong>bold</strong> and <em>italic</em> and snake_case_name</p>\n"},
+		{"code", "Run `rm -rf <dir>`", "<p>Run <code>rm -rf &lt;dir&gt;</code></p>\n"},
+		{"code block", "```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{"list", "- one\n- two\n\n1. first", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n</ol>\n"},
+		{"quote", "> quoted\n> text", "<blockquote>\n<p>quoted<br>\ntext</p>\n</blockquote>\n"},
+		{"link", "[docs](https://example.com/a_b)", "<p><a href=\"https://example.com/a_b\" rel=\"nofollow noopener noreferrer\">docs</a></p>\n"},
+		{"autolink", "See https://example.com/x.", "<p>See <a href=\"https://example.com/x\" rel=\"nofollow noopener noreferrer\">https://example.com/x</a>.</p>\n"},
+		{"unsafe link", "[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"html", "<script>alert(1)</script><img src=x onerror=alert(1)>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"mention", "cc @alice.", "<p>cc <span class=\"mention\" data-username=\"alice\">@alice</span>.</p>\n"},
+		{"task key", "Fixed in OMNI-42", "<p>Fixed in <span class=\"task-ref\" data-key=\"OMNI-42\">OMNI-42</span></p>\n"},
+		{"unclosed", "``a` [b](c d) *e [f]", "<p>``a` [b](c d) *e [f]</p>\n"},
+		{"nested", "[**bold `code`**](https://example.com/(x)) [*a*", "<p><a href=\"https://example.com/(x)\" rel=\"nofollow noopener noreferrer\"><strong>bold <code>code</code></strong></a> [<em>a</em></p>\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := markdown.Parse(test.source)
+			if document.HTML != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, document.HTML)
+			}
+		})
+	}
+}
+
+func TestParseReferences(t *testing.T) {
+	document := markdown.Parse("@alice see OMNI-1 and OMNI-2, @bob too.\n\nMail bob@example.com, @alice. `@carol OMNI-3`\n```\n@dave OMNI-4\n```\nNot a key: omni-5, XOMNI-6a")
+
+	if !slices.Equal(document.Mentions, []string{"alice", "bob"}) {
+		t.Errorf("expected alice and bob to be mentioned, got %v", document.Mentions)
+	}
+	if !slices.Equal(document.TaskKeys, []string{"OMNI-1", "OMNI-2"}) {
+		t.Errorf("expected OMNI-1 and OMNI-2 to be referenced, got %v", document.TaskKeys)
+	}
+	if strings.Contains(document.HTML, `data-username="example.com"`) {
+		t.Errorf("expected email addresses not to be mentions, got %s", document.HTML)
+	}
+}
+
+func TestParseUnclosed(t *testing.T) {
+	// Unclosed delimiters are only scanned once, parsing would otherwise be quadratic.
+	for _, delimiter := range []string{"[", "[]", "](", "`", "``x", "*", "**", "_ ", "(["} {
+		markdown.Parse(strings.Repeat(delimiter, 1<<17))
+	}
+}
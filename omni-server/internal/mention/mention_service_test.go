@@ -0,0 +1,144 @@
+package mention_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/link"
+	"github.com/khaossystems/omni-server/internal/mention"
+	"github.com/khaossystems/omni-server/internal/notification"
+	"github.com/khaossystems/omni-server/internal/participant"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMentions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	commentRepository := krest_orm.NewGenericPostgresRepository[models.Comment](db)
+	taskLinkRepository := krest_orm.NewGenericPostgresRepository[models.TaskLink](db)
+	notificationRepository := krest_orm.NewGenericPostgresRepository[models.Notification](db)
+	preferencesRepository := krest_orm.NewGenericPostgresRepository[models.NotificationPreferences](db)
+	policy := authz.NewPolicy(membershipRepository)
+	authorized := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	links := link.NewLinkService(taskLinkRepository, link.NewLinkRepository(db), authorized, policy, audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db)))
+	notifier := notification.NewNotifier(notificationRepository, preferencesRepository, participant.NewWatcherRepository(db), taskRepository, policy)
+	mentioner := mention.NewMentioner(userRepository, authorized, links, notifier)
+	tasks := krest_orm.NewRelationService[models.Task](mention.NewTaskService(authorized, mentioner), mention.DescriptionHTMLRelation())
+	comments := comment.NewCommentService(commentRepository, authorized, policy, audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db)))
+	comments.OnCreate(mentioner.CommentHook())
+	notifications := notification.NewNotificationService(notificationRepository, preferencesRepository)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	secret, err := projectRepository.Create(ctx, models.Project{Name: "Secret", Key: "SEC"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	secretTask, err := taskRepository.Create(ctx, models.Task{Summary: "Secret", Key: "SEC-1", ProjectID: secret.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Carol isn't a member of the project. Users are mentioned by their username, not their name.
+	users := map[string]context.Context{}
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		username := strings.ToLower(name)
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: username, Email: username + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if name != "Carol" {
+			err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleMember)
+			if err != nil {
+				t.Fatalf("AddMember failed: %v", err)
+			}
+		}
+		users[name] = auth.WithUser(ctx, user)
+	}
+	alice, bob, carol := users["Alice"], users["Bob"], users["Carol"]
+
+	referenced, err := tasks.Create(alice, models.Task{Summary: "Login", Key: "OMNI-1", ProjectID: project.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	task, err := tasks.Create(alice, models.Task{Summary: "Logout", Key: "OMNI-2", ProjectID: project.UUID, Description: "@bob @carol, like **OMNI-1** but not SEC-1 or `@dave`"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Editing the description doesn't notify the users mentioned before again.
+	task.Description += "\n\nThanks @bob"
+	_, err = tasks.Update(alice, task.UUID, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	_, err = comments.Create(alice, referenced.UUID, models.Comment{Body: "@bob, see OMNI-2"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	received, err := notifications.List(bob, krest.CollectionQuery{}, false)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 2 || received[0].Message != "Alice mentioned you in a comment on OMNI-1" || received[1].Message != "Alice mentioned you in the description of OMNI-2" {
+		t.Errorf("expected bob to be notified of both mentions once, got %+v", received)
+	}
+	received, err = notifications.List(carol, krest.CollectionQuery{}, false)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("expected carol not to be notified of a task she can't see, got %+v", received)
+	}
+
+	// OMNI-1 and OMNI-2 reference each other, the secret task isn't linked.
+	linked, err := links.List(alice, referenced.UUID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(linked.MentionedIn) != 1 || linked.MentionedIn[0].TargetID != task.UUID || len(linked.Mentions) != 1 || linked.Mentions[0].TargetID != task.UUID {
+		t.Errorf("expected OMNI-1 to mention and be mentioned in OMNI-2, got %+v", linked)
+	}
+	secretLinks, err := taskLinkRepository.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "target_id", Operator: krest.FilterEqual, Value: secretTask.UUID}},
+	})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(secretLinks) != 0 {
+		t.Errorf("expected the task alice can't see not to be linked, got %+v", secretLinks)
+	}
+
+	// The description is rendered as HTML on request.
+	rendered, err := tasks.Get(alice, task.UUID, krest.ResourceQuery{Expand: []string{"description_html"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if rendered.DescriptionHTML == nil || !strings.Contains(*rendered.DescriptionHTML, `<strong><span class="task-ref" data-key="OMNI-1">OMNI-1</span></strong>`) {
+		t.Errorf("expected the description to be rendered, got %v", rendered.DescriptionHTML)
+	}
+}
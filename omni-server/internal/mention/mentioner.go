@@ -0,0 +1,100 @@
+package mention
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/link"
+	"github.com/khaossystems/omni-server/internal/markdown"
+	"github.com/khaossystems/omni-server/internal/notification"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Mentioner acts on the references of descriptions and comments: it notifies the users mentioned with @username,
+// and links the tasks referenced by key, e.g. OMNI-42, so they list the task as mentioned-in.
+// Only users who can see the task are notified, and only tasks the author can see are linked.
+type Mentioner struct {
+	users    krest.Repository[models.User]
+	tasks    krest.Service[models.Task]
+	links    *link.LinkService
+	notifier *notification.Notifier
+}
+
+// The task service must authorize reading tasks, so references to tasks the author can't see are ignored.
+func NewMentioner(users krest.Repository[models.User], tasks krest.Service[models.Task], links *link.LinkService, notifier *notification.Notifier) *Mentioner {
+	return &Mentioner{users: users, tasks: tasks, links: links, notifier: notifier}
+}
+
+// Mention acts on the references of a text of a task, e.g. its description, that weren't in its previous version.
+// where describes the text in notifications, e.g. "a comment on".
+func (m *Mentioner) Mention(ctx context.Context, task models.Task, text string, previous string, where string) error {
+	document, before := markdown.Parse(text), markdown.Parse(previous)
+
+	mentions := []string{}
+	for _, username := range document.Mentions {
+		if !slices.Contains(before.Mentions, username) {
+			mentions = append(mentions, username)
+		}
+	}
+	err := m.notify(ctx, task, mentions, where)
+	if err != nil {
+		return err
+	}
+
+	if len(document.TaskKeys) == 0 {
+		return nil
+	}
+	referenced, err := m.tasks.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "key", Operator: krest.FilterIn, Value: document.TaskKeys}},
+	})
+	if err != nil {
+		return err
+	}
+	targetIDs := []uuid.UUID{}
+	for _, target := range referenced {
+		targetIDs = append(targetIDs, target.UUID)
+	}
+	return m.links.Mention(ctx, task.UUID, targetIDs)
+}
+
+// Notifies the users with the mentioned usernames, the notifier leaves out those who can't see the task.
+func (m *Mentioner) notify(ctx context.Context, task models.Task, mentions []string, where string) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	users, err := m.users.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "username", Operator: krest.FilterIn, Value: mentions}},
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients := []uuid.UUID{}
+	for _, user := range users {
+		recipients = append(recipients, user.UUID)
+	}
+
+	m.notifier.Notify(ctx, models.NotificationMentioned, task, recipients, fmt.Sprintf("%s mentioned you in %s %s", actorName(ctx), where, task.Key))
+	return nil
+}
+
+// CommentHook acts on the references of comments.
+func (m *Mentioner) CommentHook() comment.Hook {
+	return func(ctx context.Context, task models.Task, created models.Comment) error {
+		return m.Mention(ctx, task, created.Body, "", "a comment on")
+	}
+}
+
+// Returns the name of the authenticated user, for messages.
+func actorName(ctx context.Context) string {
+	if actor, ok := auth.UserFromContext(ctx); ok {
+		return actor.Name
+	}
+	return "Someone"
+}
@@ -0,0 +1,72 @@
+package mention
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/markdown"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, acting on the references of descriptions when they change.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+	mentioner *Mentioner
+}
+
+func NewTaskService(service krest.Service[models.Task], mentioner *Mentioner) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, mentioner: mentioner}
+}
+
+func (s *TaskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	created, err := s.Service.Create(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	err = s.mentioner.Mention(ctx, created, created.Description, "", "the description of")
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return created, nil
+}
+
+func (s *TaskService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"description"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	updated, err := s.Service.Update(ctx, id, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if updated.Description != current.Description {
+		err = s.mentioner.Mention(ctx, updated, updated.Description, current.Description, "the description of")
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return updated, nil
+}
+
+// DescriptionHTMLRelation renders the markdown of descriptions to sanitized HTML, when requested with ?fields=description_html.
+func DescriptionHTMLRelation() krest_orm.Relation[models.Task] {
+	return krest_orm.Relation[models.Task]{
+		Field:  "description_html",
+		Expand: []string{"description"},
+		Load: func(ctx context.Context, tasks []models.Task) error {
+			for i := range tasks {
+				html := markdown.Parse(tasks[i].Description).HTML
+				tasks[i].DescriptionHTML = &html
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,202 @@
+package notification
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/mail"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// Emailer emails notifications through the mail queue, as they happen or in a daily digest, as users prefer.
+type Emailer struct {
+	queue         *mail.Queue
+	templates     *mail.Templates
+	notifications krest.Repository[models.Notification]
+	preferences   krest.Repository[models.NotificationPreferences]
+	users         krest.Repository[models.User]
+	tasks         krest.Repository[models.Task]
+}
+
+func NewEmailer(queue *mail.Queue, notifications krest.Repository[models.Notification], preferences krest.Repository[models.NotificationPreferences], users krest.Repository[models.User], tasks krest.Repository[models.Task]) *Emailer {
+	files, err := fs.Sub(templateFiles, "templates")
+	if err != nil {
+		panic(err)
+	}
+	templates, err := mail.ParseTemplates(files)
+	if err != nil {
+		panic(fmt.Sprintf("invalid email templates: %v", err))
+	}
+
+	return &Emailer{queue: queue, templates: templates, notifications: notifications, preferences: preferences, users: users, tasks: tasks}
+}
+
+// Hook emails notifications of users wanting them immediately.
+func (e *Emailer) Hook() Hook {
+	return func(ctx context.Context, notification models.Notification) error {
+		preferences, err := Preferences(ctx, e.preferences, notification.UserID)
+		if err != nil {
+			return err
+		}
+		if preferences.EmailDelivery() != models.NotificationEmailImmediate {
+			return nil
+		}
+
+		user, err := e.users.Get(ctx, notification.UserID, krest.ResourceQuery{})
+		if err != nil {
+			return err
+		}
+		task, err := e.tasks.Get(ctx, notification.TaskID, krest.ResourceQuery{Expand: []string{"summary"}})
+		if err != nil {
+			return err
+		}
+
+		message, err := e.templates.Render("notification", user.Email, notification.Message, struct {
+			User         models.User
+			Task         models.Task
+			Notification models.Notification
+		}{user, task, notification})
+		if err != nil {
+			return fmt.Errorf("failed to render notification email: %v", err)
+		}
+
+		err = e.queue.Enqueue(ctx, message)
+		if err != nil {
+			return err
+		}
+		return e.markEmailed(ctx, []models.Notification{notification})
+	}
+}
+
+// DigestPeriod is how often users wanting a digest are emailed one, at most.
+const DigestPeriod = 24 * time.Hour
+
+// RunDigests emails the digests due at an interval, until the context is done.
+// When users were last emailed a digest is stored, so restarts don't delay or repeat digests.
+func (e *Emailer) RunDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := e.Digests(ctx)
+			if err != nil {
+				log.Printf("Failed to email notification digests: %v", err)
+			} else if sent > 0 {
+				log.Printf("Emailed %d notification digests", sent)
+			}
+		}
+	}
+}
+
+// Digests emails the users wanting a digest their notifications not emailed yet, across organizations,
+// unless they were emailed a digest within the DigestPeriod. Returns the number of digests emailed.
+func (e *Emailer) Digests(ctx context.Context) (int, error) {
+	ctx = krest_orm.WithoutTenant(ctx)
+	now := time.Now().UTC()
+
+	preferences, err := e.preferences.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "email", Operator: krest.FilterEqual, Value: models.NotificationEmailDigest}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	due := map[uuid.UUID]models.NotificationPreferences{}
+	userIDs := []uuid.UUID{}
+	for _, p := range preferences {
+		if p.LastDigestAt != nil && now.Sub(*p.LastDigestAt) < DigestPeriod {
+			continue
+		}
+		due[p.UserID] = p
+		userIDs = append(userIDs, p.UserID)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	pending, err := e.notifications.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{
+			{Field: "user_id", Operator: krest.FilterIn, Value: userIDs},
+			{Field: "emailed_at", Operator: krest.FilterIn, Value: []*time.Time{nil}},
+		},
+		Sort: []krest.Sort{{Field: "created_at"}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	byUser := map[uuid.UUID][]models.Notification{}
+	for _, notification := range pending {
+		byUser[notification.UserID] = append(byUser[notification.UserID], notification)
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		notifications := byUser[userID]
+		if len(notifications) == 0 {
+			continue
+		}
+
+		user, err := e.users.Get(ctx, userID, krest.ResourceQuery{})
+		if err != nil {
+			return sent, err
+		}
+
+		subject := fmt.Sprintf("Your Omni digest: %d notifications", len(notifications))
+		if len(notifications) == 1 {
+			subject = "Your Omni digest: 1 notification"
+		}
+		message, err := e.templates.Render("digest", user.Email, subject, struct {
+			User          models.User
+			Notifications []models.Notification
+		}{user, notifications})
+		if err != nil {
+			return sent, fmt.Errorf("failed to render digest email: %v", err)
+		}
+
+		err = e.queue.Enqueue(ctx, message)
+		if err != nil {
+			return sent, err
+		}
+		err = e.markEmailed(ctx, notifications)
+		if err != nil {
+			return sent, err
+		}
+		p := due[userID]
+		p.LastDigestAt = &now
+		_, err = e.preferences.Update(ctx, p.UUID, p)
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Marks notifications as emailed, so digests leave them out.
+func (e *Emailer) markEmailed(ctx context.Context, notifications []models.Notification) error {
+	now := time.Now().UTC()
+	for _, notification := range notifications {
+		notification.EmailedAt = &now
+		_, err := e.notifications.Update(ctx, notification.UUID, notification)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package notification
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// MarkAllReadResponse tells how many notifications were marked as read.
+type MarkAllReadResponse struct {
+	Marked int `json:"marked"`
+}
+
+// NotificationHandler implements the http api for the notifications of the authenticated user. [/v1/me/notifications]
+type NotificationHandler struct {
+	service *NotificationService
+}
+
+func NewNotificationHandler(service *NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// List lists the notifications of the authenticated user, only the unread ones with unread=true. [GET /v1/me/notifications]
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	unread := false
+	if value := r.URL.Query().Get("unread"); value != "" {
+		unread, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "invalid unread, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Notification](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the notifications
+	notifications, err := h.service.List(r.Context(), query, unread)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, notifications, len(notifications), len(notifications), query, metaQuery)
+}
+
+// MarkRead marks a notification as read. [POST /v1/me/notifications/{uuid}:read]
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Mark the notification
+	notification, err := h.service.MarkRead(r.Context(), id)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, notification, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// MarkAllRead marks every unread notification as read. [POST /v1/me/notifications:read-all]
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	// Mark the notifications
+	marked, err := h.service.MarkAllRead(r.Context())
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, MarkAllReadResponse{Marked: marked}, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Preferences returns the notification preferences of the authenticated user. [GET /v1/me/notification-preferences]
+func (h *NotificationHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	// Get the preferences
+	preferences, err := h.service.Preferences(r.Context())
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, preferences, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// UpdatePreferences changes the notification preferences of the authenticated user, e.g. {"commented": false, "email": "digest"}. [PATCH /v1/me/notification-preferences]
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	// Get the current preferences, the request body is applied on top of them.
+	preferences, err := h.service.Preferences(r.Context())
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	current := preferences
+
+	// Parse the request body.
+	err = krest.DecodeRequestBody(w, r, &preferences, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields can't be changed.
+	krest.CopyReadOnlyFields(&preferences, current)
+
+	// Validate the preferences.
+	err = krest.ValidateResource(r.Context(), preferences, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Update the preferences
+	updated, err := h.service.UpdatePreferences(r.Context(), preferences)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, updated, krest.ResourceQuery{}, krest.MetaQuery{})
+}
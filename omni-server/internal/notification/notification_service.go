@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// NotificationService manages the notifications and notification preferences of the authenticated user.
+// Users only see their own notifications.
+type NotificationService struct {
+	notifications krest.Repository[models.Notification]
+	preferences   krest.Repository[models.NotificationPreferences]
+}
+
+func NewNotificationService(notifications krest.Repository[models.Notification], preferences krest.Repository[models.NotificationPreferences]) *NotificationService {
+	return &NotificationService{notifications: notifications, preferences: preferences}
+}
+
+// List lists the notifications of the authenticated user, newest first unless sorted otherwise.
+func (s *NotificationService) List(ctx context.Context, query krest.CollectionQuery, unread bool) ([]models.Notification, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	query.Filters = append(query.Filters, krest.Filter{Field: "user_id", Operator: krest.FilterEqual, Value: user.UUID})
+	if unread {
+		query.Filters = append(query.Filters, krest.Filter{Field: "read_at", Operator: krest.FilterIn, Value: []*time.Time{nil}})
+	}
+	if len(query.Sort) == 0 {
+		query.Sort = []krest.Sort{{Field: "created_at", Descending: true}}
+	}
+	return s.notifications.List(ctx, query)
+}
+
+// MarkRead marks a notification of the authenticated user as read, and returns it.
+func (s *NotificationService) MarkRead(ctx context.Context, id uuid.UUID) (models.Notification, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Notification{}, auth.ErrUnauthenticated
+	}
+
+	notification, err := s.notifications.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Notification{}, err
+	}
+	if notification.UserID != user.UUID {
+		return models.Notification{}, krest.NewError(http.StatusNotFound, "notifications %s not found", id)
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now().UTC()
+	notification.ReadAt = &now
+	return s.notifications.Update(ctx, id, notification)
+}
+
+// MarkAllRead marks every unread notification of the authenticated user as read, and returns how many were marked.
+func (s *NotificationService) MarkAllRead(ctx context.Context) (int, error) {
+	unread, err := s.List(ctx, krest.CollectionQuery{}, true)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for _, notification := range unread {
+		notification.ReadAt = &now
+		_, err = s.notifications.Update(ctx, notification.UUID, notification)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(unread), nil
+}
+
+// Preferences returns the notification preferences of the authenticated user.
+func (s *NotificationService) Preferences(ctx context.Context) (models.NotificationPreferences, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.NotificationPreferences{}, auth.ErrUnauthenticated
+	}
+
+	return Preferences(ctx, s.preferences, user.UUID)
+}
+
+// UpdatePreferences replaces the notification preferences of the authenticated user.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, preferences models.NotificationPreferences) (models.NotificationPreferences, error) {
+	current, err := s.Preferences(ctx)
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+
+	preferences.UUID = current.UUID
+	preferences.UserID = current.UserID
+	preferences.LastDigestAt = current.LastDigestAt
+	if current.UUID == uuid.Nil {
+		return s.preferences.Create(ctx, preferences)
+	}
+	return s.preferences.Update(ctx, current.UUID, preferences)
+}
@@ -0,0 +1,291 @@
+package notification_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/mail"
+	"github.com/khaossystems/omni-server/internal/notification"
+	"github.com/khaossystems/omni-server/internal/participant"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestNotifications(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	statusRepository := krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	commentRepository := krest_orm.NewGenericPostgresRepository[models.Comment](db)
+	notificationRepository := krest_orm.NewGenericPostgresRepository[models.Notification](db)
+	preferencesRepository := krest_orm.NewGenericPostgresRepository[models.NotificationPreferences](db)
+	watcherRepository := participant.NewWatcherRepository(db)
+	policy := authz.NewPolicy(membershipRepository)
+	authorized := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	notifier := notification.NewNotifier(notificationRepository, preferencesRepository, watcherRepository, taskRepository, policy)
+	tasks := notification.NewTaskService(participant.NewTaskService(authorized, watcherRepository), notifier, statusRepository)
+	comments := comment.NewCommentService(commentRepository, authorized, policy, audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db)))
+	comments.OnCreate(notifier.CommentHook())
+	notifications := notification.NewNotificationService(notificationRepository, preferencesRepository)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	done, err := statusRepository.Create(ctx, models.Status{ProjectID: project.UUID, Name: "Done", Category: models.StatusCategoryDone})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	users, ids := map[string]context.Context{}, map[string]uuid.UUID{}
+	for _, name := range []string{"alice", "bob"} {
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleMember)
+		if err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+		users[name], ids[name] = auth.WithUser(ctx, user), user.UUID
+	}
+	alice, bob := users["alice"], users["bob"]
+
+	// Alice assigns a task to Bob, and moves it to done.
+	bobID := ids["bob"]
+	task, err := tasks.Create(alice, models.Task{Summary: "Task", Key: "OMNI-1", ProjectID: project.UUID, AssigneeID: &bobID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	task, err = tasks.Get(alice, task.UUID, krest.ResourceQuery{Expand: []string{"summary", "project_id", "assignee_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	task.StatusID = &done.UUID
+	_, err = tasks.Update(alice, task.UUID, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Bob doesn't want to hear about comments.
+	preferences, err := notifications.Preferences(bob)
+	if err != nil {
+		t.Fatalf("Preferences failed: %v", err)
+	}
+	preferences.Commented = false
+	_, err = notifications.UpdatePreferences(bob, preferences)
+	if err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	_, err = comments.Create(alice, task.UUID, models.Comment{Body: "Shipped"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Bob comments, Alice watches the task she reported.
+	_, err = comments.Create(bob, task.UUID, models.Comment{Body: "Thanks"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	received, err := notifications.List(bob, krest.CollectionQuery{}, true)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 2 || received[0].Message != "alice moved OMNI-1 to Done" || received[1].Message != "alice assigned OMNI-1 to you" {
+		t.Fatalf("expected bob to be notified of the assignment and the status change, got %+v", received)
+	}
+
+	received, err = notifications.List(alice, krest.CollectionQuery{}, true)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 1 || received[0].Kind != models.NotificationCommented {
+		t.Errorf("expected alice to be notified of the comment of bob only, got %+v", received)
+	}
+
+	// Notifications of other users can't be read.
+	_, err = notifications.MarkRead(alice, received[0].UUID)
+	if err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+	_, err = notifications.MarkRead(bob, received[0].UUID)
+	if err == nil {
+		t.Errorf("expected marking a notification of another user to fail")
+	}
+
+	marked, err := notifications.MarkAllRead(bob)
+	if err != nil {
+		t.Fatalf("MarkAllRead failed: %v", err)
+	}
+	if marked != 2 {
+		t.Errorf("expected 2 notifications to be marked as read, got %d", marked)
+	}
+	received, err = notifications.List(bob, krest.CollectionQuery{}, true)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("expected no unread notifications, got %+v", received)
+	}
+}
+
+func TestEmails(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	notificationRepository := krest_orm.NewGenericPostgresRepository[models.Notification](db)
+	preferencesRepository := krest_orm.NewGenericPostgresRepository[models.NotificationPreferences](db)
+	emailRepository := krest_orm.NewGenericPostgresRepository[models.Email](db)
+	policy := authz.NewPolicy(membershipRepository)
+	mailer := mail.NewMemoryMailer()
+	queue := mail.NewQueue(emailRepository, mailer, mail.DefaultMaxAttempts, 0)
+	notifier := notification.NewNotifier(notificationRepository, preferencesRepository, participant.NewWatcherRepository(db), taskRepository, policy)
+	emailer := notification.NewEmailer(queue, notificationRepository, preferencesRepository, userRepository, taskRepository)
+	notifier.OnNotify(emailer.Hook())
+	notifications := notification.NewNotificationService(notificationRepository, preferencesRepository)
+
+	// Dave isn't a member of the project.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	users, contexts := map[string]models.User{}, map[string]context.Context{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if name != "dave" {
+			err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleViewer)
+			if err != nil {
+				t.Fatalf("AddMember failed: %v", err)
+			}
+		}
+		users[name], contexts[name] = user, auth.WithUser(ctx, user)
+	}
+	alice := contexts["alice"]
+	task, err := taskRepository.Create(ctx, models.Task{Summary: "Fix <login>", Key: "OMNI-1", ProjectID: project.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Bob wants a digest, Carol no emails at all.
+	for name, email := range map[string]string{"bob": models.NotificationEmailDigest, "carol": models.NotificationEmailOff} {
+		user := contexts[name]
+		preferences, err := notifications.Preferences(user)
+		if err != nil {
+			t.Fatalf("Preferences failed: %v", err)
+		}
+		preferences.Email = email
+		_, err = notifications.UpdatePreferences(user, preferences)
+		if err != nil {
+			t.Fatalf("UpdatePreferences failed: %v", err)
+		}
+	}
+
+	// The mail server is down, notifying still works.
+	mailer.Err = errors.New("connection refused")
+	recipients := []uuid.UUID{users["bob"].UUID, users["carol"].UUID, users["alice"].UUID, users["dave"].UUID}
+	for _, message := range []string{"alice assigned OMNI-1 to you", "alice commented on OMNI-1"} {
+		notifier.Notify(alice, models.NotificationCommented, task, recipients, message)
+	}
+	notifier.Notify(ctx, models.NotificationCommented, task, []uuid.UUID{users["alice"].UUID}, "Someone commented on OMNI-1")
+	received, err := notifications.List(contexts["dave"], krest.CollectionQuery{}, false)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("expected dave not to be notified of a task of another project, got %+v", received)
+	}
+	sent, err := queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("expected no email to be sent while the mail server is down, got %d", sent)
+	}
+
+	// Alice gets her notification right away, once the mail server is back.
+	mailer.Err = nil
+	_, err = queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0].To != "alice@example.com" || messages[0].Subject != "Someone commented on OMNI-1" {
+		t.Fatalf("expected alice to be emailed her notification, got %+v", messages)
+	}
+	if !strings.Contains(messages[0].HTML, "Fix &lt;login&gt;") || !strings.Contains(messages[0].Text, "Fix <login>") {
+		t.Errorf("expected the task summary to be escaped in html only, got %+v", messages[0])
+	}
+
+	// Bob gets both of his notifications in a digest, once.
+	for range 2 {
+		_, err = emailer.Digests(context.Background())
+		if err != nil {
+			t.Fatalf("Digests failed: %v", err)
+		}
+	}
+	_, err = queue.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	messages = mailer.Messages()
+	if len(messages) != 2 || messages[1].To != "bob@example.com" || messages[1].Subject != "Your Omni digest: 2 notifications" {
+		t.Fatalf("expected bob to be emailed a digest, got %+v", messages)
+	}
+	if !strings.Contains(messages[1].Text, "alice assigned OMNI-1 to you") || !strings.Contains(messages[1].Text, "alice commented on OMNI-1") {
+		t.Errorf("expected the digest to list the notifications, got %s", messages[1].Text)
+	}
+
+	// Bob's next digest waits for the digest period, even if his preferences change.
+	preferences, err := notifications.Preferences(contexts["bob"])
+	if err != nil {
+		t.Fatalf("Preferences failed: %v", err)
+	}
+	if preferences.LastDigestAt == nil {
+		t.Fatalf("expected the time of the digest to be stored")
+	}
+	preferences.LastDigestAt = nil
+	_, err = notifications.UpdatePreferences(contexts["bob"], preferences)
+	if err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	notifier.Notify(alice, models.NotificationCommented, task, []uuid.UUID{users["bob"].UUID}, "alice commented on OMNI-1 again")
+	sent, err = emailer.Digests(context.Background())
+	if err != nil {
+		t.Fatalf("Digests failed: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("expected no digest within the digest period, got %d", sent)
+	}
+}
@@ -0,0 +1,157 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/participant"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Notifier turns task events into notifications of the users involved.
+// The user causing an event isn't notified, neither are users who can't see the task or whose preferences leave out the kind.
+type Notifier struct {
+	notifications krest.Repository[models.Notification]
+	preferences   krest.Repository[models.NotificationPreferences]
+	watchers      *participant.WatcherRepository
+	tasks         krest.Repository[models.Task]
+	policy        *authz.Policy
+	hooks         []Hook
+}
+
+// Hook is called with each notification created, e.g. to email it.
+type Hook func(ctx context.Context, notification models.Notification) error
+
+func NewNotifier(notifications krest.Repository[models.Notification], preferences krest.Repository[models.NotificationPreferences], watchers *participant.WatcherRepository, tasks krest.Repository[models.Task], policy *authz.Policy) *Notifier {
+	return &Notifier{notifications: notifications, preferences: preferences, watchers: watchers, tasks: tasks, policy: policy}
+}
+
+// OnNotify adds a hook called with each notification created.
+func (n *Notifier) OnNotify(hook Hook) {
+	n.hooks = append(n.hooks, hook)
+}
+
+// Notify notifies users of an event on a task, caused by the authenticated user.
+// Events are notified after they happened, failures are logged rather than failing the event.
+func (n *Notifier) Notify(ctx context.Context, kind string, task models.Task, recipients []uuid.UUID, message string) {
+	var actorID *uuid.UUID
+	if actor, ok := auth.UserFromContext(ctx); ok {
+		actorID = &actor.UUID
+	}
+
+	notified := map[uuid.UUID]bool{}
+	for _, recipient := range recipients {
+		if notified[recipient] || (actorID != nil && recipient == *actorID) {
+			continue
+		}
+		notified[recipient] = true
+
+		err := n.notify(ctx, kind, task, recipient, actorID, message)
+		if err != nil {
+			log.Printf("Failed to notify user %s of %s on task %s: %v", recipient, kind, task.UUID, err)
+		}
+	}
+}
+
+// Notifies a user who can see the task and wants the kind of notification.
+func (n *Notifier) notify(ctx context.Context, kind string, task models.Task, recipient uuid.UUID, actorID *uuid.UUID, message string) error {
+	role, err := n.policy.Role(ctx, task.ProjectID, recipient)
+	if err != nil {
+		return err
+	}
+	if role == authz.RoleNone {
+		return nil
+	}
+
+	preferences, err := Preferences(ctx, n.preferences, recipient)
+	if err != nil {
+		return err
+	}
+	if !preferences.Wants(kind) {
+		return nil
+	}
+
+	created, err := n.notifications.Create(ctx, models.Notification{
+		UserID:    recipient,
+		ActorID:   actorID,
+		TaskID:    task.UUID,
+		Kind:      kind,
+		Message:   message,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range n.hooks {
+		err = hook(ctx, created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Participants returns the users involved in a task, its watchers and its assignee.
+func (n *Notifier) Participants(ctx context.Context, task models.Task) ([]uuid.UUID, error) {
+	watchers, err := n.watchers.List(ctx, []uuid.UUID{task.UUID})
+	if err != nil {
+		return nil, err
+	}
+
+	participants := watchers[task.UUID]
+	if task.AssigneeID != nil && !slices.Contains(participants, *task.AssigneeID) {
+		participants = append(participants, *task.AssigneeID)
+	}
+	return participants, nil
+}
+
+// CommentHook notifies the participants of a task of comments on it.
+func (n *Notifier) CommentHook() comment.Hook {
+	return func(ctx context.Context, task models.Task, created models.Comment) error {
+		task, err := n.tasks.Get(ctx, task.UUID, krest.ResourceQuery{Expand: []string{"project_id", "assignee_id"}})
+		if err != nil {
+			return err
+		}
+
+		participants, err := n.Participants(ctx, task)
+		if err != nil {
+			return err
+		}
+
+		n.Notify(ctx, models.NotificationCommented, task, participants, fmt.Sprintf("%s commented on %s", actorName(ctx), task.Key))
+		return nil
+	}
+}
+
+// Returns the name of the authenticated user, for messages.
+func actorName(ctx context.Context) string {
+	if actor, ok := auth.UserFromContext(ctx); ok {
+		return actor.Name
+	}
+	return "Someone"
+}
+
+// Preferences returns the notification preferences of a user, users without preferences get every kind.
+func Preferences(ctx context.Context, preferences krest.Repository[models.NotificationPreferences], userID uuid.UUID) (models.NotificationPreferences, error) {
+	found, err := preferences.List(ctx, krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "user_id", Operator: krest.FilterEqual, Value: userID}},
+	})
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	if len(found) > 0 {
+		return found[0], nil
+	}
+
+	return models.NotificationPreferences{UserID: userID, Assigned: true, StatusChanged: true, Commented: true, Mentioned: true, Email: models.NotificationEmailImmediate}, nil
+}
@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, notifying assignees of their tasks and participants of status changes.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+	notifier *Notifier
+	statuses krest.Repository[models.Status]
+}
+
+func NewTaskService(service krest.Service[models.Task], notifier *Notifier, statuses krest.Repository[models.Status]) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, notifier: notifier, statuses: statuses}
+}
+
+func (s *TaskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	created, err := s.Service.Create(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if created.AssigneeID != nil {
+		s.notifyAssignee(ctx, created)
+	}
+
+	return created, nil
+}
+
+func (s *TaskService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"assignee_id", "status_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	updated, err := s.Service.Update(ctx, id, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if updated.AssigneeID != nil && !sameID(current.AssigneeID, updated.AssigneeID) {
+		s.notifyAssignee(ctx, updated)
+	}
+
+	// The task is already changed, failing to notify doesn't fail the change.
+	if updated.StatusID != nil && !sameID(current.StatusID, updated.StatusID) {
+		err = s.notifyStatusChange(ctx, updated)
+		if err != nil {
+			log.Printf("Failed to notify the status change of task %s: %v", id, err)
+		}
+	}
+
+	return updated, nil
+}
+
+// Notifies the assignee of a task of the assignment.
+func (s *TaskService) notifyAssignee(ctx context.Context, task models.Task) {
+	s.notifier.Notify(ctx, models.NotificationAssigned, task, []uuid.UUID{*task.AssigneeID}, fmt.Sprintf("%s assigned %s to you", actorName(ctx), task.Key))
+}
+
+// Notifies the participants of a task of its new status.
+func (s *TaskService) notifyStatusChange(ctx context.Context, task models.Task) error {
+	status, err := s.statuses.Get(ctx, *task.StatusID, krest.ResourceQuery{})
+	if err != nil {
+		return err
+	}
+
+	participants, err := s.notifier.Participants(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	s.notifier.Notify(ctx, models.NotificationStatusChanged, task, participants, fmt.Sprintf("%s moved %s to %s", actorName(ctx), task.Key, status.Name))
+	return nil
+}
+
+// Returns whether two optional ids are the same.
+func sameID(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
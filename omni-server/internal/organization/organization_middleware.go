@@ -0,0 +1,47 @@
+package organization
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+)
+
+// Header selecting the organization of anonymous requests.
+const OrganizationHeader = "X-Omni-Organization"
+
+// Middleware scopes requests to an organization, see krest_orm.WithTenant.
+// Authenticated users always act within their own organization. Anonymous requests can select an
+// organization using the X-Omni-Organization header, e.g. to create the first user of a new organization.
+// Must be used after the authentication middlewares.
+func (s *OrganizationService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r.WithContext(krest_orm.WithTenant(r.Context(), user.OrganizationID)))
+			return
+		}
+
+		header := r.Header.Get(OrganizationHeader)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		organizationID, err := uuid.Parse(header)
+		if err != nil {
+			krest.WriteErrorResponse(w, krest.NewError(http.StatusBadRequest, "invalid %s header: %v", OrganizationHeader, err))
+			return
+		}
+
+		// Make sure the organization exists.
+		_, err = s.service.Get(r.Context(), organizationID, krest.ResourceQuery{})
+		if err != nil {
+			krest.WriteErrorResponse(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(krest_orm.WithTenant(r.Context(), organizationID)))
+	})
+}
@@ -0,0 +1,88 @@
+package organization
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var ErrNotAdmin = krest.NewError(http.StatusForbidden, "only an organization admin can change the organization")
+
+// OrganizationService restricts organizations to the organization of the request.
+// Anyone can create an organization, and then create its first user. New organizations are created in their own tenant,
+// so wrapping services, e.g. an audit.Service, record the creation in the new organization.
+// Implements krest.Service[models.Organization]
+type OrganizationService struct {
+	service krest.Service[models.Organization]
+}
+
+func NewOrganizationService(service krest.Service[models.Organization]) *OrganizationService {
+	return &OrganizationService{service: service}
+}
+
+// Returns a not found error for organizations other than the organization of the request.
+func (s *OrganizationService) authorize(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return krest_orm.ErrMissingTenant
+	}
+
+	if tenantID != id {
+		return krest.NewError(http.StatusNotFound, "organizations %s not found", id)
+	}
+
+	return nil
+}
+
+func (s *OrganizationService) Get(ctx context.Context, id uuid.UUID, query krest.ResourceQuery) (models.Organization, error) {
+	err := s.authorize(ctx, id)
+	if err != nil {
+		return models.Organization{}, err
+	}
+
+	return s.service.Get(ctx, id, query)
+}
+
+func (s *OrganizationService) List(ctx context.Context, query krest.CollectionQuery) ([]models.Organization, error) {
+	tenantID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return nil, krest_orm.ErrMissingTenant
+	}
+
+	query.Filters = append(query.Filters, krest.Filter{Field: "uuid", Operator: krest.FilterEqual, Value: tenantID})
+	return s.service.List(ctx, query)
+}
+
+func (s *OrganizationService) Create(ctx context.Context, organization models.Organization) (models.Organization, error) {
+	organization.UUID = uuid.New()
+	return s.service.Create(krest_orm.WithTenant(ctx, organization.UUID), organization)
+}
+
+// Only admins of the organization can update it.
+func (s *OrganizationService) Update(ctx context.Context, id uuid.UUID, organization models.Organization) (models.Organization, error) {
+	current, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Organization{}, auth.ErrUnauthenticated
+	}
+
+	err := s.authorize(ctx, id)
+	if err != nil {
+		return models.Organization{}, err
+	}
+
+	if current.Role != models.UserRoleAdmin {
+		return models.Organization{}, ErrNotAdmin
+	}
+
+	return s.service.Update(ctx, id, organization)
+}
+
+// Deleting an organization deletes everything in it, this is not exposed through the api.
+func (s *OrganizationService) Delete(ctx context.Context, id uuid.UUID) error {
+	return krest.NewError(http.StatusMethodNotAllowed, "organizations can't be deleted")
+}
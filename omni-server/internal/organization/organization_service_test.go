@@ -0,0 +1,56 @@
+package organization_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/organization"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestOrganizationUpdate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	organizationRepository := krest_orm.NewGenericPostgresRepository[models.Organization](db)
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	organizations := organization.NewOrganizationService(krest_orm.NewGenericService(organizationRepository))
+
+	created, err := organizations.Create(context.Background(), models.Organization{Name: "Khaos"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	ctx := krest_orm.WithTenant(context.Background(), created.UUID)
+
+	member, err := userRepository.Create(ctx, models.User{Name: "Bob", Username: "bob", Email: "bob@example.com", Role: models.UserRoleMember})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	admin, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com", Role: models.UserRoleAdmin})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Only admins change the organization.
+	_, err = organizations.Update(auth.WithUser(ctx, member), created.UUID, models.Organization{Name: "Renamed"})
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a member, got %v", err)
+	}
+
+	updated, err := organizations.Update(auth.WithUser(ctx, admin), created.UUID, models.Organization{Name: "Renamed"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Name != "Renamed" {
+		t.Errorf("Update returned incorrect data: got %+v", updated)
+	}
+}
@@ -0,0 +1,151 @@
+package participant
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// WatcherHandler implements the http api for the watchers of tasks. [/v1/tasks/{uuid}/watchers]
+type WatcherHandler struct {
+	service *WatcherService
+}
+
+func NewWatcherHandler(service *WatcherService) *WatcherHandler {
+	return &WatcherHandler{service: service}
+}
+
+// List lists the watchers of a task. [GET /v1/tasks/{uuid}/watchers]
+func (h *WatcherHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the watchers
+	watchers, err := h.service.List(r.Context(), taskID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, watchers, len(watchers), len(watchers), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Watch adds a watcher to a task, the authenticated user without a body. [POST /v1/tasks/{uuid}/watchers]
+func (h *WatcherHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	taskID, request, ok := parseWatchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Add the watcher
+	watchers, err := h.service.Watch(r.Context(), taskID, request.UserID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, watchers, len(watchers), len(watchers), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Unwatch removes a watcher from a task, the authenticated user without a body. [DELETE /v1/tasks/{uuid}/watchers]
+func (h *WatcherHandler) Unwatch(w http.ResponseWriter, r *http.Request) {
+	taskID, request, ok := parseWatchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Remove the watcher
+	watchers, err := h.service.Unwatch(r.Context(), taskID, request.UserID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, watchers, len(watchers), len(watchers), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Returns the task of the url and the optional request body. Writes an error response if they're invalid.
+func parseWatchRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, WatchRequest, bool) {
+	var request WatchRequest
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, request, false
+	}
+
+	if r.ContentLength != 0 {
+		err = krest.DecodeRequestBody(w, r, &request, krest.DefaultDecodeOptions)
+		if err != nil {
+			krest.WriteErrorResponse(w, err)
+			return uuid.Nil, request, false
+		}
+	}
+
+	return taskID, request, true
+}
+
+// AssignedTaskHandler lists the tasks assigned to a user. [GET /v1/users/{uuid}/assigned-tasks]
+// Only tasks of projects visible to the authenticated user are listed.
+type AssignedTaskHandler struct {
+	tasks krest.Service[models.Task]
+	users krest.Repository[models.User]
+}
+
+func NewAssignedTaskHandler(tasks krest.Service[models.Task], users krest.Repository[models.User]) *AssignedTaskHandler {
+	return &AssignedTaskHandler{tasks: tasks, users: users}
+}
+
+func (h *AssignedTaskHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Task](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// The user must exist in the organization.
+	_, err = h.users.Get(r.Context(), userID, krest.ResourceQuery{})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the tasks
+	query.Filters = append(query.Filters, krest.Filter{Field: "assignee_id", Operator: krest.FilterEqual, Value: userID})
+	tasks, err := h.tasks.List(r.Context(), query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, tasks, len(tasks), len(tasks), query, metaQuery)
+}
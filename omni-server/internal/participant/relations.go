@@ -0,0 +1,74 @@
+package participant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// AssigneeRelation loads the assignee of tasks, when expanded with ?expand=assignee.
+func AssigneeRelation(users krest.Repository[models.User]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("assignee", "assignee_id", users,
+		func(task models.Task) *uuid.UUID { return task.AssigneeID },
+		func(task *models.Task, user *models.User) { task.Assignee = user },
+	)
+}
+
+// ReporterRelation loads the reporter of tasks, when expanded with ?expand=reporter.
+func ReporterRelation(users krest.Repository[models.User]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("reporter", "reporter_id", users,
+		func(task models.Task) *uuid.UUID { return task.ReporterID },
+		func(task *models.Task, user *models.User) { task.Reporter = user },
+	)
+}
+
+// WatchersRelation loads the watchers of tasks, when expanded with ?expand=watchers.
+func WatchersRelation(watchers *WatcherRepository, users krest.Repository[models.User]) krest_orm.Relation[models.Task] {
+	return krest_orm.Relation[models.Task]{
+		Field: "watchers",
+		Load: func(ctx context.Context, tasks []models.Task) error {
+			taskIDs := []uuid.UUID{}
+			for _, task := range tasks {
+				taskIDs = append(taskIDs, task.UUID)
+			}
+
+			byTask, err := watchers.List(ctx, taskIDs)
+			if err != nil {
+				return err
+			}
+
+			// Load the users of all tasks at once.
+			userIDs, seen := []uuid.UUID{}, map[uuid.UUID]bool{}
+			for _, ids := range byTask {
+				for _, id := range ids {
+					if !seen[id] {
+						seen[id] = true
+						userIDs = append(userIDs, id)
+					}
+				}
+			}
+			found, err := usersByID(ctx, users, userIDs)
+			if err != nil {
+				return err
+			}
+			byID := map[uuid.UUID]models.User{}
+			for _, user := range found {
+				byID[user.UUID] = user
+			}
+
+			for i := range tasks {
+				tasks[i].Watchers = []models.User{}
+				for _, id := range byTask[tasks[i].UUID] {
+					if user, ok := byID[id]; ok {
+						tasks[i].Watchers = append(tasks[i].Watchers, user)
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package participant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, reporting new tasks as the authenticated user, the reporter can't be chosen.
+// The reporter and the assignee of a task watch it, added in the transaction of the change of the task.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+	watchers *WatcherRepository
+}
+
+func NewTaskService(service krest.Service[models.Task], watchers *WatcherRepository) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, watchers: watchers}
+}
+
+func (s *TaskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	if user, ok := auth.UserFromContext(ctx); ok {
+		task.ReporterID = &user.UUID
+	}
+
+	var created models.Task
+	err := s.watchers.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.Service.Create(ctx, task)
+		if err != nil {
+			return err
+		}
+
+		return s.watch(ctx, created.UUID, created.ReporterID, created.AssigneeID)
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return created, nil
+}
+
+func (s *TaskService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	var updated models.Task
+	err := s.watchers.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.Service.Update(ctx, id, task)
+		if err != nil {
+			return err
+		}
+
+		return s.watch(ctx, updated.UUID, updated.AssigneeID)
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return updated, nil
+}
+
+// Adds the users to the watchers of a task.
+func (s *TaskService) watch(ctx context.Context, taskID uuid.UUID, userIDs ...*uuid.UUID) error {
+	for _, userID := range userIDs {
+		if userID == nil {
+			continue
+		}
+
+		err := s.watchers.Add(ctx, taskID, *userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
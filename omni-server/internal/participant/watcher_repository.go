@@ -0,0 +1,100 @@
+package participant
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+)
+
+// WatcherRepository stores the users watching tasks.
+// Watchers are only reachable through their task, authorize access to the task first.
+type WatcherRepository struct {
+	db *sql.DB
+}
+
+// NewWatcherRepository creates the table of the repository, after the users and tasks tables.
+func NewWatcherRepository(db *sql.DB) *WatcherRepository {
+	query := "CREATE TABLE IF NOT EXISTS task_watchers (task_id UUID NOT NULL REFERENCES tasks(uuid) ON DELETE CASCADE, user_id UUID NOT NULL REFERENCES users(uuid) ON DELETE CASCADE, PRIMARY KEY (task_id, user_id));"
+	log.Printf("creating table: %s", query)
+	_, err := db.Exec(query)
+	if err != nil {
+		log.Fatalf("failed to create table: %v", err)
+	}
+
+	return &WatcherRepository{db: db}
+}
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *WatcherRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// Add adds a watcher to a task, users already watching the task are left as is.
+func (r *WatcherRepository) Add(ctx context.Context, taskID uuid.UUID, userID uuid.UUID) error {
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO task_watchers (task_id, user_id) VALUES ($1, $2) ON CONFLICT (task_id, user_id) DO NOTHING",
+		taskID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add watcher: %v", err)
+	}
+	return nil
+}
+
+// Remove removes a watcher from a task.
+func (r *WatcherRepository) Remove(ctx context.Context, taskID uuid.UUID, userID uuid.UUID) error {
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2", taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove watcher: %v", err)
+	}
+	return nil
+}
+
+// List returns the users watching each of the tasks.
+func (r *WatcherRepository) List(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	watchers := map[uuid.UUID][]uuid.UUID{}
+	if len(taskIDs) == 0 {
+		return watchers, nil
+	}
+
+	placeholders, args := []string{}, []interface{}{}
+	for i, id := range taskIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, id)
+	}
+
+	// Only the watchers of tasks of the tenant.
+	tenant, tenantArgs, err := krest_orm.TenantCondition(ctx, "tasks", len(args)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := krest_orm.Conn(ctx, r.db).QueryContext(ctx,
+		fmt.Sprintf(`
+			SELECT task_watchers.task_id, task_watchers.user_id FROM task_watchers JOIN tasks ON tasks.uuid = task_watchers.task_id
+			WHERE task_watchers.task_id IN (%s) AND %s
+			ORDER BY task_watchers.task_id, task_watchers.user_id`,
+			strings.Join(placeholders, ", "), tenant),
+		append(args, tenantArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watchers: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, userID uuid.UUID
+		err = rows.Scan(&taskID, &userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list watchers: %v", err)
+		}
+		watchers[taskID] = append(watchers[taskID], userID)
+	}
+
+	return watchers, rows.Err()
+}
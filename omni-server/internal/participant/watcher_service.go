@@ -0,0 +1,192 @@
+package participant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// WatchRequest adds a user to the watchers of a task, the authenticated user without one.
+type WatchRequest struct {
+	UserID *uuid.UUID `json:"user_id"`
+}
+
+// WatcherService manages the watchers of tasks.
+// Everyone who can see a task can watch it, members of the project can add and remove other watchers.
+// Changes of the watchers are audited as changes of the task.
+type WatcherService struct {
+	watchers *WatcherRepository
+	tasks    krest.Service[models.Task]
+	users    krest.Repository[models.User]
+	policy   *authz.Policy
+	auditLog *audit.Log
+}
+
+// The watchers of a task, as recorded in the audit log.
+type taskWatchers struct {
+	Watchers []string `json:"watchers"`
+}
+
+// The task service must authorize reading tasks, watchers are only visible through their task.
+func NewWatcherService(watchers *WatcherRepository, tasks krest.Service[models.Task], users krest.Repository[models.User], policy *authz.Policy, auditLog *audit.Log) *WatcherService {
+	return &WatcherService{watchers: watchers, tasks: tasks, users: users, policy: policy, auditLog: auditLog}
+}
+
+// List lists the users watching a task.
+func (s *WatcherService) List(ctx context.Context, taskID uuid.UUID) ([]models.User, error) {
+	_, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.list(ctx, taskID)
+}
+
+// Watch adds a user to the watchers of a task, and returns the watchers.
+func (s *WatcherService) Watch(ctx context.Context, taskID uuid.UUID, userID *uuid.UUID) ([]models.User, error) {
+	task, id, err := s.authorize(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.users.Get(ctx, id, krest.ResourceQuery{})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return nil, &krest.ValidationError{Errors: []krest.FieldError{{Field: "user_id", Rule: "ref", Message: fmt.Sprintf("references a nonexistent resource: %s", id)}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.change(ctx, task, func(ctx context.Context) error {
+		return s.watchers.Add(ctx, taskID, id)
+	})
+}
+
+// Unwatch removes a user from the watchers of a task, and returns the watchers.
+func (s *WatcherService) Unwatch(ctx context.Context, taskID uuid.UUID, userID *uuid.UUID) ([]models.User, error) {
+	task, id, err := s.authorize(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.change(ctx, task, func(ctx context.Context) error {
+		return s.watchers.Remove(ctx, taskID, id)
+	})
+}
+
+// Changes the watchers of a task and records the change in one transaction, and returns the watchers of the task.
+func (s *WatcherService) change(ctx context.Context, task models.Task, fn func(ctx context.Context) error) ([]models.User, error) {
+	var users []models.User
+	err := s.watchers.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.watcherIDs(ctx, task.UUID)
+		if err != nil {
+			return err
+		}
+		err = fn(ctx)
+		if err != nil {
+			return err
+		}
+		after, err := s.watcherIDs(ctx, task.UUID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Equal(before.Watchers, after.Watchers) {
+			err = s.auditLog.Record(ctx, audit.ActionUpdate, "tasks", task.UUID, task.ProjectID, before, after)
+			if err != nil {
+				return fmt.Errorf("failed to record audit event: %v", err)
+			}
+		}
+
+		users, err = s.list(ctx, task.UUID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Returns the ids of the users watching a task, sorted so changes can be compared.
+func (s *WatcherService) watcherIDs(ctx context.Context, taskID uuid.UUID) (taskWatchers, error) {
+	watchers, err := s.watchers.List(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return taskWatchers{}, err
+	}
+
+	ids := []string{}
+	for _, id := range watchers[taskID] {
+		ids = append(ids, id.String())
+	}
+	slices.Sort(ids)
+	return taskWatchers{Watchers: ids}, nil
+}
+
+// Returns the task and the user to add or remove, the authenticated user if none is given.
+// Changing the watchers of a task for other users requires the member role.
+func (s *WatcherService) authorize(ctx context.Context, taskID uuid.UUID, userID *uuid.UUID) (models.Task, uuid.UUID, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Task{}, uuid.Nil, auth.ErrUnauthenticated
+	}
+
+	task, err := s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if err != nil {
+		return models.Task{}, uuid.Nil, err
+	}
+
+	if userID == nil || *userID == user.UUID {
+		return task, user.UUID, nil
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Task{}, uuid.Nil, err
+	}
+	return task, *userID, nil
+}
+
+// Returns the users watching a task, without authorizing.
+func (s *WatcherService) list(ctx context.Context, taskID uuid.UUID) ([]models.User, error) {
+	watchers, err := s.watchers.List(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	return usersByID(ctx, s.users, watchers[taskID])
+}
+
+// Returns the users with the ids, in the same order.
+func usersByID(ctx context.Context, users krest.Repository[models.User], ids []uuid.UUID) ([]models.User, error) {
+	if len(ids) == 0 {
+		return []models.User{}, nil
+	}
+
+	found, err := users.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: ids}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[uuid.UUID]models.User{}
+	for _, user := range found {
+		byID[user.UUID] = user
+	}
+
+	ordered := []models.User{}
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			ordered = append(ordered, user)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,143 @@
+package participant_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/participant"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestWatchers(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	watcherRepository := participant.NewWatcherRepository(db)
+	policy := authz.NewPolicy(membershipRepository)
+	authorized := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	tasks := krest_orm.NewRelationService[models.Task](participant.NewTaskService(authorized, watcherRepository),
+		participant.AssigneeRelation(userRepository),
+		participant.ReporterRelation(userRepository),
+		participant.WatchersRelation(watcherRepository, userRepository),
+	)
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	watchers := participant.NewWatcherService(watcherRepository, authorized, userRepository, policy, auditLog)
+
+	// Alice is a member of the project, Bob a viewer.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	users, ids := map[string]context.Context{}, map[string]uuid.UUID{}
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for name, role := range map[string]authz.Role{"alice": authz.RoleMember, "bob": authz.RoleViewer, "carol": authz.RoleMember} {
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		err = policy.AddMember(ctx, project.UUID, user.UUID, role)
+		if err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+		users[name], ids[name] = auth.WithUser(ctx, user), user.UUID
+	}
+
+	// The creator reports the task, whoever the request names, the reporter and the assignee watch it.
+	carol := ids["carol"]
+	task, err := tasks.Create(users["alice"], models.Task{Summary: "Task", ProjectID: project.UUID, AssigneeID: &carol, ReporterID: &carol})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if task.ReporterID == nil || *task.ReporterID != ids["alice"] {
+		t.Errorf("expected alice to report the task, got %v", task.ReporterID)
+	}
+
+	task, err = tasks.Get(users["bob"], task.UUID, krest.ResourceQuery{Expand: []string{"assignee", "reporter", "watchers"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if task.Assignee == nil || task.Assignee.Name != "carol" || task.Reporter == nil || task.Reporter.Name != "alice" {
+		t.Errorf("expected carol assigned and alice reporting, got %+v and %+v", task.Assignee, task.Reporter)
+	}
+	if len(task.Watchers) != 2 {
+		t.Errorf("expected 2 watchers, got %v", task.Watchers)
+	}
+
+	// Viewers can watch a task themselves, but not change the watchers of others.
+	list, err := watchers.Watch(users["bob"], task.UUID, nil)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if len(list) != 3 {
+		t.Errorf("expected 3 watchers, got %v", list)
+	}
+
+	_, err = watchers.Unwatch(users["bob"], task.UUID, &carol)
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer removing another watcher, got %v", err)
+	}
+
+	list, err = watchers.Unwatch(users["alice"], task.UUID, &carol)
+	if err != nil {
+		t.Fatalf("Unwatch failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("expected 2 watchers, got %v", list)
+	}
+
+	// Watching and unwatching is audited as a change of the watchers of the task.
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: task.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ResourceType != "tasks" || events[0].ProjectID != project.UUID || events[1].Changes["watchers"].Before == nil {
+		t.Errorf("expected an event for each change of the watchers, got %+v", events)
+	}
+
+	unknown := uuid.New()
+	_, err = watchers.Watch(users["alice"], task.UUID, &unknown)
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for an unknown user, got %v", err)
+	}
+
+	// Users outside the project can't see the watchers.
+	outsider, err := userRepository.Create(ctx, models.User{Name: "dave", Username: "dave", Email: "dave@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = watchers.List(auth.WithUser(ctx, outsider), task.UUID)
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a user outside the project, got %v", err)
+	}
+
+	// Other organizations don't see the watchers, even with the id of the task.
+	byTask, err := watcherRepository.List(krest_orm.WithTenant(context.Background(), uuid.New()), []uuid.UUID{task.UUID})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(byTask) != 0 {
+		t.Errorf("expected no watchers for another organization, got %v", byTask)
+	}
+	_, err = watcherRepository.List(context.Background(), []uuid.UUID{task.UUID})
+	if err != krest_orm.ErrMissingTenant {
+		t.Errorf("expected missing tenant error, got %v", err)
+	}
+}
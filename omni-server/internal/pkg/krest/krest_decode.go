@@ -0,0 +1,123 @@
+/*
+* This file contains the request body decoding used by the krest handlers.
+ */
+package krest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+/*
+* DecodeOptions controls how request bodies are decoded.
+ */
+type DecodeOptions struct {
+	// Strict rejects unknown fields, and anything following the JSON value.
+	Strict bool
+	// MaxBodySize is the maximum size of a request body in bytes, zero or less means unlimited.
+	MaxBodySize int64
+}
+
+/*
+* DefaultDecodeOptions are the decode options used by handlers unless configured otherwise.
+ */
+var DefaultDecodeOptions = DecodeOptions{
+	Strict:      true,
+	MaxBodySize: 1 << 20, // 1 MiB
+}
+
+/*
+* DecodeRequestBody decodes the JSON body of a request into v.
+* Returns an *Error with status 400, 413 or 415 describing the problem if the body can not be decoded.
+ */
+func DecodeRequestBody(w http.ResponseWriter, r *http.Request, v interface{}, options DecodeOptions) error {
+	// Make sure the body is JSON.
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return NewError(http.StatusUnsupportedMediaType, "unsupported content type %q, expected application/json", contentType)
+	}
+
+	// Read the body, respecting the size limit.
+	body := r.Body
+	if options.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, options.MaxBodySize)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return NewError(http.StatusRequestEntityTooLarge, "request body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return NewError(http.StatusBadRequest, "failed to read request body: %v", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return NewError(http.StatusBadRequest, "request body must not be empty")
+	}
+
+	// Decode the body.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if options.Strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	err = decoder.Decode(v)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxError):
+			// The offset points just past the offending character.
+			return NewError(http.StatusBadRequest, "malformed JSON at %s: %v", bodyPosition(data, syntaxError.Offset-1), syntaxError)
+		case errors.As(err, &typeError):
+			return NewError(http.StatusBadRequest, "invalid value for field %q at %s: expected %s", typeError.Field, bodyPosition(data, typeError.Offset), typeError.Type)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return NewError(http.StatusBadRequest, "malformed JSON at %s: unexpected end of body", bodyPosition(data, int64(len(data))))
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			// The json package does not export an error type, nor the position, for unknown fields.
+			// Find the quoted key followed by a colon, so a string value with the name of the field doesn't match.
+			field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			offset := int64(-1)
+			if loc := regexp.MustCompile(regexp.QuoteMeta(field) + `\s*:`).FindIndex(data); loc != nil {
+				offset = int64(loc[0])
+			}
+			if offset < 0 {
+				offset = decoder.InputOffset()
+			}
+			return NewError(http.StatusBadRequest, "unknown field %s at %s", field, bodyPosition(data, offset))
+		default:
+			return NewError(http.StatusBadRequest, "failed to decode request body: %v", err)
+		}
+	}
+
+	// Make sure the body only contains a single JSON value.
+	if options.Strict {
+		var extra json.RawMessage
+		if err := decoder.Decode(&extra); err != io.EOF {
+			return NewError(http.StatusBadRequest, "unexpected data after JSON value at %s", bodyPosition(data, decoder.InputOffset()))
+		}
+	}
+
+	return nil
+}
+
+/*
+* Returns a human-readable "line X, column Y" position of a byte offset in the body, both starting at 1.
+ */
+func bodyPosition(data []byte, offset int64) string {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+	return fmt.Sprintf("line %d, column %d", line, column)
+}
@@ -0,0 +1,73 @@
+package krest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type DecodedType struct {
+	Summary string `json:"summary"`
+}
+
+func decode(body string, contentType string) (DecodedType, error) {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	var resource DecodedType
+	err := krest.DecodeRequestBody(httptest.NewRecorder(), request, &resource, krest.DecodeOptions{Strict: true, MaxBodySize: 64})
+	return resource, err
+}
+
+func TestDecodeRequestBody(t *testing.T) {
+	resource, err := decode(`{"summary": "Test"}`, "application/json; charset=utf-8")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if resource.Summary != "Test" {
+		t.Errorf("Decode returned incorrect data: got %+v", resource)
+	}
+}
+
+func TestDecodeRequestBodyErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		status      int
+		message     string
+	}{
+		{"unknown field", "{\n  \"sumary\": \"Test\"\n}", "application/json", http.StatusBadRequest, `unknown field "sumary" at line 2`},
+		{"unknown field named in a value", "{\"summary\": \"sumary\",\n  \"sumary\": \"Test\"\n}", "application/json", http.StatusBadRequest, `unknown field "sumary" at line 2, column 3`},
+		{"trailing data", `{"summary": "Test"} {}`, "application/json", http.StatusBadRequest, "unexpected data after JSON value"},
+		{"syntax error", `{"summary": "Test",}`, "application/json", http.StatusBadRequest, "malformed JSON at line 1, column 20"},
+		{"wrong type", `{"summary": 42}`, "application/json", http.StatusBadRequest, `invalid value for field "summary"`},
+		{"empty body", ``, "application/json", http.StatusBadRequest, "must not be empty"},
+		{"too large", `{"summary": "` + strings.Repeat("a", 64) + `"}`, "application/json", http.StatusRequestEntityTooLarge, "must not be larger than 64 bytes"},
+		{"not json", `summary=Test`, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType, "unsupported content type"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decode(test.body, test.contentType)
+
+			var krestError *krest.Error
+			if !errors.As(err, &krestError) {
+				t.Fatalf("expected a krest error, got %v", err)
+			}
+
+			if krestError.Status != test.status {
+				t.Errorf("expected status %d, got %d (%s)", test.status, krestError.Status, krestError.Message)
+			}
+
+			if !strings.Contains(krestError.Message, test.message) {
+				t.Errorf("expected message to contain %q, got %q", test.message, krestError.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package krest
+
+import (
+	"fmt"
+	"reflect"
+)
+
+/*
+* FieldChange is the value of a field before and after a change, nil if the field didn't exist.
+ */
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+/*
+* Diff compares two resources field by field, using their JSON representation.
+* Pass nil as before or after for created and deleted resources. Write-only fields are never included.
+ */
+func Diff(before interface{}, after interface{}) (map[string]FieldChange, error) {
+	beforeFields, err := diffFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := diffFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for name, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[name]) {
+			changes[name] = FieldChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok && value != nil {
+			changes[name] = FieldChange{Before: nil, After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+/*
+* Returns the fields of a resource as they're serialized in responses.
+ */
+func diffFields(resource interface{}) (map[string]interface{}, error) {
+	if resource == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := ResponseData(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("type %T is not a resource", resource)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,35 @@
+package krest_test
+
+import (
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type diffResource struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Password string `json:"password" krest:"writeonly"`
+}
+
+func TestDiff(t *testing.T) {
+	before := diffResource{Name: "Omni", Key: "OMNI", Password: "old"}
+	after := diffResource{Name: "Omni", Key: "OM", Password: "new"}
+
+	changes, err := krest.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(changes) != 1 || changes["key"].Before != "OMNI" || changes["key"].After != "OM" {
+		t.Errorf("Diff returned incorrect changes: got %+v", changes)
+	}
+
+	// Created resources have no before.
+	changes, err = krest.Diff(nil, after)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(changes) != 2 || changes["name"].Before != nil || changes["name"].After != "Omni" {
+		t.Errorf("Diff returned incorrect changes: got %+v", changes)
+	}
+}
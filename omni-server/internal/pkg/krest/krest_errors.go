@@ -0,0 +1,97 @@
+/*
+* This file contains the error types used by the krest package.
+ */
+package krest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/*
+* Error is an error carrying the http status code it should be reported with.
+* Services can return (or wrap) an Error to control how the handler responds.
+ */
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(status int, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+/*
+* ErrorStatus returns the http status code of a krest error, 0 for nil and other errors.
+ */
+func ErrorStatus(err error) int {
+	var krestError *Error
+	if errors.As(err, &krestError) {
+		return krestError.Status
+	}
+	return 0
+}
+
+/*
+* FieldError describes a single field that failed validation.
+ */
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+/*
+* ValidationError is returned when a resource fails validation, it's reported as 422 Unprocessable Entity.
+ */
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, fieldError := range e.Errors {
+		if fieldError.Field == "" {
+			messages = append(messages, fieldError.Message)
+		} else {
+			messages = append(messages, fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message))
+		}
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+/*
+* WriteErrorResponse writes an error to the response, using the status code of krest errors.
+* Errors not originating from krest are reported as 500 Internal Server Error.
+ */
+func WriteErrorResponse(w http.ResponseWriter, err error) {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		writeJSONError(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "validation failed",
+			"errors":  validationError.Errors,
+		})
+		return
+	}
+
+	var krestError *Error
+	if errors.As(err, &krestError) {
+		writeJSONError(w, krestError.Status, krestError)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -17,11 +19,45 @@ import (
 * Generic handler for http api. Implements basic CRUD operations.
  */
 type Handler[T any] struct {
-	service Service[T]
+	service       Service[T]
+	decodeOptions DecodeOptions
 }
 
-func NewHandler[T any](service Service[T]) *Handler[T] {
-	return &Handler[T]{service: service}
+/*
+* HandlerOption configures optional behaviour of a Handler.
+ */
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	decodeOptions DecodeOptions
+}
+
+/*
+* WithDecodeOptions sets how request bodies are decoded, defaults to DefaultDecodeOptions.
+ */
+func WithDecodeOptions(options DecodeOptions) HandlerOption {
+	return func(c *handlerConfig) {
+		c.decodeOptions = options
+	}
+}
+
+func NewHandler[T any](service Service[T], options ...HandlerOption) *Handler[T] {
+	config := handlerConfig{decodeOptions: DefaultDecodeOptions}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return &Handler[T]{service: service, decodeOptions: config.decodeOptions}
+}
+
+/*
+* Returns the service as a ReferenceChecker if it implements it, used to validate 'ref' rules.
+ */
+func (h *Handler[T]) referenceChecker() ReferenceChecker {
+	if checker, ok := h.service.(ReferenceChecker); ok {
+		return checker
+	}
+	return nil
 }
 
 func (h *Handler[T]) Get(w http.ResponseWriter, r *http.Request) {
@@ -51,7 +87,7 @@ func (h *Handler[T]) Get(w http.ResponseWriter, r *http.Request) {
 	// Get the resource
 	resource, err := h.service.Get(r.Context(), uuid, query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -74,10 +110,16 @@ func (h *Handler[T]) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = ValidateCollectionQuery[T](query)
+	if err != nil {
+		WriteErrorResponse(w, err)
+		return
+	}
+
 	// Get the resources
 	resources, err := h.service.List(r.Context(), query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -88,9 +130,19 @@ func (h *Handler[T]) List(w http.ResponseWriter, r *http.Request) {
 func (h *Handler[T]) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body.
 	var resource T
-	err := json.NewDecoder(r.Body).Decode(&resource)
+	err := DecodeRequestBody(w, r, &resource, h.decodeOptions)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	ClearReadOnlyFields(&resource)
+
+	// Validate the resource.
+	err = ValidateResource(r.Context(), resource, h.referenceChecker())
+	if err != nil {
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -98,7 +150,7 @@ func (h *Handler[T]) Create(w http.ResponseWriter, r *http.Request) {
 	createdResource, err := h.service.Create(r.Context(), resource)
 	if err != nil {
 		log.Printf("Failed to create resource: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -115,18 +167,40 @@ func (h *Handler[T]) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse the request body.
-	var resource T
-	err = json.NewDecoder(r.Body).Decode(&resource)
+	// Get the current resource, so fields missing from the request body keep their values.
+	expand, err := ExpandableFieldNames[T]()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	currentResource, err := h.service.Get(r.Context(), uuid, ResourceQuery{Expand: expand})
+	if err != nil {
+		WriteErrorResponse(w, err)
+		return
+	}
+
+	// Parse the request body, on top of the current resource.
+	resource := currentResource
+	err = DecodeRequestBody(w, r, &resource, h.decodeOptions)
+	if err != nil {
+		WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	CopyReadOnlyFields(&resource, currentResource)
+
+	// Validate the resource.
+	err = ValidateResource(r.Context(), resource, h.referenceChecker())
+	if err != nil {
+		WriteErrorResponse(w, err)
 		return
 	}
 
 	// Update the resource.
 	updatedResource, err := h.service.Update(r.Context(), uuid, resource)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -146,7 +220,7 @@ func (h *Handler[T]) Delete(w http.ResponseWriter, r *http.Request) {
 	// Delete the resource.
 	err = h.service.Delete(r.Context(), uuid)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteErrorResponse(w, err)
 		return
 	}
 
@@ -172,6 +246,11 @@ func ParseResourceQuery(r *http.Request) (ResourceQuery, error) {
 		expand = strings.Split(expandStr, ",")
 	}
 
+	// Optional computed fields, e.g. ?fields=description_html, are loaded like expanded fields.
+	if fieldsStr := r.URL.Query().Get("fields"); fieldsStr != "" {
+		expand = append(expand, strings.Split(fieldsStr, ",")...)
+	}
+
 	return ResourceQuery{
 		Expand: expand,
 	}, nil
@@ -195,7 +274,11 @@ func ParseMetaQuery(r *http.Request) (MetaQuery, error) {
 }
 
 /*
-*
+* Extracts the collection query parameters from the http request.
+* Filters are given as `filter[field]=value`, or `filter[field][operator]=value` for operators other than eq.
+* Values of the in and all operators are separated by commas. Sorts are given as `sort=field,-other` (- for descending).
+* Fields are JSON names, use a dot for keys of JSON object fields, e.g. `filter[fields.points][gte]=3`.
+* A full-text search is given as `q=text`.
  */
 func ParseCollectionQuery(r *http.Request) (CollectionQuery, error) {
 	limitStr := r.URL.Query().Get("limit")
@@ -225,13 +308,120 @@ func ParseCollectionQuery(r *http.Request) (CollectionQuery, error) {
 		expand = strings.Split(expandStr, ",")
 	}
 
+	// Optional computed fields, e.g. ?fields=description_html, are loaded like expanded fields.
+	if fieldsStr := r.URL.Query().Get("fields"); fieldsStr != "" {
+		expand = append(expand, strings.Split(fieldsStr, ",")...)
+	}
+
+	filters, err := parseFilters(r)
+	if err != nil {
+		return CollectionQuery{}, err
+	}
+
+	var sorts []Sort
+	if sortStr := r.URL.Query().Get("sort"); sortStr != "" {
+		for _, field := range strings.Split(sortStr, ",") {
+			descending := strings.HasPrefix(field, "-")
+			sorts = append(sorts, Sort{Field: strings.TrimPrefix(field, "-"), Descending: descending})
+		}
+	}
+
 	return CollectionQuery{
-		Limit:  limit,
-		Offset: offset,
-		Expand: expand,
+		Limit:   limit,
+		Offset:  offset,
+		Expand:  expand,
+		Filters: filters,
+		Sort:    sorts,
+		Search:  strings.TrimSpace(r.URL.Query().Get("q")),
 	}, nil
 }
 
+var filterParamPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([a-z]+)\])?$`)
+
+/*
+* Extracts the filters of the http request, ordered by parameter name.
+ */
+func parseFilters(r *http.Request) ([]Filter, error) {
+	params := r.URL.Query()
+	names := []string{}
+	for name := range params {
+		if strings.HasPrefix(name, "filter[") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	filters := []Filter{}
+	for _, name := range names {
+		match := filterParamPattern.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter parameter: %s", name)
+		}
+
+		operator := FilterOperator(match[2])
+		if operator == "" {
+			operator = FilterEqual
+		}
+
+		for _, value := range params[name] {
+			switch operator {
+			case FilterEqual, FilterNotEqual, FilterLessThan, FilterLessOrEqual, FilterGreaterThan, FilterGreaterOrEqual, FilterContains:
+				filters = append(filters, Filter{Field: match[1], Operator: operator, Value: value})
+			case FilterIn, FilterAll:
+				filters = append(filters, Filter{Field: match[1], Operator: operator, Value: strings.Split(value, ",")})
+			default:
+				return nil, fmt.Errorf("unknown filter operator: %s", operator)
+			}
+		}
+	}
+
+	return filters, nil
+}
+
+/*
+* Returns a 400 error if the query filters or sorts by a field T doesn't have, or a write-only field.
+* Use on queries parsed from requests, so clients can't probe the values of write-only fields.
+ */
+func ValidateCollectionQuery[T any](query CollectionQuery) error {
+	fields := []string{}
+	for _, filter := range query.Filters {
+		fields = append(fields, filter.Field)
+	}
+	for _, sort := range query.Sort {
+		fields = append(fields, sort.Field)
+	}
+
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	for _, name := range fields {
+		// Keys of JSON object fields are checked by the repository.
+		base := strings.SplitN(name, ".", 2)[0]
+
+		field, ok := fieldByJSONName(tType, base)
+		if !ok {
+			return NewError(http.StatusBadRequest, "unknown field: %s", name)
+		}
+		if _, ok := GetTags(field)["writeonly"]; ok {
+			return NewError(http.StatusBadRequest, "unknown field: %s", name)
+		}
+	}
+
+	return nil
+}
+
+func fieldByJSONName(tType reflect.Type, name string) (reflect.StructField, bool) {
+	if tType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < tType.NumField(); i++ {
+		field := tType.Field(i)
+		if field.IsExported() && JSONFieldName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
 func WriteCollectionResponse[T any](w http.ResponseWriter, code int, data []T, count int, total int, collectionQueryParams CollectionQuery, metaParams MetaQuery) {
 	var response map[string]interface{} = make(map[string]interface{})
 
@@ -257,10 +447,17 @@ func WriteCollectionResponse[T any](w http.ResponseWriter, code int, data []T, c
 		}
 	}
 
+	// Serialize the provided data, without write-only fields.
+	results, err := ResponseData(data)
+	if err != nil {
+		http.Error(w, "Failed to serialize response data", http.StatusInternalServerError)
+		return
+	}
+
 	// Create the collection, and add it to the response.
 	response["count"] = count
 	response["total"] = total
-	response["results"] = data
+	response["results"] = results
 
 	// Write the response.
 	w.Header().Set("Content-Type", "application/json")
@@ -328,18 +525,17 @@ func WriteResourceResponse[T any](w http.ResponseWriter, code int, data T, query
 		}
 	}
 
-	// Serialize the provided data and add it to the response.
+	// Serialize the provided data, without write-only fields, and add it to the response.
 	// Reflect on the data structure to allow generic serialization.
-	dataBytes, err := json.Marshal(data)
+	responseData, err := ResponseData(data)
 	if err != nil {
 		http.Error(w, "Failed to serialize response data", http.StatusInternalServerError)
 		return
 	}
 
-	// Deserialize back into a map to merge into the response.
-	var dataMap map[string]interface{}
-	err = json.Unmarshal(dataBytes, &dataMap)
-	if err != nil {
+	// The data is merged into the response, so it must be an object.
+	dataMap, ok := responseData.(map[string]interface{})
+	if !ok {
 		http.Error(w, "Failed to deserialize response data", http.StatusInternalServerError)
 		return
 	}
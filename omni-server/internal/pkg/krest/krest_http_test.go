@@ -0,0 +1,63 @@
+package krest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type QueriedType struct {
+	Summary  string         `json:"summary"`
+	Password string         `json:"password" krest:"writeonly"`
+	Fields   map[string]any `json:"fields"`
+}
+
+func TestParseCollectionQuery(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/?filter[summary]=Test&filter[fields.points][gte]=3&filter[status][in]=a,b&sort=-fields.points,summary", nil)
+
+	query, err := krest.ParseCollectionQuery(request)
+	if err != nil {
+		t.Fatalf("ParseCollectionQuery failed: %v", err)
+	}
+
+	expectedFilters := []krest.Filter{
+		{Field: "fields.points", Operator: krest.FilterGreaterOrEqual, Value: "3"},
+		{Field: "status", Operator: krest.FilterIn, Value: []string{"a", "b"}},
+		{Field: "summary", Operator: krest.FilterEqual, Value: "Test"},
+	}
+	if !reflect.DeepEqual(query.Filters, expectedFilters) {
+		t.Errorf("unexpected filters: got %+v, want %+v", query.Filters, expectedFilters)
+	}
+
+	expectedSort := []krest.Sort{{Field: "fields.points", Descending: true}, {Field: "summary"}}
+	if !reflect.DeepEqual(query.Sort, expectedSort) {
+		t.Errorf("unexpected sort: got %+v, want %+v", query.Sort, expectedSort)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/?filter[summary][like]=Test", nil)
+	_, err = krest.ParseCollectionQuery(request)
+	if err == nil {
+		t.Errorf("expected an error for an unknown operator")
+	}
+}
+
+func TestValidateCollectionQuery(t *testing.T) {
+	err := krest.ValidateCollectionQuery[QueriedType](krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "fields.points", Operator: krest.FilterEqual, Value: "3"}},
+		Sort:    []krest.Sort{{Field: "summary"}},
+	})
+	if err != nil {
+		t.Errorf("ValidateCollectionQuery failed: %v", err)
+	}
+
+	// Write-only fields can't be used to probe their values.
+	err = krest.ValidateCollectionQuery[QueriedType](krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "password", Operator: krest.FilterEqual, Value: "hunter2"}},
+	})
+	if krest.ErrorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for filtering by a write-only field, got %v", err)
+	}
+}
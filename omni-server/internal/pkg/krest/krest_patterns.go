@@ -2,6 +2,7 @@ package krest
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 )
@@ -29,3 +30,19 @@ type Service[T any] interface {
 	Update(ctx context.Context, id uuid.UUID, resource T) (T, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
+
+/*
+* ServiceWrapper is embedded by services wrapping another service, to override some of its operations.
+* The operations it doesn't override are passed on to the wrapped service, including reference checks.
+ */
+type ServiceWrapper[T any] struct {
+	Service[T]
+}
+
+func (w ServiceWrapper[T]) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	checker, ok := w.Service.(ReferenceChecker)
+	if !ok {
+		return false, fmt.Errorf("service does not support reference checks")
+	}
+	return checker.ReferenceExists(ctx, table, id)
+}
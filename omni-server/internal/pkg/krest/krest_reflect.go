@@ -4,10 +4,34 @@
 package krest
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 )
 
+/*
+* Returns all krest tags for a given field, e.g. `krest:"expandable,readonly"`.
+ */
+func GetTags(field reflect.StructField) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(field.Tag.Get("krest"), ",") {
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) == 2 {
+			tags[kv[0]] = kv[1]
+		} else {
+			tags[kv[0]] = ""
+		}
+	}
+
+	return tags
+}
+
 /*
 * ReflectExpandableFields returns a map of expandable fields in a struct.
 * The keys are the JSON names of the fields, and the values are the struct fields.
@@ -23,7 +47,7 @@ func ReflectExpandableFields[T any]() ([]reflect.StructField, error) {
 
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		if field.Tag.Get("krest") == "expandable" {
+		if _, ok := GetTags(field)["expandable"]; ok {
 			fields = append(fields, field)
 		}
 	}
@@ -47,7 +71,7 @@ func ReflectNonExpandableFields[T any]() ([]reflect.StructField, error) {
 	// Iterate over the fields of the struct.
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		if field.Tag.Get("krest") != "expandable" {
+		if _, ok := GetTags(field)["expandable"]; !ok {
 			fields = append(fields, field)
 		}
 	}
@@ -67,10 +91,147 @@ func ReflectPrimaryKeyField[T any]() (reflect.StructField, error) {
 	// Iterate over the fields of the struct.
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		if field.Tag.Get("krest_orm") == "pk" {
+		if slices.Contains(strings.Split(field.Tag.Get("krest_orm"), ","), "pk") {
 			return field, nil
 		}
 	}
 
 	return reflect.StructField{}, fmt.Errorf("no primary key field found for type %s", typ.Name())
 }
+
+/*
+* JSONFieldName returns the name a struct field is serialized as, falling back to the field name.
+ */
+func JSONFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+/*
+* ExpandableFieldNames returns the JSON names of all expandable fields in a struct type.
+ */
+func ExpandableFieldNames[T any]() ([]string, error) {
+	fields, err := ReflectExpandableFields[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, field := range fields {
+		names = append(names, JSONFieldName(field))
+	}
+
+	return names, nil
+}
+
+/*
+* ClearReadOnlyFields resets all fields tagged `krest:"readonly"` to their zero value.
+* Used to ignore read-only fields sent by clients when creating resources.
+ */
+func ClearReadOnlyFields[T any](resource *T) {
+	resourceValue := reflect.ValueOf(resource).Elem()
+	if resourceValue.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < resourceValue.NumField(); i++ {
+		if _, ok := GetTags(resourceValue.Type().Field(i))["readonly"]; ok {
+			resourceValue.Field(i).SetZero()
+		}
+	}
+}
+
+/*
+* CopyReadOnlyFields copies all fields tagged `krest:"readonly"` from src to dst.
+* Used to ignore read-only fields sent by clients when updating resources.
+ */
+func CopyReadOnlyFields[T any](dst *T, src T) {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src)
+	if dstValue.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < dstValue.NumField(); i++ {
+		if _, ok := GetTags(dstValue.Type().Field(i))["readonly"]; ok {
+			dstValue.Field(i).Set(srcValue.Field(i))
+		}
+	}
+}
+
+/*
+* ResponseData converts a value into its generic JSON representation (maps, slices and values),
+* without the fields tagged `krest:"writeonly"`, at any depth.
+ */
+func ResponseData(data interface{}) (interface{}, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	err = json.Unmarshal(dataBytes, &generic)
+	if err != nil {
+		return nil, err
+	}
+
+	removeWriteOnlyFields(reflect.TypeOf(data), generic)
+	return generic, nil
+}
+
+/*
+* Walks the generic JSON representation of a value alongside its type, removing write-only fields.
+ */
+func removeWriteOnlyFields(typ reflect.Type, data interface{}) {
+	if typ == nil || data == nil {
+		return
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		if items, ok := data.([]interface{}); ok {
+			for _, item := range items {
+				removeWriteOnlyFields(typ.Elem(), item)
+			}
+		}
+	case reflect.Map:
+		if values, ok := data.(map[string]interface{}); ok {
+			for _, value := range values {
+				removeWriteOnlyFields(typ.Elem(), value)
+			}
+		}
+	case reflect.Struct:
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			// Embedded structs without a name are flattened into the parent object.
+			if field.Anonymous && strings.Split(field.Tag.Get("json"), ",")[0] == "" {
+				removeWriteOnlyFields(field.Type, object)
+				continue
+			}
+
+			name := JSONFieldName(field)
+			if _, ok := GetTags(field)["writeonly"]; ok {
+				delete(object, name)
+				continue
+			}
+
+			removeWriteOnlyFields(field.Type, object[name])
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package krest_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type AccountType struct {
+	UUID     uuid.UUID    `json:"uuid" krest:"readonly"`
+	Name     string       `json:"name"`
+	Password string       `json:"password" krest:"writeonly"`
+	Owner    *AccountType `json:"owner"`
+}
+
+func TestReadOnlyFields(t *testing.T) {
+	id := uuid.New()
+	account := AccountType{UUID: uuid.New(), Name: "Name"}
+
+	krest.ClearReadOnlyFields(&account)
+	if account.UUID != uuid.Nil || account.Name != "Name" {
+		t.Errorf("ClearReadOnlyFields returned incorrect data: got %+v", account)
+	}
+
+	krest.CopyReadOnlyFields(&account, AccountType{UUID: id, Name: "Other"})
+	if account.UUID != id || account.Name != "Name" {
+		t.Errorf("CopyReadOnlyFields returned incorrect data: got %+v", account)
+	}
+}
+
+func TestResponseDataWithoutWriteOnlyFields(t *testing.T) {
+	accounts := []AccountType{
+		{Name: "Alice", Password: "secret", Owner: &AccountType{Name: "Bob", Password: "secret"}},
+	}
+
+	data, err := krest.ResponseData(accounts)
+	if err != nil {
+		t.Fatalf("ResponseData failed: %v", err)
+	}
+
+	account := data.([]interface{})[0].(map[string]interface{})
+	if _, ok := account["password"]; ok {
+		t.Errorf("ResponseData included a write-only field: %+v", account)
+	}
+
+	owner := account["owner"].(map[string]interface{})
+	if _, ok := owner["password"]; ok {
+		t.Errorf("ResponseData included a nested write-only field: %+v", owner)
+	}
+	if owner["name"] != "Bob" {
+		t.Errorf("ResponseData returned incorrect data: got %+v", owner)
+	}
+}
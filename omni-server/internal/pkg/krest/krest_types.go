@@ -24,9 +24,46 @@ type CollectionLinks struct {
 }
 
 type CollectionQuery struct {
-	Limit  int      `json:"limit"`
-	Offset int      `json:"offset"`
-	Expand []string `json:"expand"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+	Expand  []string `json:"expand"`
+	Filters []Filter `json:"filters"`
+	Sort    []Sort   `json:"sort"`
+	Search  string   `json:"search"` // Full-text search of the searchable fields, results are ranked by relevance unless sorted.
+}
+
+// Filters
+type FilterOperator string
+
+const (
+	FilterEqual          FilterOperator = "eq"
+	FilterNotEqual       FilterOperator = "ne"
+	FilterLessThan       FilterOperator = "lt"
+	FilterLessOrEqual    FilterOperator = "lte"
+	FilterGreaterThan    FilterOperator = "gt"
+	FilterGreaterOrEqual FilterOperator = "gte"
+	FilterIn             FilterOperator = "in"       // Value must be a slice, nil values match null fields.
+	FilterContains       FilterOperator = "contains" // The field is an array containing the value.
+	FilterAll            FilterOperator = "all"      // The field is an array containing all the values, value must be a slice.
+)
+
+/*
+* Filter restricts a collection to the resources where the field matches the value.
+* Field is the JSON name of the field, repositories are responsible for rejecting unknown fields.
+ */
+type Filter struct {
+	Field    string         `json:"field"`
+	Operator FilterOperator `json:"operator"`
+	Value    interface{}    `json:"value"`
+}
+
+/*
+* Sort orders a collection by a field, in ascending order unless descending.
+* Field is the JSON name of the field, the first sort takes precedence.
+ */
+type Sort struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending"`
 }
 
 type CollectionResponse[T any] struct {
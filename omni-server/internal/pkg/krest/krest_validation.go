@@ -0,0 +1,218 @@
+/*
+* This file contains the declarative validation of krest resources.
+*
+* Rules are declared with the 'krest_validate' tag, as a comma separated list:
+*
+*	Summary   string    `json:"summary" krest_validate:"required,max:255"`
+*	ProjectID uuid.UUID `json:"project_id" krest_validate:"required,ref:projects"`
+*
+* Supported rules:
+*  - required: The value must not be the zero value.
+*  - min:N, max:N: Length of strings and slices, or the value of numbers.
+*  - pattern:REGEX: Strings must match the regular expression (can not contain commas).
+*  - oneof:a|b|c: The value must be one of the listed values.
+*  - email: Strings must be a plain email address.
+*  - ref:TABLE: UUIDs must reference an existing row in TABLE, checked using a ReferenceChecker.
+*
+* Rules other than 'required' are skipped for zero values.
+ */
+package krest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Validatable can be implemented by resources to validate rules spanning multiple fields.
+* Returning a ValidationError reports field errors, any other error is reported on the resource as a whole.
+ */
+type Validatable interface {
+	Validate(ctx context.Context) error
+}
+
+/*
+* ReferenceChecker checks if a referenced resource exists, used by the 'ref' validation rule.
+* The generic krest_orm service implements this interface.
+ */
+type ReferenceChecker interface {
+	ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error)
+}
+
+/*
+* Returns the validation rules for a given field, as (rule, argument) pairs in declaration order.
+ */
+func GetValidationRules(field reflect.StructField) [][2]string {
+	tag := field.Tag.Get("krest_validate")
+	if tag == "" {
+		return nil
+	}
+
+	rules := [][2]string{}
+	for _, rule := range strings.Split(tag, ",") {
+		kv := strings.SplitN(rule, ":", 2)
+		if len(kv) == 2 {
+			rules = append(rules, [2]string{kv[0], kv[1]})
+		} else {
+			rules = append(rules, [2]string{kv[0], ""})
+		}
+	}
+
+	return rules
+}
+
+/*
+* ValidateResource validates a resource against its 'krest_validate' tags, and the Validatable interface.
+* Returns a *ValidationError listing every failing field, or nil if the resource is valid.
+* The checker is used for 'ref' rules, and may be nil, in which case references are not checked.
+ */
+func ValidateResource[T any](ctx context.Context, resource T, checker ReferenceChecker) error {
+	resourceValue := reflect.ValueOf(&resource).Elem()
+	resourceType := resourceValue.Type()
+	if resourceType.Kind() != reflect.Struct {
+		return fmt.Errorf("type %s is not a struct", resourceType.Name())
+	}
+
+	fieldErrors := []FieldError{}
+	for i := 0; i < resourceType.NumField(); i++ {
+		field := resourceType.Field(i)
+		for _, rule := range GetValidationRules(field) {
+			message, err := validateRule(ctx, resourceValue.Field(i), rule[0], rule[1], checker)
+			if err != nil {
+				return err
+			}
+
+			if message != "" {
+				fieldErrors = append(fieldErrors, FieldError{Field: JSONFieldName(field), Rule: rule[0], Message: message})
+			}
+		}
+	}
+
+	// Run the resource's own validation, if any.
+	var validatable Validatable
+	if v, ok := any(resource).(Validatable); ok {
+		validatable = v
+	} else if v, ok := any(&resource).(Validatable); ok {
+		validatable = v
+	}
+	if validatable != nil {
+		if err := validatable.Validate(ctx); err != nil {
+			var validationError *ValidationError
+			if errors.As(err, &validationError) {
+				fieldErrors = append(fieldErrors, validationError.Errors...)
+			} else {
+				fieldErrors = append(fieldErrors, FieldError{Rule: "validate", Message: err.Error()})
+			}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+
+	return nil
+}
+
+/*
+* Validates a single rule against a field value.
+* Returns a human-readable message if the rule failed, or an error if the rule could not be evaluated.
+ */
+func validateRule(ctx context.Context, value reflect.Value, rule string, arg string, checker ReferenceChecker) (string, error) {
+	if rule == "required" {
+		if value.IsZero() {
+			return "is required", nil
+		}
+		return "", nil
+	}
+
+	// Optional values are only validated when set.
+	if value.IsZero() {
+		return "", nil
+	}
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	switch rule {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s rule argument: %s", rule, arg)
+		}
+
+		var actual float64
+		var unit string
+		switch value.Kind() {
+		case reflect.String:
+			actual, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+		case reflect.Slice, reflect.Map:
+			actual, unit = float64(value.Len()), " items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			actual = float64(value.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			actual = float64(value.Uint())
+		case reflect.Float32, reflect.Float64:
+			actual = value.Float()
+		default:
+			return "", fmt.Errorf("%s rule is not supported for kind %s", rule, value.Kind())
+		}
+
+		if rule == "min" && actual < limit {
+			return fmt.Sprintf("must be at least %s%s", arg, unit), nil
+		}
+		if rule == "max" && actual > limit {
+			return fmt.Sprintf("must be at most %s%s", arg, unit), nil
+		}
+	case "pattern":
+		pattern, err := regexp.Compile(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern rule argument: %v", err)
+		}
+		if !pattern.MatchString(fmt.Sprint(value.Interface())) {
+			return fmt.Sprintf("must match the pattern %s", arg), nil
+		}
+	case "oneof":
+		options := strings.Split(arg, "|")
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if option == actual {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(options, ", ")), nil
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if err != nil || address.Address != value.String() {
+			return "must be a valid email address", nil
+		}
+	case "ref":
+		id, ok := value.Interface().(uuid.UUID)
+		if !ok {
+			return "", fmt.Errorf("ref rule is only supported for uuid.UUID fields")
+		}
+		if checker == nil {
+			return "", nil
+		}
+
+		exists, err := checker.ReferenceExists(ctx, arg, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to check reference: %v", err)
+		}
+		if !exists {
+			return fmt.Sprintf("references a nonexistent resource: %s", id), nil
+		}
+	default:
+		return "", fmt.Errorf("unknown validation rule: %s", rule)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,69 @@
+package krest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type ValidatedType struct {
+	UUID     uuid.UUID `json:"uuid"`
+	Name     string    `json:"name" krest_validate:"required,max:8"`
+	Email    string    `json:"email" krest_validate:"email"`
+	Key      string    `json:"key" krest_validate:"pattern:^[A-Z]+$"`
+	Kind     string    `json:"kind" krest_validate:"oneof:bug|story"`
+	ParentID uuid.UUID `json:"parent_id" krest_validate:"ref:parents"`
+}
+
+type referenceChecker map[uuid.UUID]bool
+
+func (c referenceChecker) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	return c[id], nil
+}
+
+func TestValidateResourceValid(t *testing.T) {
+	parent := uuid.New()
+	resource := ValidatedType{Name: "Name", Email: "jane@example.com", Key: "OMNI", Kind: "bug", ParentID: parent}
+
+	err := krest.ValidateResource(context.Background(), resource, referenceChecker{parent: true})
+	if err != nil {
+		t.Fatalf("expected resource to be valid, got %v", err)
+	}
+}
+
+func TestValidateResourceInvalid(t *testing.T) {
+	resource := ValidatedType{Name: "Too long name", Email: "not an email", Key: "omni", Kind: "epic", ParentID: uuid.New()}
+
+	err := krest.ValidateResource(context.Background(), resource, referenceChecker{})
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	failed := map[string]string{}
+	for _, fieldError := range validationError.Errors {
+		failed[fieldError.Field] = fieldError.Rule
+	}
+
+	expected := map[string]string{"name": "max", "email": "email", "key": "pattern", "kind": "oneof", "parent_id": "ref"}
+	for field, rule := range expected {
+		if failed[field] != rule {
+			t.Errorf("expected %s to fail rule %s, got %q", field, rule, failed[field])
+		}
+	}
+}
+
+func TestValidateResourceRequired(t *testing.T) {
+	err := krest.ValidateResource(context.Background(), ValidatedType{}, nil)
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	if len(validationError.Errors) != 1 || validationError.Errors[0].Field != "name" || validationError.Errors[0].Rule != "required" {
+		t.Errorf("expected only name to be required, got %+v", validationError.Errors)
+	}
+}
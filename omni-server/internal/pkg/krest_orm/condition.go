@@ -0,0 +1,38 @@
+package krest_orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+/*
+* FilterCondition filters on an SQL condition, for what filters on fields can't express, e.g. subqueries of other tables.
+* The value must be a Condition and the field is ignored. Requests can't filter on conditions, only services build them.
+ */
+const FilterCondition krest.FilterOperator = "condition"
+
+/*
+* Condition returns an SQL condition on the rows of a table, with placeholders numbered from argIdx, and their arguments.
+ */
+type Condition func(table string, argIdx int) (string, []interface{})
+
+/*
+* Returns a filter on a condition.
+ */
+func ConditionFilter(condition Condition) krest.Filter {
+	return krest.Filter{Operator: FilterCondition, Value: condition}
+}
+
+/*
+* Returns the placeholders of a list of values numbered from argIdx, e.g. for IN lists of conditions.
+ */
+func Placeholders[V any](values []V, argIdx int) (string, []interface{}) {
+	placeholders, args := []string{}, []interface{}{}
+	for _, value := range values {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", argIdx+len(args)))
+		args = append(args, value)
+	}
+	return strings.Join(placeholders, ", "), args
+}
@@ -0,0 +1,234 @@
+package krest_orm
+
+/*
+* SQL dialects supported by the generic repository. Queries are written in the subset of SQL shared by Postgres and SQLite,
+* dialects cover the differences, e.g. querying keys of JSON columns.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type dialect interface {
+	/*
+	* Returns the expression for a key of a JSON object column, to compare and sort by.
+	 */
+	jsonField(column string, key string) string
+
+	/*
+	* Returns the placeholder for a value compared to a key of a JSON column, and the argument for the value.
+	 */
+	jsonComparand(placeholder string, value interface{}) (string, interface{}, error)
+
+	/*
+	* Returns the condition that the array of a key of a JSON column contains a value, and the argument for the value.
+	 */
+	jsonContains(column string, key string, placeholder string, value interface{}) (string, interface{}, error)
+
+	/*
+	* Returns the statements creating the full-text index of text columns of a table.
+	 */
+	searchIndex(table string, columns []string) []string
+
+	/*
+	* Returns the join restricting a table to the rows matching a full-text search, and the argument for the search.
+	* The join adds the search_rank column, higher is more relevant.
+	 */
+	searchJoin(table string, columns []string, placeholder string, text string) (string, interface{})
+
+	/*
+	* Returns the query of the snippets of rows matching a full-text search, the uuids of the rows given as placeholders,
+	* and the argument for the search. The query selects the search_uuid column, and the search_snippet column,
+	* the matching text with the matches between snippetStart and snippetEnd.
+	 */
+	searchSnippets(table string, columns []string, placeholder string, ids string, text string) (string, interface{})
+
+	/*
+	* Returns the clause locking the rows a query selects until the end of its transaction.
+	 */
+	lockClause() string
+}
+
+// Markers of the matches in search snippets, from the Unicode private use area so they can't appear in the text.
+const (
+	snippetStart = "\uE000"
+	snippetEnd   = "\uE001"
+)
+
+/*
+* Returns the dialect of the database, based on its driver. Defaults to Postgres.
+ */
+func dialectOf(db *sql.DB) dialect {
+	if _, ok := db.Driver().(*sqlite3.SQLiteDriver); ok {
+		return sqliteDialect{}
+	}
+	return postgresDialect{}
+}
+
+// Postgres compares JSON values as jsonb, so numbers compare as numbers, and strings as strings.
+type postgresDialect struct{}
+
+func (postgresDialect) jsonField(column string, key string) string {
+	return fmt.Sprintf("%s->'%s'", column, key)
+}
+
+func (postgresDialect) jsonComparand(placeholder string, value interface{}) (string, interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return placeholder + "::jsonb", string(data), nil
+}
+
+func (postgresDialect) jsonContains(column string, key string, placeholder string, value interface{}) (string, interface{}, error) {
+	data, err := json.Marshal([]interface{}{value})
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s->'%s' @> %s::jsonb", column, key, placeholder), string(data), nil
+}
+
+// Postgres indexes the columns as a single tsvector with a GIN index, queries use the same expression to use the index.
+func (postgresDialect) searchIndex(table string, columns []string) []string {
+	return []string{
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_search ON %s USING GIN (%s)", table, table, postgresSearchVector(columns)),
+	}
+}
+
+func (postgresDialect) searchJoin(table string, columns []string, placeholder string, text string) (string, interface{}) {
+	query := fmt.Sprintf("plainto_tsquery('english', %s)", placeholder)
+	return fmt.Sprintf(
+		"JOIN (SELECT uuid AS search_uuid, ts_rank(%s, %s) AS search_rank FROM %s WHERE %s @@ %s) search ON search.search_uuid = %s.uuid",
+		postgresSearchVector(columns), query, table, postgresSearchVector(columns), query, table,
+	), text
+}
+
+func (postgresDialect) searchSnippets(table string, columns []string, placeholder string, ids string, text string) (string, interface{}) {
+	query := fmt.Sprintf("plainto_tsquery('english', %s)", placeholder)
+	snippet := fmt.Sprintf("ts_headline('english', %s, %s, 'StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, MaxFragments=2')", postgresSearchDocument(columns), query, snippetStart, snippetEnd)
+	return fmt.Sprintf("SELECT uuid AS search_uuid, %s AS search_snippet FROM %s WHERE uuid IN (%s)", snippet, table, ids), text
+}
+
+func (postgresDialect) lockClause() string {
+	return " FOR UPDATE"
+}
+
+func postgresSearchDocument(columns []string) string {
+	parts := []string{}
+	for _, column := range columns {
+		parts = append(parts, fmt.Sprintf("coalesce(%s, '')", column))
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+func postgresSearchVector(columns []string) string {
+	return fmt.Sprintf("to_tsvector('english', %s)", postgresSearchDocument(columns))
+}
+
+// SQLite extracts JSON values as SQL values, so arguments must be plain strings, numbers and booleans.
+type sqliteDialect struct{}
+
+func (sqliteDialect) jsonField(column string, key string) string {
+	return fmt.Sprintf("json_extract(%s, '$.%s')", column, key)
+}
+
+func (sqliteDialect) jsonComparand(placeholder string, value interface{}) (string, interface{}, error) {
+	argument, err := jsonScalar(value)
+	return placeholder, argument, err
+}
+
+func (sqliteDialect) jsonContains(column string, key string, placeholder string, value interface{}) (string, interface{}, error) {
+	argument, err := jsonScalar(value)
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s, '$.%s') WHERE value = %s)", column, key, placeholder), argument, err
+}
+
+// SQLite indexes the columns in an FTS5 table, kept in sync with the table by triggers. Requires SQLite built with FTS5,
+// go-sqlite3 includes it with the sqlite_fts5 build tag. The index is rebuilt on start, to index rows written before it existed.
+func (sqliteDialect) searchIndex(table string, columns []string) []string {
+	index := table + "_search"
+	list := strings.Join(columns, ", ")
+	values := func(prefix string) string {
+		prefixed := []string{}
+		for _, column := range columns {
+			prefixed = append(prefixed, prefix+"."+column)
+		}
+		return strings.Join(prefixed, ", ")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (new.rowid, %s);", index, list, values("new"))
+	remove := fmt.Sprintf("INSERT INTO %s (%s, rowid, %s) VALUES ('delete', old.rowid, %s);", index, index, list, values("old"))
+
+	return []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s', content_rowid='rowid')", index, list, table),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_insert AFTER INSERT ON %s BEGIN %s END", index, table, insert),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_delete AFTER DELETE ON %s BEGIN %s END", index, table, remove),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_update AFTER UPDATE ON %s BEGIN %s %s END", index, table, remove, insert),
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES ('rebuild')", index, index),
+	}
+}
+
+func (sqliteDialect) searchJoin(table string, columns []string, placeholder string, text string) (string, interface{}) {
+	index := table + "_search"
+	return fmt.Sprintf(
+		"JOIN (SELECT rowid AS search_rowid, -bm25(%s) AS search_rank FROM %s WHERE %s MATCH %s) search ON search.search_rowid = %s.rowid",
+		index, index, index, placeholder, table,
+	), sqliteSearchQuery(text)
+}
+
+// FTS5 only makes snippets in queries matching the index.
+func (sqliteDialect) searchSnippets(table string, columns []string, placeholder string, ids string, text string) (string, interface{}) {
+	index := table + "_search"
+	return fmt.Sprintf(
+		"SELECT %s.uuid AS search_uuid, snippet(%s, -1, '%s', '%s', '…', 24) AS search_snippet FROM %s JOIN %s ON %s.rowid = %s.rowid WHERE %s MATCH %s AND %s.uuid IN (%s)",
+		table, index, snippetStart, snippetEnd, index, table, table, index, index, placeholder, table, ids,
+	), sqliteSearchQuery(text)
+}
+
+// SQLite locks the whole database for writes, a transaction writing after a concurrent write to what it read fails instead.
+func (sqliteDialect) lockClause() string {
+	return ""
+}
+
+/*
+* Returns an FTS5 query matching all the words of a text, like plainto_tsquery in Postgres.
+* Words are quoted, so the text can't use the FTS5 query syntax.
+ */
+func sqliteSearchQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+	if len(words) == 0 {
+		return `""`
+	}
+
+	quoted := []string{}
+	for _, word := range words {
+		quoted = append(quoted, `"`+word+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+/*
+* Returns the value as it would be extracted from JSON, e.g. a uuid.UUID as a string.
+ */
+func jsonScalar(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var scalar interface{}
+	err = json.Unmarshal(data, &scalar)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scalar.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, fmt.Errorf("can't compare JSON fields to %T", value)
+	}
+	return scalar, nil
+}
@@ -8,22 +8,38 @@ package krest_orm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"html"
 	"log"
+	"net/http"
 	"reflect"
+	"regexp"
 	"slices"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/khaossystems/omni-server/internal/pkg/krest"
 	krest_sql_helpers "github.com/khaossystems/omni-server/internal/pkg/krest_orm/sql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
 // Implement the krest.Repository[T] interface.
 type GenericPostgresRepository[T any] struct {
 	db          *sqlx.DB
+	dialect     dialect
 	tableSchema krest_sql_helpers.TableSchema
+	// Index of the field tagged `krest_orm:"tenant"`, -1 if the model is not scoped to a tenant.
+	tenantField int
+	// Table storing revisions of resources, empty if the model doesn't keep revisions.
+	revisionsTable string
+	// Columns of the fields tagged `krest_orm:"searchable"`, in the full-text index of the table.
+	searchColumns []string
+	// Why the full-text index couldn't be created, e.g. SQLite without FTS5, searches fail with it.
+	searchErr error
 }
 
 func NewGenericPostgresRepository[T any](db *sql.DB) *GenericPostgresRepository[T] {
@@ -43,10 +59,323 @@ func NewGenericPostgresRepository[T any](db *sql.DB) *GenericPostgresRepository[
 		log.Fatalf("failed to create table %s: %v", schema.Name, err)
 	}
 
-	return &GenericPostgresRepository[T]{
-		db:          sqlx.NewDb(db, "postgres"),
+	// Map struct fields to columns the same way the schema does.
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	sqlxDB.Mapper = reflectx.NewMapperFunc("db", krest_sql_helpers.ColumnName)
+
+	repository := &GenericPostgresRepository[T]{
+		db:          sqlxDB,
+		dialect:     dialectOf(db),
 		tableSchema: schema,
+		tenantField: -1,
+	}
+
+	// Find the tenant field, and whether revisions are kept.
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < tType.NumField(); i++ {
+		tags := krest_sql_helpers.GetKrestTags(tType.Field(i))
+		if _, ok := tags["tenant"]; ok {
+			repository.tenantField = i
+			tenantColumns.Store(schema.Name, krest_sql_helpers.ColumnName(tType.Field(i).Name))
+		}
+		if _, ok := tags["revisions"]; ok {
+			repository.revisionsTable = revisionsTableName(schema.Name)
+		}
+		if _, ok := tags["searchable"]; ok {
+			repository.searchColumns = append(repository.searchColumns, krest_sql_helpers.ColumnName(tType.Field(i).Name))
+		}
+	}
+
+	if repository.revisionsTable != "" {
+		err = repository.createRevisionsTable()
+		if err != nil {
+			log.Fatalf("failed to create table %s: %v", repository.revisionsTable, err)
+		}
+	}
+
+	// Searches fail until the database supports full-text search, check SearchError to refuse to start without it.
+	if len(repository.searchColumns) > 0 {
+		for _, query := range repository.dialect.searchIndex(schema.Name, repository.searchColumns) {
+			log.Printf("creating search index: %s", query)
+			_, err = db.Exec(query)
+			if err != nil {
+				log.Printf("full-text search of %s is unavailable: %v", schema.Name, err)
+				repository.searchErr = err
+				break
+			}
+		}
+	}
+
+	return repository
+}
+
+/*
+* Returns the condition restricting a query on a table to the tenant of the context, numbering the placeholder argIdx.
+* Returns an empty condition if the table is not scoped to a tenant, or the context explicitly opts out.
+ */
+func tenantCondition(ctx context.Context, table string, argIdx int) (string, []interface{}, error) {
+	column, ok := tenantColumns.Load(table)
+	if !ok || isUnscoped(ctx) {
+		return "", nil, nil
+	}
+
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return "", nil, ErrMissingTenant
+	}
+
+	return fmt.Sprintf("%s = $%d", column, argIdx), []interface{}{tenantID}, nil
+}
+
+/*
+* Sets the tenant of a resource to the tenant of the context, so resources can't be moved between tenants.
+* Without a tenant in the context, the resource must already belong to one.
+ */
+func (r *GenericPostgresRepository[T]) setTenant(ctx context.Context, resource *T) error {
+	if r.tenantField < 0 {
+		return nil
+	}
+
+	field := reflect.ValueOf(resource).Elem().Field(r.tenantField)
+	tenantID, ok := TenantFromContext(ctx)
+	if ok {
+		field.Set(reflect.ValueOf(tenantID))
+		return nil
+	}
+
+	if !isUnscoped(ctx) || field.Interface() == uuid.Nil {
+		return ErrMissingTenant
+	}
+
+	return nil
+}
+
+// A field queries can filter and sort by, a column or a key of a JSON object column.
+type queryField struct {
+	column string
+	key    string
+}
+
+var jsonKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+/*
+* Returns the query field for the JSON name of a field, or an error if the field is not stored in the table.
+* Keys of JSON object columns are named using a dot, e.g. `fields.points` for the points key of the fields column.
+ */
+func (r *GenericPostgresRepository[T]) queryField(name string) (queryField, error) {
+	base, key, isKey := strings.Cut(name, ".")
+
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < tType.NumField(); i++ {
+		field := tType.Field(i)
+		if krest.JSONFieldName(field) != base {
+			continue
+		}
+
+		tags := krest_sql_helpers.GetKrestTags(field)
+		if _, ok := tags["ignore"]; ok {
+			break
+		}
+		column := krest_sql_helpers.ColumnName(field.Name)
+
+		if !isKey {
+			return queryField{column: column}, nil
+		}
+
+		// Only JSON columns have keys.
+		if !strings.Contains(strings.ToUpper(tags["type"]), "JSON") || !jsonKeyPattern.MatchString(key) {
+			break
+		}
+		return queryField{column: column, key: key}, nil
+	}
+
+	return queryField{}, krest.NewError(http.StatusBadRequest, "unknown field: %s", name)
+}
+
+/*
+* Returns the expression for the field, and the placeholder and argument to compare it to a value.
+ */
+func (r *GenericPostgresRepository[T]) comparison(field queryField, argIdx int, value interface{}) (string, string, interface{}, error) {
+	placeholder := fmt.Sprintf("$%d", argIdx)
+	if field.key == "" {
+		return field.column, placeholder, value, nil
+	}
+
+	placeholder, argument, err := r.dialect.jsonComparand(placeholder, value)
+	if err != nil {
+		return "", "", nil, krest.NewError(http.StatusBadRequest, "invalid value for %s.%s: %v", field.column, field.key, err)
+	}
+	return r.expression(field), placeholder, argument, nil
+}
+
+/*
+* Returns the SQL expression of a field, the column or the path of a JSON key.
+ */
+func (r *GenericPostgresRepository[T]) expression(field queryField) string {
+	if field.key == "" {
+		return field.column
+	}
+	return r.dialect.jsonField(field.column, field.key)
+}
+
+/*
+* Builds the WHERE clause for a list of filters, numbering placeholders from argIdx.
+* Rows of other tenants are always filtered out. Returns an empty clause if there is nothing to filter.
+ */
+func (r *GenericPostgresRepository[T]) whereClause(ctx context.Context, filters []krest.Filter, argIdx int) (string, []interface{}, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	tenant, tenantArgs, err := tenantCondition(ctx, r.tableSchema.Name, argIdx)
+	if err != nil {
+		return "", nil, err
+	}
+	if tenant != "" {
+		conditions = append(conditions, tenant)
+		args = append(args, tenantArgs...)
+		argIdx += len(tenantArgs)
+	}
+
+	for _, filter := range filters {
+		if filter.Operator == FilterCondition {
+			condition, ok := filter.Value.(Condition)
+			if !ok {
+				return "", nil, fmt.Errorf("value of condition filter must be a Condition, got %T", filter.Value)
+			}
+			sql, conditionArgs := condition(r.tableSchema.Name, argIdx)
+			conditions = append(conditions, "("+sql+")")
+			args = append(args, conditionArgs...)
+			argIdx += len(conditionArgs)
+			continue
+		}
+
+		field, err := r.queryField(filter.Field)
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch filter.Operator {
+		case krest.FilterEqual, krest.FilterNotEqual, krest.FilterLessThan, krest.FilterLessOrEqual, krest.FilterGreaterThan, krest.FilterGreaterOrEqual:
+			operators := map[krest.FilterOperator]string{
+				krest.FilterEqual: "=", krest.FilterNotEqual: "<>",
+				krest.FilterLessThan: "<", krest.FilterLessOrEqual: "<=",
+				krest.FilterGreaterThan: ">", krest.FilterGreaterOrEqual: ">=",
+			}
+			expression, placeholder, argument, err := r.comparison(field, argIdx, filter.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", expression, operators[filter.Operator], placeholder))
+			args = append(args, argument)
+			argIdx++
+		case krest.FilterIn:
+			values := reflect.ValueOf(filter.Value)
+			if values.Kind() != reflect.Slice {
+				return "", nil, fmt.Errorf("value of in filter on %s must be a slice", filter.Field)
+			}
+
+			// Nothing is in an empty list.
+			if values.Len() == 0 {
+				conditions = append(conditions, "1 = 0")
+				continue
+			}
+
+			expression, matchNull := r.expression(field), false
+			placeholders := []string{}
+			for i := 0; i < values.Len(); i++ {
+				value := values.Index(i)
+				if (value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer) && value.IsNil() {
+					matchNull = true
+					continue
+				}
+
+				_, placeholder, argument, err := r.comparison(field, argIdx, value.Interface())
+				if err != nil {
+					return "", nil, err
+				}
+				placeholders = append(placeholders, placeholder)
+				args = append(args, argument)
+				argIdx++
+			}
+
+			switch {
+			case len(placeholders) == 0:
+				conditions = append(conditions, fmt.Sprintf("%s IS NULL", expression))
+			case matchNull:
+				conditions = append(conditions, fmt.Sprintf("(%s IN (%s) OR %s IS NULL)", expression, strings.Join(placeholders, ", "), expression))
+			default:
+				conditions = append(conditions, fmt.Sprintf("%s IN (%s)", expression, strings.Join(placeholders, ", ")))
+			}
+		case krest.FilterContains:
+			if field.key == "" {
+				return "", nil, krest.NewError(http.StatusBadRequest, "contains filters are only supported on keys of JSON fields, not %s", filter.Field)
+			}
+
+			condition, argument, err := r.dialect.jsonContains(field.column, field.key, fmt.Sprintf("$%d", argIdx), filter.Value)
+			if err != nil {
+				return "", nil, krest.NewError(http.StatusBadRequest, "invalid value for %s: %v", filter.Field, err)
+			}
+			conditions = append(conditions, condition)
+			args = append(args, argument)
+			argIdx++
+		case krest.FilterAll:
+			if field.key == "" {
+				return "", nil, krest.NewError(http.StatusBadRequest, "all filters are only supported on keys of JSON fields, not %s", filter.Field)
+			}
+			values := reflect.ValueOf(filter.Value)
+			if values.Kind() != reflect.Slice {
+				return "", nil, fmt.Errorf("value of all filter on %s must be a slice", filter.Field)
+			}
+
+			for i := 0; i < values.Len(); i++ {
+				condition, argument, err := r.dialect.jsonContains(field.column, field.key, fmt.Sprintf("$%d", argIdx), values.Index(i).Interface())
+				if err != nil {
+					return "", nil, krest.NewError(http.StatusBadRequest, "invalid value for %s: %v", filter.Field, err)
+				}
+				conditions = append(conditions, condition)
+				args = append(args, argument)
+				argIdx++
+			}
+		default:
+			return "", nil, krest.NewError(http.StatusBadRequest, "unknown filter operator: %s", filter.Operator)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
 	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+/*
+* Builds the ORDER BY clause for a list of sorts. Returns an empty clause if there are no sorts.
+ */
+func (r *GenericPostgresRepository[T]) orderByClause(sorts []krest.Sort) (string, error) {
+	terms := []string{}
+	for _, sort := range sorts {
+		field, err := r.queryField(sort.Field)
+		if err != nil {
+			return "", err
+		}
+
+		expression := field.column
+		if field.key != "" {
+			expression = r.dialect.jsonField(field.column, field.key)
+		}
+
+		if sort.Descending {
+			terms = append(terms, expression+" DESC")
+		} else {
+			terms = append(terms, expression+" ASC")
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", nil
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", "), nil
 }
 
 /*
@@ -87,6 +416,12 @@ func (r *GenericPostgresRepository[T]) FieldsToGet(expand []string) ([]reflect.S
 		}
 	}
 
+	// Fields not stored in the table can not be selected.
+	fields = slices.DeleteFunc(fields, func(field reflect.StructField) bool {
+		_, ok := krest_sql_helpers.GetKrestTags(field)["ignore"]
+		return ok
+	})
+
 	// Ensure that at least one field is selected
 	if len(fields) == 0 {
 		return []reflect.StructField{}, fmt.Errorf("no fields selected for query.. not event the uuid.. for some reason")
@@ -115,13 +450,22 @@ func (r *GenericPostgresRepository[T]) Get(ctx context.Context, id uuid.UUID, qu
 		columnNamesToGet = append(columnNamesToGet, krest_sql_helpers.ColumnName(field.Name))
 	}
 
+	// Only get the resource of the tenant.
+	where, args, err := r.whereClause(ctx, []krest.Filter{{Field: "uuid", Operator: krest.FilterEqual, Value: id}}, 1)
+	if err != nil {
+		return *new(T), err
+	}
+
 	// Get the fields from the database.
 	queryFields := strings.Join(columnNamesToGet, ", ")
-	sql := fmt.Sprintf("SELECT %s FROM %s WHERE uuid = $1", queryFields, r.tableSchema.Name)
+	selectQuery := fmt.Sprintf("SELECT %s FROM %s%s", queryFields, r.tableSchema.Name, where)
 
 	// Execute the query.
 	resource := new(T)
-	err = r.db.Get(resource, sql, id)
+	err = sqlx.GetContext(ctx, r.conn(ctx), resource, selectQuery, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return *new(T), krest.NewError(http.StatusNotFound, "%s %s not found", r.tableSchema.Name, id)
+	}
 	if err != nil {
 		return *new(T), fmt.Errorf("failed to query database: %v", err)
 	}
@@ -150,11 +494,16 @@ func (r *GenericPostgresRepository[T]) List(ctx context.Context, query krest.Col
 		columnNamesToGet = append(columnNamesToGet, krest_sql_helpers.ColumnName(field.Name))
 	}
 
+	// Search, filter and sort the resources.
+	from, orderBy, args, err := r.fromClause(ctx, query)
+	if err != nil {
+		return []T{}, err
+	}
+	argIdx := len(args) + 1
+
 	// Get the fields from the database.
 	queryFields := strings.Join(columnNamesToGet, ", ")
-	sql := fmt.Sprintf("SELECT %s FROM %s", queryFields, r.tableSchema.Name)
-	args := []interface{}{}
-	argIdx := 1
+	sql := fmt.Sprintf("SELECT %s%s%s", queryFields, from, orderBy)
 
 	// Add the limit and offset to the query.
 	if query.Limit > 0 {
@@ -171,21 +520,177 @@ func (r *GenericPostgresRepository[T]) List(ctx context.Context, query krest.Col
 
 	// Query the database for all projects.
 	resources := []T{}
-	err = r.db.Select(&resources, sql, args...)
+	err = sqlx.SelectContext(ctx, r.conn(ctx), &resources, sql, args...)
 	if err != nil {
 		return []T{}, fmt.Errorf("failed to query database: %v", err)
 	}
 
-	// Get the total count of resources.
-	var total int
-	totalQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", r.tableSchema.Name)
-	err = r.db.Get(&total, totalQuery)
+	// The task should now be populated with the selected fields.
+	return resources, nil
+}
+
+/*
+* Count returns the number of resources matching the filters and full-text search of a query, ignoring its limit and offset.
+ */
+func (r *GenericPostgresRepository[T]) Count(ctx context.Context, query krest.CollectionQuery) (int, error) {
+	from, _, args, err := r.fromClause(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total users: %v", err)
+		return 0, err
 	}
 
-	// The task should now be populated with the selected fields.
-	return resources, nil
+	var count int
+	err = sqlx.GetContext(ctx, r.conn(ctx), &count, "SELECT COUNT(*)"+from, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count resources: %v", err)
+	}
+	return count, nil
+}
+
+/*
+* SearchResult is a resource matching a full-text search, see GenericPostgresRepository.Search.
+ */
+type SearchResult[T any] struct {
+	Resource T
+	Rank     float64 // Relevance of the resource, higher is more relevant. Only comparable between results of the same search.
+	Snippet  string  // HTML of the matching text, the matches are highlighted with <mark>.
+}
+
+/*
+* Search lists the resources matching the full-text search of a query, with snippets of the matching text.
+* The resources are filtered, sorted and paged like List, and ranked by relevance unless sorted otherwise.
+* Snippets are only made for the resources of the page, making them is much slower than ranking.
+ */
+func (r *GenericPostgresRepository[T]) Search(ctx context.Context, query krest.CollectionQuery) ([]SearchResult[T], error) {
+	if query.Search == "" {
+		return nil, krest.NewError(http.StatusBadRequest, "missing search text")
+	}
+
+	from, orderBy, args, err := r.fromClause(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("SELECT %s.uuid, search_rank%s%s", r.tableSchema.Name, from, orderBy)
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, query.Limit)
+	}
+	if query.Offset > 0 {
+		sql += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, query.Offset)
+	}
+
+	hits := []struct {
+		UUID uuid.UUID `db:"uuid"`
+		Rank float64   `db:"search_rank"`
+	}{}
+	err = sqlx.SelectContext(ctx, r.conn(ctx), &hits, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search database: %v", err)
+	}
+	if len(hits) == 0 {
+		return []SearchResult[T]{}, nil
+	}
+
+	// Get the resources and snippets of the hits, in the order of the hits.
+	ids := []uuid.UUID{}
+	for _, hit := range hits {
+		ids = append(ids, hit.UUID)
+	}
+	resources, err := r.List(ctx, krest.CollectionQuery{
+		Expand:  query.Expand,
+		Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: ids}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uuid.UUID]T{}
+	for _, resource := range resources {
+		id, err := primaryKeyValue(resource)
+		if err != nil {
+			return nil, err
+		}
+		byID[id] = resource
+	}
+
+	placeholders, idArgs := Placeholders(ids, 2)
+	snippetQuery, argument := r.dialect.searchSnippets(r.tableSchema.Name, r.searchColumns, "$1", placeholders, query.Search)
+	snippets := []struct {
+		UUID    uuid.UUID `db:"search_uuid"`
+		Snippet string    `db:"search_snippet"`
+	}{}
+	err = sqlx.SelectContext(ctx, r.conn(ctx), &snippets, snippetQuery, append([]interface{}{argument}, idArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search snippets: %v", err)
+	}
+	snippetsByID := map[uuid.UUID]string{}
+	for _, snippet := range snippets {
+		snippetsByID[snippet.UUID] = snippet.Snippet
+	}
+
+	results := []SearchResult[T]{}
+	for _, hit := range hits {
+		resource, ok := byID[hit.UUID]
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult[T]{Resource: resource, Rank: hit.Rank, Snippet: highlight(snippetsByID[hit.UUID])})
+	}
+	return results, nil
+}
+
+/*
+* SearchError returns why the full-text index of the table couldn't be created, nil if searches work.
+ */
+func (r *GenericPostgresRepository[T]) SearchError() error {
+	return r.searchErr
+}
+
+/*
+* Builds the FROM and WHERE clauses, and the ORDER BY clause, of a list query, numbering placeholders from 1.
+* Searches join the full-text index, and sort by relevance unless sorted otherwise.
+ */
+func (r *GenericPostgresRepository[T]) fromClause(ctx context.Context, query krest.CollectionQuery) (string, string, []interface{}, error) {
+	from, args := " FROM "+r.tableSchema.Name, []interface{}{}
+	sorts := query.Sort
+
+	if query.Search != "" {
+		if len(r.searchColumns) == 0 {
+			return "", "", nil, krest.NewError(http.StatusBadRequest, "%s can't be searched", r.tableSchema.Name)
+		}
+		if r.searchErr != nil {
+			return "", "", nil, krest.NewError(http.StatusNotImplemented, "full-text search is unavailable: %v", r.searchErr)
+		}
+
+		join, argument := r.dialect.searchJoin(r.tableSchema.Name, r.searchColumns, "$1", query.Search)
+		from += " " + join
+		args = append(args, argument)
+	}
+
+	where, whereArgs, err := r.whereClause(ctx, query.Filters, len(args)+1)
+	if err != nil {
+		return "", "", nil, err
+	}
+	args = append(args, whereArgs...)
+
+	orderBy, err := r.orderByClause(sorts)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if query.Search != "" && len(sorts) == 0 {
+		orderBy = " ORDER BY search_rank DESC"
+	}
+
+	return from + where, orderBy, args, nil
+}
+
+/*
+* Returns the HTML of a search snippet, escaping the text and highlighting the matches.
+ */
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetStart, "<mark>")
+	return strings.ReplaceAll(escaped, snippetEnd, "</mark>")
 }
 
 func (r *GenericPostgresRepository[T]) Create(ctx context.Context, resource T) (T, error) {
@@ -204,6 +709,12 @@ func (r *GenericPostgresRepository[T]) Create(ctx context.Context, resource T) (
 		return *new(T), fmt.Errorf("failed to ensure primary key: %v", err)
 	}
 
+	// Create the resource in the tenant of the context.
+	err = r.setTenant(ctx, &resource)
+	if err != nil {
+		return *new(T), err
+	}
+
 	// TODO: A cleaner way to do this, is getting the fields from the shema, instead of though reflection..
 	// TODO: This is a bit of a mess, and should be cleaned up.
 
@@ -251,11 +762,19 @@ func (r *GenericPostgresRepository[T]) Create(ctx context.Context, resource T) (
 	// Execute the query and return the created resource
 	var createdResource T
 	log.Printf("query: %s, values: %v", query, values)
-	err = r.db.GetContext(ctx, &createdResource, query, values...)
+	err = sqlx.GetContext(ctx, r.conn(ctx), &createdResource, query, values...)
+	if isUniqueViolation(err) {
+		return *new(T), krest.NewError(http.StatusConflict, "%s conflicts with an existing resource", r.tableSchema.Name)
+	}
 	if err != nil {
 		return *new(T), fmt.Errorf("failed to insert resource into database: %v", err)
 	}
 
+	err = r.recordRevision(ctx, createdResource)
+	if err != nil {
+		return *new(T), err
+	}
+
 	return createdResource, nil
 }
 
@@ -268,6 +787,12 @@ func (r *GenericPostgresRepository[T]) Update(ctx context.Context, id uuid.UUID,
 		return *new(T), fmt.Errorf("type %T is not a struct", resource)
 	}
 
+	// Keep the resource in the tenant of the context.
+	err := r.setTenant(ctx, &resource)
+	if err != nil {
+		return *new(T), err
+	}
+
 	// Prepare slices for column names, placeholders, and values
 	setClauses := []string{}
 	values := []interface{}{}
@@ -303,34 +828,107 @@ func (r *GenericPostgresRepository[T]) Update(ctx context.Context, id uuid.UUID,
 		return *new(T), fmt.Errorf("no fields to update for resource %v", id)
 	}
 
-	// Add the ID as the last argument for the WHERE clause
-	values = append(values, id)
+	// Only update the resource of the tenant.
+	where, whereArgs, err := r.whereClause(ctx, []krest.Filter{{Field: "uuid", Operator: krest.FilterEqual, Value: id}}, argIdx)
+	if err != nil {
+		return *new(T), err
+	}
+	values = append(values, whereArgs...)
 
 	// Generate the SQL query for the update
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE uuid = $%d RETURNING *",
+		"UPDATE %s SET %s%s RETURNING *",
 		r.tableSchema.Name,
 		strings.Join(setClauses, ", "),
-		argIdx,
+		where,
 	)
 	//fmt.Printf("query: %s, values: %v\n", query, values)
 
 	// Execute the query and return the updated resource
 	var updatedResource T
-	err := r.db.GetContext(ctx, &updatedResource, query, values...)
+	err = sqlx.GetContext(ctx, r.conn(ctx), &updatedResource, query, values...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return *new(T), krest.NewError(http.StatusNotFound, "%s %s not found", r.tableSchema.Name, id)
+	}
+	if isUniqueViolation(err) {
+		return *new(T), krest.NewError(http.StatusConflict, "%s conflicts with an existing resource", r.tableSchema.Name)
+	}
 	if err != nil {
 		return *new(T), fmt.Errorf("failed to update resource in database: %v", err)
 	}
 
+	err = r.recordRevision(ctx, updatedResource)
+	if err != nil {
+		return *new(T), err
+	}
+
 	return updatedResource, nil
 }
 
 func (r *GenericPostgresRepository[T]) Delete(ctx context.Context, id uuid.UUID) error {
-	sql := fmt.Sprintf("DELETE FROM %s WHERE uuid = $1", r.tableSchema.Name)
-	_, err := r.db.Exec(sql, id)
+	// Only delete the resource of the tenant.
+	where, args, err := r.whereClause(ctx, []krest.Filter{{Field: "uuid", Operator: krest.FilterEqual, Value: id}}, 1)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s%s", r.tableSchema.Name, where)
+	result, err := r.conn(ctx).ExecContext(ctx, deleteQuery, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete resource from database: %v", err)
 	}
 
+	// Not every database enforces foreign keys, delete the revisions of the resource explicitly.
+	deleted, err := result.RowsAffected()
+	if err == nil && deleted > 0 && r.revisionsTable != "" {
+		_, err = r.conn(ctx).ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE resource_id = $1", r.revisionsTable), id)
+		if err != nil {
+			return fmt.Errorf("failed to delete revisions from database: %v", err)
+		}
+	}
+
 	return nil
 }
+
+/*
+* Checks if a row with the given uuid exists in a table, used to validate references between resources.
+* Rows of other tenants don't exist.
+ */
+func (r *GenericPostgresRepository[T]) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE uuid = $1", table)
+	args := []interface{}{id}
+
+	tenant, tenantArgs, err := tenantCondition(ctx, table, 2)
+	if err != nil {
+		return false, err
+	}
+	if tenant != "" {
+		query += " AND " + tenant
+		args = append(args, tenantArgs...)
+	}
+
+	var exists bool
+	err = sqlx.GetContext(ctx, r.conn(ctx), &exists, fmt.Sprintf("SELECT EXISTS (%s)", query), args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to query database: %v", err)
+	}
+
+	return exists, nil
+}
+
+/*
+* Checks if an error is caused by a unique constraint, for both Postgres and SQLite.
+ */
+func isUniqueViolation(err error) bool {
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		return pqError.Code == "23505"
+	}
+
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteError.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+
+	return false
+}
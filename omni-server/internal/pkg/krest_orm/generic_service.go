@@ -2,6 +2,7 @@ package krest_orm
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/khaossystems/omni-server/internal/pkg/krest"
@@ -35,3 +36,11 @@ func (s *GenericService[T]) Update(ctx context.Context, id uuid.UUID, user T) (T
 func (s *GenericService[T]) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repository.Delete(ctx, id)
 }
+
+func (s *GenericService[T]) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	checker, ok := s.repository.(krest.ReferenceChecker)
+	if !ok {
+		return false, fmt.Errorf("repository does not support reference checks")
+	}
+	return checker.ReferenceExists(ctx, table, id)
+}
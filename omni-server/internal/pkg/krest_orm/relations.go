@@ -0,0 +1,166 @@
+package krest_orm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+/*
+* Relation loads an expandable field that isn't stored in the table of the resource, e.g. the comments of a task.
+ */
+type Relation[T any] struct {
+	// JSON name of the expandable field.
+	Field string
+	// JSON names of the fields the relation is loaded from, they're expanded along with the relation.
+	Expand []string
+	// Sets the field on each of the resources, resources are loaded in batches to avoid a query per resource.
+	Load func(ctx context.Context, resources []T) error
+}
+
+/*
+* RelationService wraps a krest.Service[T], loading relations when they're expanded.
+* Wrap the service after authorizing it, so relations are only loaded for resources that are visible.
+* Implements krest.Service[T]
+ */
+type RelationService[T any] struct {
+	service   krest.Service[T]
+	relations []Relation[T]
+}
+
+func NewRelationService[T any](service krest.Service[T], relations ...Relation[T]) *RelationService[T] {
+	return &RelationService[T]{service: service, relations: relations}
+}
+
+func (s *RelationService[T]) Get(ctx context.Context, id uuid.UUID, query krest.ResourceQuery) (T, error) {
+	resource, err := s.service.Get(ctx, id, krest.ResourceQuery{Expand: s.expand(query.Expand)})
+	if err != nil {
+		return *new(T), err
+	}
+
+	resources := []T{resource}
+	err = s.load(ctx, resources, query.Expand)
+	if err != nil {
+		return *new(T), err
+	}
+
+	return resources[0], nil
+}
+
+func (s *RelationService[T]) List(ctx context.Context, query krest.CollectionQuery) ([]T, error) {
+	expand := query.Expand
+	query.Expand = s.expand(expand)
+	resources, err := s.service.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.load(ctx, resources, expand)
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+func (s *RelationService[T]) Create(ctx context.Context, resource T) (T, error) {
+	return s.service.Create(ctx, resource)
+}
+
+func (s *RelationService[T]) Update(ctx context.Context, id uuid.UUID, resource T) (T, error) {
+	return s.service.Update(ctx, id, resource)
+}
+
+func (s *RelationService[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.service.Delete(ctx, id)
+}
+
+func (s *RelationService[T]) ReferenceExists(ctx context.Context, table string, id uuid.UUID) (bool, error) {
+	checker, ok := s.service.(krest.ReferenceChecker)
+	if !ok {
+		return false, fmt.Errorf("service does not support reference checks")
+	}
+	return checker.ReferenceExists(ctx, table, id)
+}
+
+/*
+* Adds the fields expanded relations are loaded from to the expanded fields.
+ */
+func (s *RelationService[T]) expand(expand []string) []string {
+	expanded := slices.Clone(expand)
+	for _, relation := range s.relations {
+		if slices.Contains(expand, relation.Field) {
+			expanded = append(expanded, relation.Expand...)
+		}
+	}
+	return expanded
+}
+
+/*
+* Loads the expanded relations of resources.
+ */
+func (s *RelationService[T]) load(ctx context.Context, resources []T, expand []string) error {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	for _, relation := range s.relations {
+		if !slices.Contains(expand, relation.Field) {
+			continue
+		}
+
+		err := relation.Load(ctx, resources)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+* BelongsTo is a relation to a resource referenced by a foreign key, e.g. the project of a task.
+* field is the JSON name of the expandable field, and key the JSON name of the foreign key.
+* The referenced resources of all resources are loaded in a single query.
+ */
+func BelongsTo[T any, R any](field string, key string, repository krest.Repository[R], id func(resource T) *uuid.UUID, set func(resource *T, related *R)) Relation[T] {
+	return Relation[T]{
+		Field:  field,
+		Expand: []string{key},
+		Load: func(ctx context.Context, resources []T) error {
+			ids := []uuid.UUID{}
+			for _, resource := range resources {
+				if relatedID := id(resource); relatedID != nil {
+					ids = append(ids, *relatedID)
+				}
+			}
+
+			related, err := repository.List(ctx, krest.CollectionQuery{
+				Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: ids}},
+			})
+			if err != nil {
+				return err
+			}
+
+			byID := map[uuid.UUID]*R{}
+			for i := range related {
+				relatedID, err := primaryKeyValue(related[i])
+				if err != nil {
+					return err
+				}
+				byID[relatedID] = &related[i]
+			}
+
+			for i := range resources {
+				if relatedID := id(resources[i]); relatedID != nil {
+					set(&resources[i], byID[*relatedID])
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package krest_orm
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+/*
+* RevisionHandler implements the http api for revisions of a resource. [/v1/tasks/{uuid}/revisions]
+* Every request first gets the resource through the service, so revisions are only visible to those who can see it.
+ */
+type RevisionHandler[T any] struct {
+	service   krest.Service[T]
+	revisions RevisionRepository[T]
+}
+
+func NewRevisionHandler[T any](service krest.Service[T], revisions RevisionRepository[T]) *RevisionHandler[T] {
+	return &RevisionHandler[T]{service: service, revisions: revisions}
+}
+
+/*
+* Lists the revisions of a resource, with the changes of each revision. [GET /v1/tasks/{uuid}/revisions]
+ */
+func (h *RevisionHandler[T]) List(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Make sure the resource is visible.
+	_, err = h.service.Get(r.Context(), id, krest.ResourceQuery{})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the revisions
+	revisions, err := h.revisions.ListRevisions(r.Context(), id, query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, revisions, len(revisions), len(revisions), query, metaQuery)
+}
+
+/*
+* Returns a revision, including a snapshot of the resource at that revision. [GET /v1/tasks/{uuid}/revisions/{number}]
+ */
+func (h *RevisionHandler[T]) Get(w http.ResponseWriter, r *http.Request) {
+	id, number, ok := parseRevisionParams(w, r)
+	if !ok {
+		return
+	}
+
+	// Make sure the resource is visible.
+	_, err := h.service.Get(r.Context(), id, krest.ResourceQuery{})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the revision
+	revision, err := h.revisions.GetRevision(r.Context(), id, number)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, revision, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+/*
+* Restores the values of a revision, by updating the resource to its snapshot. [POST /v1/tasks/{uuid}/revisions/{number}:revert]
+* The update goes through the service like any other update, and is stored as a new revision.
+ */
+func (h *RevisionHandler[T]) Revert(w http.ResponseWriter, r *http.Request) {
+	id, number, ok := parseRevisionParams(w, r)
+	if !ok {
+		return
+	}
+
+	// Get the current resource, read-only fields are kept as they are.
+	expand, err := krest.ExpandableFieldNames[T]()
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	current, err := h.service.Get(r.Context(), id, krest.ResourceQuery{Expand: expand})
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the revision to revert to.
+	revision, err := h.revisions.GetRevision(r.Context(), id, number)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	resource := *revision.Snapshot
+	krest.CopyReadOnlyFields(&resource, current)
+
+	// Validate the resource, references might not exist anymore.
+	var checker krest.ReferenceChecker
+	if c, ok := h.service.(krest.ReferenceChecker); ok {
+		checker = c
+	}
+	err = krest.ValidateResource(r.Context(), resource, checker)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Update the resource
+	updatedResource, err := h.service.Update(r.Context(), id, resource)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, updatedResource, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+/*
+* Parses the resource and revision number of the url. Writes an error response if they're invalid.
+ */
+func parseRevisionParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, int, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, 0, false
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil || number < 1 {
+		krest.WriteErrorResponse(w, krest.NewError(http.StatusBadRequest, "invalid revision number: %s", chi.URLParam(r, "number")))
+		return uuid.Nil, 0, false
+	}
+
+	return id, number, true
+}
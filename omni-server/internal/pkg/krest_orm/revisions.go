@@ -0,0 +1,266 @@
+package krest_orm
+
+/*
+* Revision history. Models with the primary key tagged `krest_orm:"pk,revisions"` keep a snapshot of every
+* version of a resource in a separate table, e.g. task_revisions for tasks. Revisions are numbered per resource,
+* starting at 1 for the created resource, and hold the changes made compared to the revision before.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type actorContextKey struct{}
+
+/*
+* Returns a copy of the context carrying the user making changes, recorded in revisions.
+ */
+func WithActor(ctx context.Context, actorID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actorID)
+}
+
+/*
+* Returns the user making changes, uuid.Nil if unknown.
+ */
+func ActorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctx.Value(actorContextKey{}).(uuid.UUID)
+	return actorID
+}
+
+/*
+* Revision is a version of a resource. The snapshot is only included when getting a single revision.
+ */
+type Revision[T any] struct {
+	Number     int                          `json:"number"`
+	ResourceID uuid.UUID                    `json:"resource_id"`
+	ActorID    uuid.UUID                    `json:"actor_id"`
+	CreatedAt  time.Time                    `json:"created_at"`
+	Changes    map[string]krest.FieldChange `json:"changes"`
+	Snapshot   *T                           `json:"snapshot,omitempty"`
+}
+
+/*
+* RevisionRepository is implemented by repositories keeping revisions of resources.
+ */
+type RevisionRepository[T any] interface {
+	ListRevisions(ctx context.Context, id uuid.UUID, query krest.CollectionQuery) ([]Revision[T], error)
+	GetRevision(ctx context.Context, id uuid.UUID, number int) (Revision[T], error)
+}
+
+// A revision as stored in the database, the snapshot and changes are stored as JSON.
+type revisionRow struct {
+	UUID       uuid.UUID
+	ResourceID uuid.UUID
+	Number     int
+	ActorID    uuid.UUID
+	CreatedAt  time.Time
+	Snapshot   string
+	Changes    string
+}
+
+/*
+* Returns the name of the revisions table of a table, e.g. task_revisions for tasks.
+ */
+func revisionsTableName(table string) string {
+	return pluralize.NewClient().Singular(table) + "_revisions"
+}
+
+/*
+* Creates the revisions table, rows are deleted together with their resource.
+ */
+func (r *GenericPostgresRepository[T]) createRevisionsTable() error {
+	query := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (uuid UUID PRIMARY KEY, resource_id UUID NOT NULL REFERENCES %s(uuid) ON DELETE CASCADE, number INTEGER NOT NULL, actor_id UUID, created_at TIMESTAMP, snapshot TEXT, changes TEXT, UNIQUE (resource_id, number));",
+		r.revisionsTable,
+		r.tableSchema.Name,
+	)
+	_, err := r.db.Exec(query)
+	return err
+}
+
+/*
+* Stores a new revision of a resource, unless nothing changed since the last revision.
+ */
+func (r *GenericPostgresRepository[T]) recordRevision(ctx context.Context, resource T) error {
+	if r.revisionsTable == "" {
+		return nil
+	}
+
+	id, err := primaryKeyValue(resource)
+	if err != nil {
+		return err
+	}
+
+	// Compare with the latest revision.
+	var previous interface{}
+	var latest revisionRow
+	query := fmt.Sprintf("SELECT * FROM %s WHERE resource_id = $1 ORDER BY number DESC LIMIT 1", r.revisionsTable)
+	err = sqlx.GetContext(ctx, r.conn(ctx), &latest, query, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get latest revision: %v", err)
+	}
+	if err == nil {
+		var snapshot T
+		err = json.Unmarshal([]byte(latest.Snapshot), &snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to decode revision snapshot: %v", err)
+		}
+		previous = snapshot
+	}
+
+	changes, err := krest.Diff(previous, resource)
+	if err != nil {
+		return err
+	}
+	if previous != nil && len(changes) == 0 {
+		return nil
+	}
+
+	snapshot, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	changesData, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	// Number the revision after the latest one, the unique constraint catches concurrent updates.
+	query = fmt.Sprintf(
+		"INSERT INTO %s (uuid, resource_id, number, actor_id, created_at, snapshot, changes) SELECT $1, $2, COALESCE(MAX(number), 0) + 1, $3, $4, $5, $6 FROM %s WHERE resource_id = $2",
+		r.revisionsTable,
+		r.revisionsTable,
+	)
+	_, err = r.conn(ctx).ExecContext(ctx, query, uuid.New(), id, ActorFromContext(ctx), time.Now().UTC(), string(snapshot), string(changesData))
+	if err != nil {
+		return fmt.Errorf("failed to insert revision: %v", err)
+	}
+
+	return nil
+}
+
+/*
+* Lists the revisions of a resource, oldest first, without snapshots.
+ */
+func (r *GenericPostgresRepository[T]) ListRevisions(ctx context.Context, id uuid.UUID, query krest.CollectionQuery) ([]Revision[T], error) {
+	if r.revisionsTable == "" {
+		return nil, fmt.Errorf("%s have no revisions", r.tableSchema.Name)
+	}
+
+	// The resource must be visible, revisions are scoped through it.
+	_, err := r.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{id}
+	selectQuery := fmt.Sprintf("SELECT * FROM %s WHERE resource_id = $1 ORDER BY number", r.revisionsTable)
+	if query.Limit > 0 {
+		args = append(args, query.Limit)
+		selectQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if query.Offset > 0 {
+		args = append(args, query.Offset)
+		selectQuery += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows := []revisionRow{}
+	err = sqlx.SelectContext(ctx, r.conn(ctx), &rows, selectQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+
+	revisions := []Revision[T]{}
+	for _, row := range rows {
+		revision, err := decodeRevision[T](row, false)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+/*
+* Returns a revision of a resource, including the snapshot of the resource at that revision.
+ */
+func (r *GenericPostgresRepository[T]) GetRevision(ctx context.Context, id uuid.UUID, number int) (Revision[T], error) {
+	if r.revisionsTable == "" {
+		return Revision[T]{}, fmt.Errorf("%s have no revisions", r.tableSchema.Name)
+	}
+
+	// The resource must be visible, revisions are scoped through it.
+	_, err := r.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return Revision[T]{}, err
+	}
+
+	var row revisionRow
+	query := fmt.Sprintf("SELECT * FROM %s WHERE resource_id = $1 AND number = $2", r.revisionsTable)
+	err = sqlx.GetContext(ctx, r.conn(ctx), &row, query, id, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Revision[T]{}, krest.NewError(http.StatusNotFound, "revision %d of %s %s not found", number, r.tableSchema.Name, id)
+	}
+	if err != nil {
+		return Revision[T]{}, fmt.Errorf("failed to query database: %v", err)
+	}
+
+	return decodeRevision[T](row, true)
+}
+
+/*
+* Decodes a stored revision.
+ */
+func decodeRevision[T any](row revisionRow, withSnapshot bool) (Revision[T], error) {
+	revision := Revision[T]{
+		Number:     row.Number,
+		ResourceID: row.ResourceID,
+		ActorID:    row.ActorID,
+		CreatedAt:  row.CreatedAt,
+	}
+
+	err := json.Unmarshal([]byte(row.Changes), &revision.Changes)
+	if err != nil {
+		return Revision[T]{}, fmt.Errorf("failed to decode revision changes: %v", err)
+	}
+
+	if withSnapshot {
+		revision.Snapshot = new(T)
+		err = json.Unmarshal([]byte(row.Snapshot), revision.Snapshot)
+		if err != nil {
+			return Revision[T]{}, fmt.Errorf("failed to decode revision snapshot: %v", err)
+		}
+	}
+
+	return revision, nil
+}
+
+/*
+* Returns the value of the primary key of a resource.
+ */
+func primaryKeyValue[T any](resource T) (uuid.UUID, error) {
+	field, err := krest.ReflectPrimaryKeyField[T]()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id, ok := reflect.ValueOf(resource).FieldByIndex(field.Index).Interface().(uuid.UUID)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("primary key of %T is not a uuid", resource)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,72 @@
+package krest_orm_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type RevisionTestType struct {
+	UUID uuid.UUID `json:"uuid" krest_orm:"pk,revisions"`
+	Name string    `json:"name"`
+}
+
+func TestRevisions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[RevisionTestType](db)
+
+	actorID := uuid.New()
+	ctx := krest_orm.WithActor(context.Background(), actorID)
+
+	created, err := repository.Create(ctx, RevisionTestType{Name: "One"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for _, name := range []string{"Two", "Two", "Three"} {
+		_, err = repository.Update(ctx, created.UUID, RevisionTestType{UUID: created.UUID, Name: name})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	// Updates without changes are not stored.
+	revisions, err := repository.ListRevisions(ctx, created.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("ListRevisions returned incorrect number of revisions: got %d, want 3", len(revisions))
+	}
+	changes := revisions[2].Changes
+	if revisions[2].Number != 3 || revisions[2].ActorID != actorID || changes["name"].Before != "Two" || changes["name"].After != "Three" {
+		t.Errorf("ListRevisions returned incorrect revision: got %+v", revisions[2])
+	}
+
+	revision, err := repository.GetRevision(ctx, created.UUID, 1)
+	if err != nil {
+		t.Fatalf("GetRevision failed: %v", err)
+	}
+	if revision.Snapshot == nil || revision.Snapshot.Name != "One" {
+		t.Errorf("GetRevision returned incorrect snapshot: got %+v", revision.Snapshot)
+	}
+
+	// Revisions are deleted together with the resource.
+	err = repository.Delete(ctx, created.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	_, err = repository.GetRevision(ctx, created.UUID, 1)
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a revision of a deleted resource, got %v", err)
+	}
+}
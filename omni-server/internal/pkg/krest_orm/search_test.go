@@ -0,0 +1,130 @@
+//go:build sqlite_fts5
+
+package krest_orm_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SearchTestType struct {
+	UUID     uuid.UUID `json:"uuid" krest_orm:"pk"`
+	TenantID uuid.UUID `json:"tenant_id" krest_orm:"tenant"`
+	Title    string    `json:"title" krest_orm:"searchable"`
+	Body     string    `json:"body" krest_orm:"searchable"`
+	Rank     int       `json:"rank"`
+}
+
+func TestSearch(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[SearchTestType](db)
+
+	tenantA := krest_orm.WithTenant(context.Background(), uuid.New())
+	tenantB := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	if err := repository.SearchError(); err != nil {
+		t.Fatalf("full-text search is unavailable: %v", err)
+	}
+
+	resources := []struct {
+		ctx      context.Context
+		resource SearchTestType
+	}{
+		{tenantA, SearchTestType{Title: "Login fails", Body: "The <b>login</b> page fails after a login attempt with a wrong password.", Rank: 1}},
+		{tenantA, SearchTestType{Title: "Slow dashboard", Body: "The dashboard takes a while to load after login.", Rank: 2}},
+		{tenantA, SearchTestType{Title: "Signup", Body: "Add a signup page.", Rank: 3}},
+		{tenantB, SearchTestType{Title: "Login", Body: "Login of another tenant.", Rank: 4}},
+	}
+	ids := []uuid.UUID{}
+	for _, r := range resources {
+		created, err := repository.Create(r.ctx, r.resource)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids = append(ids, created.UUID)
+	}
+
+	// Results are ranked, and only of the tenant.
+	results, err := repository.Search(tenantA, krest.CollectionQuery{Search: "login", Expand: []string{"title"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Resource.UUID != ids[0] || results[1].Resource.UUID != ids[1] || results[0].Rank < results[1].Rank {
+		t.Fatalf("expected the two matches of the tenant, most relevant first, got %+v", results)
+	}
+	if results[0].Resource.Title != "Login fails" {
+		t.Errorf("expected the resource to be loaded, got %+v", results[0].Resource)
+	}
+	if results[1].Snippet != "The dashboard takes a while to load after <mark>login</mark>." {
+		t.Errorf("expected a highlighted snippet, got %q", results[1].Snippet)
+	}
+	if !strings.Contains(results[0].Snippet, "&lt;b&gt;") {
+		t.Errorf("expected the snippet to be escaped, got %q", results[0].Snippet)
+	}
+
+	// Pages are ranked like the whole, and counted without paging.
+	results, err = repository.Search(tenantA, krest.CollectionQuery{Search: "login", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Resource.UUID != ids[1] || !strings.Contains(results[0].Snippet, "<mark>login</mark>") {
+		t.Errorf("expected the second match with its snippet, got %+v", results)
+	}
+	total, err := repository.Count(tenantA, krest.CollectionQuery{Search: "login", Limit: 1})
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected 2 matches, got %d", total)
+	}
+
+	// Updates are indexed, search syntax is ignored.
+	updated := resources[2].resource
+	updated.UUID, updated.Body = ids[2], "Add a signup page, and a login link on it."
+	_, err = repository.Update(tenantA, ids[2], updated)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	listed, err := repository.List(tenantA, krest.CollectionQuery{
+		Search:  `"dashboard (login*`,
+		Filters: []krest.Filter{{Field: "rank", Operator: krest.FilterGreaterThan, Value: 1}},
+		Sort:    []krest.Sort{{Field: "rank", Descending: true}},
+	})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].UUID != ids[1] {
+		t.Errorf("expected the resource with both words, got %+v", listed)
+	}
+	listed, err = repository.List(tenantA, krest.CollectionQuery{Search: "signup login"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].UUID != ids[2] {
+		t.Errorf("expected the updated resource, got %+v", listed)
+	}
+
+	// Deleted resources are removed from the index.
+	err = repository.Delete(tenantA, ids[0])
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, err = repository.Search(tenantA, krest.CollectionQuery{Search: "password"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results, got %+v", results)
+	}
+}
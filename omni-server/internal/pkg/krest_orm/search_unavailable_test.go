@@ -0,0 +1,39 @@
+//go:build !sqlite_fts5
+
+package krest_orm_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type UnavailableSearchTestType struct {
+	UUID  uuid.UUID `json:"uuid" krest_orm:"pk"`
+	Title string    `json:"title" krest_orm:"searchable"`
+}
+
+// Without FTS5 searches fail, and the error is reported so the server can refuse to start.
+// The search tests need -tags sqlite_fts5, see the Makefile.
+func TestSearchUnavailable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[UnavailableSearchTestType](db)
+
+	if repository.SearchError() == nil {
+		t.Fatalf("expected full-text search to be unavailable without FTS5")
+	}
+	_, err = repository.Search(krest_orm.WithoutTenant(context.Background()), krest.CollectionQuery{Search: "login"})
+	if krest.ErrorStatus(err) != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %v", err)
+	}
+}
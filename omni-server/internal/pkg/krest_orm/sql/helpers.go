@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/gertd/go-pluralize"
 	"github.com/google/uuid"
@@ -57,10 +58,13 @@ func TableName[T any]() string {
 
 /*
 * Helper function for converting go types to SQL types.
-* TODO: Allow for custom types using the krest_orm tag (type:varchar(36)).
+* Other types need their SQL type set using the krest_orm tag (type:varchar(36)).
  */
 func GoTypeToSQLType(goType reflect.Type) (string, error) {
 	switch goType.Kind() {
+	case reflect.Pointer:
+		// Nil pointers are stored as NULL.
+		return GoTypeToSQLType(goType.Elem())
 	case reflect.String:
 		return "TEXT", nil
 	case reflect.Int:
@@ -76,6 +80,10 @@ func GoTypeToSQLType(goType reflect.Type) (string, error) {
 		if goType == reflect.TypeOf(uuid.UUID{}) {
 			return "UUID", nil
 		}
+	case reflect.Struct:
+		if goType == reflect.TypeOf(time.Time{}) {
+			return "TIMESTAMP", nil
+		}
 	}
 
 	return "", fmt.Errorf("unsupported type: %s, kind: %s", goType, goType.Kind())
@@ -90,22 +98,26 @@ func ColumnSchemaFromField(field reflect.StructField) (ColumnSchema, error) {
 	// Name.
 	builder.Name(ColumnName(field.Name))
 
-	// Find the SQL type of the field.
-	// TODO: Allow for custom types using the krest_orm tag (type:varchar(36)).
-	sqlType, err := GoTypeToSQLType(field.Type)
-	if err != nil {
-		return ColumnSchema{}, err
+	// Find the SQL type of the field, custom types can be set using the krest_orm tag (type:JSONB).
+	tags := GetKrestTags(field)
+	sqlType, ok := tags["type"]
+	if !ok || sqlType == "" {
+		var err error
+		sqlType, err = GoTypeToSQLType(field.Type)
+		if err != nil {
+			return ColumnSchema{}, err
+		}
 	}
 
 	builder.Type(sqlType)
 
 	// Constraints.
-	tags := GetKrestTags(field)
 	if _, ok := tags["pk"]; ok {
 		builder.AddConstraint("PRIMARY KEY")
 	}
 
-	if _, ok := tags["unique"]; ok {
+	// Unique together with other columns (unique:other_column) is a table constraint.
+	if unique, ok := tags["unique"]; ok && unique == "" {
 		builder.AddConstraint("UNIQUE")
 	}
 
@@ -156,6 +168,10 @@ func TableSchemaFromStruct[T any]() (TableSchema, error) {
 		}
 
 		builder.Column(colSchema)
+
+		if unique := tags["unique"]; unique != "" {
+			builder.AddConstraint(fmt.Sprintf("UNIQUE (%s, %s)", colSchema.Name, strings.ReplaceAll(unique, "|", ", ")))
+		}
 	}
 
 	return builder.Build()
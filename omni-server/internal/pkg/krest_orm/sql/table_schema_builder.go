@@ -9,8 +9,9 @@ import (
 * TableSchema is a helper struct representing a SQL table schema.
  */
 type TableSchema struct {
-	Name    string
-	Columns []ColumnSchema
+	Name        string
+	Columns     []ColumnSchema
+	Constraints []string
 }
 
 func (s TableSchema) ColumnDefinitions() []string {
@@ -22,16 +23,17 @@ func (s TableSchema) ColumnDefinitions() []string {
 }
 
 func (s TableSchema) CreateTableQuery() string {
-	columns := strings.Join(s.ColumnDefinitions(), ", ")
-	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", s.Name, columns)
+	definitions := append(s.ColumnDefinitions(), s.Constraints...)
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", s.Name, strings.Join(definitions, ", "))
 }
 
 /*
 * TableSchemaBuilder is a helper struct for building, and validating, SQL schemas.
  */
 type TableSchemaBuilder struct {
-	name    string
-	columns []ColumnSchema
+	name        string
+	columns     []ColumnSchema
+	constraints []string
 }
 
 func NewTableSchemaBuilder() *TableSchemaBuilder {
@@ -48,6 +50,11 @@ func (b *TableSchemaBuilder) Column(schema ColumnSchema) *TableSchemaBuilder {
 	return b
 }
 
+func (b *TableSchemaBuilder) AddConstraint(constraint string) *TableSchemaBuilder {
+	b.constraints = append(b.constraints, constraint)
+	return b
+}
+
 func (b *TableSchemaBuilder) Build() (TableSchema, error) {
 	if b.name == "" {
 		return TableSchema{}, fmt.Errorf("Table name is required")
@@ -58,7 +65,8 @@ func (b *TableSchemaBuilder) Build() (TableSchema, error) {
 	}
 
 	return TableSchema{
-		Name:    b.name,
-		Columns: b.columns,
+		Name:        b.name,
+		Columns:     b.columns,
+		Constraints: b.constraints,
 	}, nil
 }
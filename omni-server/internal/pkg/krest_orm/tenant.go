@@ -0,0 +1,73 @@
+package krest_orm
+
+/*
+* Multi-tenancy support. Models with a field tagged `krest_orm:"tenant"` are scoped to a tenant,
+* every query the generic repository makes for them is restricted to the tenant of the context.
+* Queries without a tenant in the context fail, unless the context explicitly opts out using WithoutTenant.
+ */
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type tenantContextKey struct{}
+
+type unscopedContextKey struct{}
+
+var ErrMissingTenant = krest.NewError(http.StatusBadRequest, "no organization selected, sign in or set the X-Omni-Organization header")
+
+/*
+* Returns a copy of the context scoped to a tenant.
+ */
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+/*
+* Returns the tenant of the context, if any.
+ */
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(uuid.UUID)
+	return tenantID, ok && tenantID != uuid.Nil
+}
+
+/*
+* Returns a copy of the context that is explicitly not scoped to a tenant, for operations spanning tenants.
+* E.g. signing in, before the tenant of the user is known. Use with care.
+ */
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(context.WithValue(ctx, tenantContextKey{}, uuid.Nil), unscopedContextKey{}, true)
+}
+
+func isUnscoped(ctx context.Context) bool {
+	unscoped, _ := ctx.Value(unscopedContextKey{}).(bool)
+	return unscoped
+}
+
+// Tenant columns of all tables managed by generic repositories, so references to them can be scoped.
+var tenantColumns sync.Map // map[string]string
+
+/*
+* Returns the condition restricting a query writing its own SQL to the rows of a table of the tenant of the context,
+* qualified with the table so it can be used in joins, numbering the placeholder argIdx.
+* Fails with ErrMissingTenant without a tenant in the context, even if the context explicitly opts out.
+ */
+func TenantCondition(ctx context.Context, table string, argIdx int) (string, []interface{}, error) {
+	column, ok := tenantColumns.Load(table)
+	if !ok {
+		return "", nil, fmt.Errorf("table %s is not scoped to a tenant", table)
+	}
+
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return "", nil, ErrMissingTenant
+	}
+
+	return fmt.Sprintf("%s.%s = $%d", table, column, argIdx), []interface{}{tenantID}, nil
+}
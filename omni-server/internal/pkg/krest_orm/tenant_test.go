@@ -0,0 +1,132 @@
+package krest_orm_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type TenantTestType struct {
+	UUID     uuid.UUID `json:"uuid" krest_orm:"pk"`
+	TenantID uuid.UUID `json:"tenant_id" krest_orm:"tenant"`
+	Name     string    `json:"name" krest_orm:"unique:tenant_id"`
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[TenantTestType](db)
+
+	tenantA := krest_orm.WithTenant(context.Background(), uuid.New())
+	tenantB := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	// The tenant of the resource is always the tenant of the context.
+	created, err := repository.Create(tenantA, TenantTestType{TenantID: uuid.New(), Name: "Shared"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	tenantID, _ := krest_orm.TenantFromContext(tenantA)
+	if created.TenantID != tenantID {
+		t.Errorf("Create stored incorrect tenant: got %s, want %s", created.TenantID, tenantID)
+	}
+
+	// Unique per tenant.
+	_, err = repository.Create(tenantB, TenantTestType{Name: "Shared"})
+	if err != nil {
+		t.Fatalf("Create failed for the same name in another tenant: %v", err)
+	}
+
+	_, err = repository.Create(tenantA, TenantTestType{Name: "Shared"})
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for the same name in the same tenant, got %v", err)
+	}
+
+	// Other tenants can't see, change or delete the resource.
+	resources, err := repository.List(tenantB, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(resources) != 1 || resources[0].UUID == created.UUID {
+		t.Errorf("List returned resources of another tenant: got %+v", resources)
+	}
+
+	_, err = repository.Get(tenantB, created.UUID, krest.ResourceQuery{})
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a resource of another tenant, got %v", err)
+	}
+
+	_, err = repository.Update(tenantB, created.UUID, TenantTestType{UUID: created.UUID, Name: "Stolen"})
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 updating a resource of another tenant, got %v", err)
+	}
+
+	err = repository.Delete(tenantB, created.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	exists, err := repository.ReferenceExists(tenantB, "tenant_test_types", created.UUID)
+	if err != nil {
+		t.Fatalf("ReferenceExists failed: %v", err)
+	}
+	if exists {
+		t.Errorf("ReferenceExists found a resource of another tenant")
+	}
+
+	current, err := repository.Get(tenantA, created.UUID, krest.ResourceQuery{})
+	if err != nil || current.Name != "Shared" {
+		t.Errorf("resource was changed by another tenant: got %+v, %v", current, err)
+	}
+
+	// Queries without a tenant fail, unless explicitly unscoped.
+	_, err = repository.List(context.Background(), krest.CollectionQuery{})
+	if err != krest_orm.ErrMissingTenant {
+		t.Errorf("expected missing tenant error, got %v", err)
+	}
+
+	resources, err = repository.List(krest_orm.WithoutTenant(context.Background()), krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Errorf("List returned incorrect number of resources: got %d, want 2", len(resources))
+	}
+}
+
+func TestTenantCondition(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	krest_orm.NewGenericPostgresRepository[TenantTestType](db)
+
+	tenantID := uuid.New()
+	condition, args, err := krest_orm.TenantCondition(krest_orm.WithTenant(context.Background(), tenantID), "tenant_test_types", 2)
+	if err != nil {
+		t.Fatalf("TenantCondition failed: %v", err)
+	}
+	if condition != "tenant_test_types.tenant_id = $2" || len(args) != 1 || args[0] != tenantID {
+		t.Errorf("TenantCondition returned incorrect condition: got %s, %v", condition, args)
+	}
+
+	// Queries writing their own SQL always need a tenant.
+	_, _, err = krest_orm.TenantCondition(krest_orm.WithoutTenant(context.Background()), "tenant_test_types", 2)
+	if err != krest_orm.ErrMissingTenant {
+		t.Errorf("expected missing tenant error, got %v", err)
+	}
+
+	_, _, err = krest_orm.TenantCondition(krest_orm.WithTenant(context.Background(), tenantID), "unknown", 2)
+	if err == nil {
+		t.Errorf("expected an error for a table not scoped to a tenant")
+	}
+}
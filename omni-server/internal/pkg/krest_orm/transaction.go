@@ -0,0 +1,124 @@
+package krest_orm
+
+/*
+* Transaction support. The transaction is carried in the context, every query the generic repository makes with
+* the context runs in it. Repositories writing their own SQL run their queries on Conn to take part.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+type transactionContextKey struct{}
+
+type transaction struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+/*
+* Querier runs queries, either on a database or in a transaction.
+ */
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+/*
+* Transactor runs functions in transactions, implemented by the generic repository and repositories writing their own SQL.
+ */
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+/*
+* Runs fn in a transaction of the database, committed if fn succeeds and rolled back otherwise.
+* Queries with the context passed to fn run in the transaction, transactions started in fn join it.
+ */
+func Transaction(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if current, ok := ctx.Value(transactionContextKey{}).(*transaction); ok && current.db == db {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	err = fn(context.WithValue(ctx, transactionContextKey{}, &transaction{db: db, tx: tx}))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+/*
+* Returns the transaction of the context if it's a transaction of the database, the database otherwise.
+ */
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if current, ok := ctx.Value(transactionContextKey{}).(*transaction); ok && current.db == db {
+		return current.tx
+	}
+	return db
+}
+
+/*
+* Locks the row with the uuid of a table of the tenant of the context until the end of the transaction of the context,
+* so it can be read and changed without concurrent changes in between.
+ */
+func LockRow(ctx context.Context, db *sql.DB, table string, id uuid.UUID) error {
+	if current, ok := ctx.Value(transactionContextKey{}).(*transaction); !ok || current.db != db {
+		return fmt.Errorf("locking %s %s requires a transaction", table, id)
+	}
+
+	condition, args, err := tenantCondition(ctx, table, 2)
+	if err != nil {
+		return err
+	}
+	if condition != "" {
+		condition = " AND " + condition
+	}
+
+	var found int
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE uuid = $1%s%s", table, condition, dialectOf(db).lockClause())
+	err = Conn(ctx, db).QueryRowContext(ctx, query, append([]interface{}{id}, args...)...).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return krest.NewError(http.StatusNotFound, "%s %s not found", table, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock %s %s: %v", table, id, err)
+	}
+	return nil
+}
+
+/*
+* Returns the transaction of the context or the database, mapping struct fields to columns the same way the schema does.
+ */
+func (r *GenericPostgresRepository[T]) conn(ctx context.Context) sqlx.ExtContext {
+	if current, ok := ctx.Value(transactionContextKey{}).(*transaction); ok && current.db == r.db.DB {
+		return &sqlx.Tx{Tx: current.tx, Mapper: r.db.Mapper}
+	}
+	return r.db
+}
+
+/*
+* Runs fn in a transaction of the database of the repository, see Transaction.
+ */
+func (r *GenericPostgresRepository[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Transaction(ctx, r.db.DB, fn)
+}
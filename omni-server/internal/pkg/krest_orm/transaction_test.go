@@ -0,0 +1,112 @@
+package krest_orm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type TransactionTestType struct {
+	UUID uuid.UUID `json:"uuid" krest_orm:"pk"`
+	Name string    `json:"name"`
+}
+
+func TestTransaction(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// A single connection, queries outside of the transaction would wait for it forever.
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[TransactionTestType](db)
+	ctx := krest_orm.WithoutTenant(context.Background())
+
+	count := func() int {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM transaction_test_types").Scan(&count)
+		if err != nil {
+			t.Fatalf("failed to count: %v", err)
+		}
+		return count
+	}
+
+	// Failures roll back everything, including nested transactions.
+	failure := errors.New("failure")
+	err = krest_orm.Transaction(ctx, db, func(ctx context.Context) error {
+		_, err := repository.Create(ctx, TransactionTestType{Name: "Rolled back"})
+		if err != nil {
+			return err
+		}
+		return krest_orm.Transaction(ctx, db, func(ctx context.Context) error {
+			_, err := krest_orm.Conn(ctx, db).ExecContext(ctx, "UPDATE transaction_test_types SET name = $1", "Updated")
+			if err != nil {
+				return err
+			}
+			return failure
+		})
+	})
+	if err != failure {
+		t.Fatalf("expected the error of the transaction, got %v", err)
+	}
+	if count() != 0 {
+		t.Errorf("expected the transaction to be rolled back")
+	}
+
+	err = krest_orm.Transaction(ctx, db, func(ctx context.Context) error {
+		created, err := repository.Create(ctx, TransactionTestType{Name: "Committed"})
+		if err != nil {
+			return err
+		}
+		_, err = repository.Get(ctx, created.UUID, krest.ResourceQuery{})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if count() != 1 {
+		t.Errorf("expected the transaction to be committed")
+	}
+}
+
+func TestLockRow(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	repository := krest_orm.NewGenericPostgresRepository[TransactionTestType](db)
+	ctx := krest_orm.WithoutTenant(context.Background())
+
+	created, err := repository.Create(ctx, TransactionTestType{Name: "Locked"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Locks are released at the end of a transaction, there's nothing to lock without one.
+	err = krest_orm.LockRow(ctx, db, "transaction_test_types", created.UUID)
+	if err == nil {
+		t.Errorf("expected locking outside of a transaction to fail")
+	}
+
+	err = krest_orm.Transaction(ctx, db, func(ctx context.Context) error {
+		err := krest_orm.LockRow(ctx, db, "transaction_test_types", created.UUID)
+		if err != nil {
+			return err
+		}
+		err = krest_orm.LockRow(ctx, db, "transaction_test_types", uuid.New())
+		if krest.ErrorStatus(err) != http.StatusNotFound {
+			t.Errorf("expected 404 for locking a nonexistent row, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package ranking
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ranks are strings of base 36 digits, ordered lexicographically. There is always a rank between two ranks,
+// so moving a task only changes its own rank. Ranks never end with the lowest digit, so there is always room before them.
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// Between returns a rank ordered after a and before b. An empty a is the start of the list, an empty b the end.
+func Between(a string, b string) (string, error) {
+	if b != "" && a >= b {
+		return "", fmt.Errorf("rank %q is not before %q", a, b)
+	}
+
+	rank := strings.Builder{}
+	bounded := b != ""
+	for i := 0; ; i++ {
+		low := digitAt(a, i, 0)
+		high := base
+		if bounded {
+			high = digitAt(b, i, 0)
+		}
+
+		if low > high || (low == high && bounded && i >= len(b)) {
+			return "", fmt.Errorf("rank %q is not before %q", a, b)
+		}
+
+		if high-low > 1 {
+			rank.WriteByte(digits[(low+high)/2])
+			return rank.String(), nil
+		}
+
+		// There's no room at this position, the rank continues after a.
+		rank.WriteByte(digits[low])
+		if high-low == 1 {
+			bounded = false
+		}
+	}
+}
+
+// Spread returns n evenly spaced ranks, for rebalancing a list.
+func Spread(n int) []string {
+	// Use enough digits to leave room between the ranks.
+	width := 1
+	for capacity := base; capacity < (n+1)*base; capacity *= base {
+		width++
+	}
+
+	capacity := 1
+	for i := 0; i < width; i++ {
+		capacity *= base
+	}
+
+	ranks := make([]string, n)
+	for i := range ranks {
+		value := (i + 1) * capacity / (n + 1)
+
+		rank := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			rank[j] = digits[value%base]
+			value /= base
+		}
+		ranks[i] = strings.TrimRight(string(rank), digits[:1])
+	}
+	return ranks
+}
+
+// Returns the value of the digit at a position of a rank, or fallback past its end.
+func digitAt(rank string, i int, fallback int) int {
+	if i >= len(rank) {
+		return fallback
+	}
+	return strings.IndexByte(digits, rank[i])
+}
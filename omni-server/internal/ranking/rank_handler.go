@@ -0,0 +1,45 @@
+package ranking
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// RankHandler implements reordering the backlog. [POST /v1/tasks/{uuid}:move]
+type RankHandler struct {
+	service *RankService
+}
+
+func NewRankHandler(service *RankService) *RankHandler {
+	return &RankHandler{service: service}
+}
+
+// Move places a task before or after another task, e.g. {"before": "<uuid>"}.
+func (h *RankHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the request body.
+	var request MoveRequest
+	err = krest.DecodeRequestBody(w, r, &request, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Move the task.
+	task, err := h.service.Move(r.Context(), id, request)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusOK, task, krest.ResourceQuery{}, krest.MetaQuery{})
+}
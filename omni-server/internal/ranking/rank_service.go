@@ -0,0 +1,196 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Ranks longer than this are rebalanced in the background.
+const maxRankLength = 12
+
+// MoveRequest places a task directly before or after another task of its project.
+type MoveRequest struct {
+	Before *uuid.UUID `json:"before"`
+	After  *uuid.UUID `json:"after"`
+}
+
+// RankService wraps the task service, ranking tasks within the backlog of their project.
+// New tasks, and tasks moving to another project, go to the bottom of the backlog. Tasks are listed by rank by default.
+// Implements krest.Service[models.Task]
+type RankService struct {
+	krest.ServiceWrapper[models.Task]
+	rebalancer *Rebalancer
+}
+
+func NewRankService(service krest.Service[models.Task], rebalancer *Rebalancer) *RankService {
+	return &RankService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, rebalancer: rebalancer}
+}
+
+func (s *RankService) List(ctx context.Context, query krest.CollectionQuery) ([]models.Task, error) {
+	if len(query.Sort) == 0 {
+		query.Sort = []krest.Sort{{Field: "rank"}}
+	}
+
+	return s.Service.List(ctx, query)
+}
+
+func (s *RankService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	var err error
+	task.Rank, err = s.bottom(ctx, task.ProjectID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	created, err := s.Service.Create(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	s.checkLength(ctx, created)
+	return created, nil
+}
+
+func (s *RankService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task.Rank = current.Rank
+	if current.ProjectID != task.ProjectID {
+		task.Rank, err = s.bottom(ctx, task.ProjectID)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return s.Service.Update(ctx, id, task)
+}
+
+// Move places a task before or after another task of its project, only changing the rank of the moved task.
+func (s *RankService) Move(ctx context.Context, id uuid.UUID, request MoveRequest) (models.Task, error) {
+	field, targetID := "before", request.Before
+	if request.After != nil {
+		field, targetID = "after", request.After
+	}
+	if (request.Before == nil) == (request.After == nil) {
+		return models.Task{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "before", Rule: "required", Message: "either before or after is required"}}}
+	}
+	if *targetID == id {
+		return models.Task{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: field, Rule: "task", Message: "must be another task"}}}
+	}
+
+	// Get the whole task, it's updated as a whole.
+	expand, err := krest.ExpandableFieldNames[models.Task]()
+	if err != nil {
+		return models.Task{}, err
+	}
+	task, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: expand})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Tasks without a rank, or with the same rank, have no room between them until the project is rebalanced.
+	for attempt := 0; ; attempt++ {
+		task.Rank, err = s.rankNextTo(ctx, task, field, *targetID)
+		if err == nil {
+			break
+		}
+		if attempt > 0 || krest.ErrorStatus(err) != http.StatusConflict {
+			return models.Task{}, err
+		}
+
+		err = s.rebalancer.Rebalance(ctx, task.ProjectID)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	updated, err := s.Service.Update(ctx, id, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	s.checkLength(ctx, updated)
+	return updated, nil
+}
+
+// Returns the rank between the target and its neighbour on the side of the field, before or after.
+func (s *RankService) rankNextTo(ctx context.Context, task models.Task, field string, targetID uuid.UUID) (string, error) {
+	target, err := s.Service.Get(ctx, targetID, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if krest.ErrorStatus(err) == http.StatusNotFound {
+		return "", &krest.ValidationError{Errors: []krest.FieldError{{Field: field, Rule: "ref", Message: fmt.Sprintf("references a nonexistent resource: %s", targetID)}}}
+	}
+	if err != nil {
+		return "", err
+	}
+	if target.ProjectID != task.ProjectID {
+		return "", &krest.ValidationError{Errors: []krest.FieldError{{Field: field, Rule: "project", Message: "must be a task of the same project"}}}
+	}
+	if target.Rank == "" {
+		return "", krest.NewError(http.StatusConflict, "task %s has no rank", target.UUID)
+	}
+
+	operator, descending := krest.FilterGreaterThan, false
+	if field == "before" {
+		operator, descending = krest.FilterLessThan, true
+	}
+
+	neighbours, err := s.Service.List(ctx, krest.CollectionQuery{
+		Limit: 1,
+		Filters: []krest.Filter{
+			{Field: "project_id", Operator: krest.FilterEqual, Value: task.ProjectID},
+			{Field: "uuid", Operator: krest.FilterNotEqual, Value: task.UUID},
+			{Field: "rank", Operator: operator, Value: target.Rank},
+		},
+		Sort: []krest.Sort{{Field: "rank", Descending: descending}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	neighbour := ""
+	if len(neighbours) > 0 {
+		neighbour = neighbours[0].Rank
+	}
+
+	var rank string
+	if field == "before" {
+		rank, err = Between(neighbour, target.Rank)
+	} else {
+		rank, err = Between(target.Rank, neighbour)
+	}
+	if err != nil {
+		return "", krest.NewError(http.StatusConflict, "no room next to task %s: %v", target.UUID, err)
+	}
+	return rank, nil
+}
+
+// Returns a rank at the bottom of the backlog of a project.
+func (s *RankService) bottom(ctx context.Context, projectID uuid.UUID) (string, error) {
+	last, err := s.Service.List(ctx, krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "project_id", Operator: krest.FilterEqual, Value: projectID}},
+		Sort:    []krest.Sort{{Field: "rank", Descending: true}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(last) == 0 {
+		return Between("", "")
+	}
+	return Between(last[0].Rank, "")
+}
+
+// Requests a rebalance of the project of a task if its rank got too long.
+func (s *RankService) checkLength(ctx context.Context, task models.Task) {
+	if len(task.Rank) > maxRankLength {
+		s.rebalancer.Request(ctx, task.ProjectID)
+	}
+}
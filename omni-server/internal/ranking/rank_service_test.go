@@ -0,0 +1,119 @@
+package ranking_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/ranking"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMove(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	rebalancer := ranking.NewRebalancer(db, krest_orm.NewGenericService(taskRepository))
+	tasks := ranking.NewRankService(krest_orm.NewGenericService(taskRepository), rebalancer)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// New tasks go to the bottom of the backlog.
+	ids := map[string]uuid.UUID{}
+	for _, summary := range []string{"A", "B", "C"} {
+		task, err := tasks.Create(ctx, models.Task{Summary: summary, ProjectID: project.UUID})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids[summary] = task.UUID
+	}
+
+	backlog := func() string {
+		list, err := tasks.List(ctx, krest.CollectionQuery{Expand: []string{"summary"}})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		order := ""
+		for _, task := range list {
+			order += task.Summary
+		}
+		return order
+	}
+
+	if order := backlog(); order != "ABC" {
+		t.Errorf("expected tasks in order of creation, got %s", order)
+	}
+
+	c := ids["C"]
+	_, err = tasks.Move(ctx, ids["A"], ranking.MoveRequest{After: &c})
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if order := backlog(); order != "BCA" {
+		t.Errorf("expected A after C, got %s", order)
+	}
+
+	// Moving back and forth between the same tasks makes ranks longer, until they are rebalanced.
+	a, b := ids["A"], ids["B"]
+	for i := 0; i < 40; i++ {
+		target := &a
+		if i%2 == 1 {
+			target = &b
+		}
+		_, err = tasks.Move(ctx, ids["C"], ranking.MoveRequest{Before: target})
+		if err != nil {
+			t.Fatalf("Move failed: %v", err)
+		}
+	}
+	if order := backlog(); order != "CBA" {
+		t.Errorf("expected C before B, got %s", order)
+	}
+
+	err = rebalancer.Rebalance(ctx, project.UUID)
+	if err != nil {
+		t.Fatalf("Rebalance failed: %v", err)
+	}
+	list, err := tasks.List(ctx, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, task := range list {
+		if len(task.Rank) > 2 {
+			t.Errorf("expected short ranks after rebalancing, got %s", task.Rank)
+		}
+	}
+	if order := backlog(); order != "CBA" {
+		t.Errorf("expected rebalancing to keep the order, got %s", order)
+	}
+	revisions, err := taskRepository.ListRevisions(ctx, ids["C"], krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if rank := revisions[len(revisions)-1].Changes["rank"].After; rank == nil || len(rank.(string)) > 2 {
+		t.Errorf("expected rebalancing to be kept as a revision, got %+v", revisions)
+	}
+
+	// Tasks can only be moved next to other tasks of the project.
+	_, err = tasks.Move(ctx, ids["A"], ranking.MoveRequest{Before: &a})
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for moving a task next to itself, got %v", err)
+	}
+}
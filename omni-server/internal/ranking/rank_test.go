@@ -0,0 +1,71 @@
+package ranking_test
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/khaossystems/omni-server/internal/ranking"
+)
+
+func TestBetween(t *testing.T) {
+	cases := [][2]string{{"", ""}, {"", "i"}, {"i", ""}, {"a", "b"}, {"a", "a1"}, {"az", "b"}, {"z", ""}, {"", "01"}}
+	for _, c := range cases {
+		rank, err := ranking.Between(c[0], c[1])
+		if err != nil {
+			t.Fatalf("Between(%q, %q) failed: %v", c[0], c[1], err)
+		}
+		if rank <= c[0] || (c[1] != "" && rank >= c[1]) || strings.HasSuffix(rank, "0") {
+			t.Errorf("Between(%q, %q) = %q, not in between", c[0], c[1], rank)
+		}
+	}
+
+	_, err := ranking.Between("b", "a")
+	if err == nil {
+		t.Errorf("expected an error for ranks out of order")
+	}
+}
+
+func TestRandomInserts(t *testing.T) {
+	ranks := []string{}
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		position := random.Intn(len(ranks) + 1)
+		before, after := "", ""
+		if position > 0 {
+			before = ranks[position-1]
+		}
+		if position < len(ranks) {
+			after = ranks[position]
+		}
+
+		rank, err := ranking.Between(before, after)
+		if err != nil {
+			t.Fatalf("Between(%q, %q) failed: %v", before, after, err)
+		}
+		ranks = slices.Insert(ranks, position, rank)
+	}
+
+	if !slices.IsSorted(ranks) {
+		t.Errorf("ranks are out of order")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 35, 36, 1000} {
+		ranks := ranking.Spread(n)
+		if len(ranks) != n {
+			t.Fatalf("Spread(%d) returned %d ranks", n, len(ranks))
+		}
+		if !slices.IsSorted(ranks) || len(slices.Compact(slices.Clone(ranks))) != n {
+			t.Errorf("Spread(%d) returned ranks out of order: %v", n, ranks)
+		}
+		for _, rank := range ranks {
+			if rank == "" || strings.HasSuffix(rank, "0") {
+				t.Errorf("Spread(%d) returned invalid rank %q", n, rank)
+			}
+		}
+	}
+}
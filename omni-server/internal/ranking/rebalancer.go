@@ -0,0 +1,95 @@
+package ranking
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Rebalancer spreads the ranks of the tasks of a project evenly again, in the background.
+// Ranks grow longer as tasks are moved between the same neighbours, rebalancing makes them short again.
+// Ranks are changed through the task service in a transaction, so the changes are audited.
+type Rebalancer struct {
+	db       *sql.DB
+	tasks    krest.Service[models.Task]
+	requests chan rebalanceRequest
+}
+
+type rebalanceRequest struct {
+	organizationID uuid.UUID
+	projectID      uuid.UUID
+}
+
+// Rebalancing runs in the background without a user, the task service must not authorize.
+func NewRebalancer(db *sql.DB, tasks krest.Service[models.Task]) *Rebalancer {
+	return &Rebalancer{db: db, tasks: tasks, requests: make(chan rebalanceRequest, 64)}
+}
+
+// Request queues a rebalance of a project of the tenant of the context.
+// Requests are dropped while the queue is full, the next move requests it again.
+func (r *Rebalancer) Request(ctx context.Context, projectID uuid.UUID) {
+	organizationID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	select {
+	case r.requests <- rebalanceRequest{organizationID: organizationID, projectID: projectID}:
+	default:
+	}
+}
+
+// Run rebalances the requested projects until the context is done.
+func (r *Rebalancer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-r.requests:
+			err := r.Rebalance(krest_orm.WithTenant(ctx, request.organizationID), request.projectID)
+			if err != nil {
+				log.Printf("Failed to rebalance the ranks of project %s: %v", request.projectID, err)
+			}
+		}
+	}
+}
+
+// Rebalance spreads the ranks of the tasks of a project of the tenant of the context, keeping their order.
+func (r *Rebalancer) Rebalance(ctx context.Context, projectID uuid.UUID) error {
+	if _, ok := krest_orm.TenantFromContext(ctx); !ok {
+		return krest_orm.ErrMissingTenant
+	}
+
+	expand, err := krest.ExpandableFieldNames[models.Task]()
+	if err != nil {
+		return err
+	}
+
+	return krest_orm.Transaction(ctx, r.db, func(ctx context.Context) error {
+		tasks, err := r.tasks.List(ctx, krest.CollectionQuery{
+			Expand:  expand,
+			Filters: []krest.Filter{{Field: "project_id", Operator: krest.FilterEqual, Value: projectID}},
+			Sort:    []krest.Sort{{Field: "rank"}, {Field: "number"}},
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, rank := range Spread(len(tasks)) {
+			if tasks[i].Rank == rank {
+				continue
+			}
+			tasks[i].Rank = rank
+			_, err = r.tasks.Update(ctx, tasks[i].UUID, tasks[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"net/http"
+
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// SearchHandler implements the http api of the search. [/v1/search]
+type SearchHandler struct {
+	service *SearchService
+}
+
+func NewSearchHandler(service *SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// Search searches tasks and comments, e.g. ?q=login+fails&limit=20&offset=40. [GET /v1/search]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Search
+	results, total, err := h.service.Search(r.Context(), query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, results, len(results), total, query, metaQuery)
+}
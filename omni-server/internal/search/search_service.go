@@ -0,0 +1,118 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// The fields of the tasks listed with results.
+var taskFields = []string{"summary", "project_id", "status_id", "assignee_id", "completed_at"}
+
+// SearchService searches the tasks and comments of the projects the authenticated user is a member of.
+type SearchService struct {
+	tasks    *krest_orm.GenericPostgresRepository[models.Task]
+	comments *krest_orm.GenericPostgresRepository[models.Comment]
+	policy   *authz.Policy
+}
+
+func NewSearchService(tasks *krest_orm.GenericPostgresRepository[models.Task], comments *krest_orm.GenericPostgresRepository[models.Comment], policy *authz.Policy) *SearchService {
+	return &SearchService{tasks: tasks, comments: comments, policy: policy}
+}
+
+// Search returns a page of the tasks and comments matching the search text of a query, the most relevant first,
+// and the total number of matches.
+func (s *SearchService) Search(ctx context.Context, query krest.CollectionQuery) ([]models.SearchResult, int, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, 0, auth.ErrUnauthenticated
+	}
+	if query.Search == "" {
+		return nil, 0, krest.NewError(http.StatusBadRequest, "missing search text, expected q")
+	}
+
+	projects, err := s.policy.VisibleProjects(ctx, user.UUID)
+	if err != nil {
+		return nil, 0, err
+	}
+	visible := krest.Filter{Field: "project_id", Operator: krest.FilterIn, Value: projects}
+
+	// Comments are visible through their tasks.
+	visibleComments := krest_orm.ConditionFilter(func(table string, argIdx int) (string, []interface{}) {
+		if len(projects) == 0 {
+			return "1 = 0", nil
+		}
+		placeholders, args := krest_orm.Placeholders(projects, argIdx)
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM tasks WHERE tasks.uuid = %s.task_id AND tasks.project_id IN (%s))", table, placeholders), args
+	})
+
+	// The page of the merged results is within the first offset + limit results of each.
+	taskQuery := krest.CollectionQuery{Search: query.Search, Expand: taskFields, Filters: []krest.Filter{visible}}
+	commentQuery := krest.CollectionQuery{Search: query.Search, Filters: []krest.Filter{visibleComments}}
+	if query.Limit > 0 {
+		taskQuery.Limit, commentQuery.Limit = query.Offset+query.Limit, query.Offset+query.Limit
+	}
+
+	taskTotal, err := s.tasks.Count(ctx, taskQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	commentTotal, err := s.comments.Count(ctx, commentQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tasks, err := s.tasks.Search(ctx, taskQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := []models.SearchResult{}
+	for _, task := range tasks {
+		results = append(results, models.SearchResult{Type: models.SearchResultTask, Rank: task.Rank, Snippet: task.Snippet, Task: &task.Resource})
+	}
+
+	comments, err := s.comments.Search(ctx, commentQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	taskIDs := []uuid.UUID{}
+	for _, comment := range comments {
+		taskIDs = append(taskIDs, comment.Resource.TaskID)
+	}
+	commentTasks, err := s.tasks.List(ctx, krest.CollectionQuery{
+		Expand:  taskFields,
+		Filters: []krest.Filter{{Field: "uuid", Operator: krest.FilterIn, Value: taskIDs}},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := map[uuid.UUID]models.Task{}
+	for _, task := range commentTasks {
+		byID[task.UUID] = task
+	}
+	for _, comment := range comments {
+		task, ok := byID[comment.Resource.TaskID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.SearchResult{Type: models.SearchResultComment, Rank: comment.Rank, Snippet: comment.Snippet, Task: &task, Comment: &comment.Resource})
+	}
+
+	slices.SortStableFunc(results, func(a models.SearchResult, b models.SearchResult) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	results = results[min(query.Offset, len(results)):]
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, taskTotal + commentTotal, nil
+}
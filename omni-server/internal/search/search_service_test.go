@@ -0,0 +1,113 @@
+//go:build sqlite_fts5
+
+package search_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/search"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSearch(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	commentRepository := krest_orm.NewGenericPostgresRepository[models.Comment](db)
+	policy := authz.NewPolicy(membershipRepository)
+	service := search.NewSearchService(taskRepository, commentRepository, policy)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	user, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	alice := auth.WithUser(ctx, user)
+
+	// Alice isn't a member of the secret project.
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	secret, err := projectRepository.Create(ctx, models.Project{Name: "Secret", Key: "SEC"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	err = policy.AddMember(ctx, project.UUID, user.UUID, authz.RoleViewer)
+	if err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	tasks := []models.Task{
+		{Summary: "Login fails", Key: "OMNI-1", ProjectID: project.UUID, Description: "The login page fails with a wrong password."},
+		{Summary: "Dashboard", Key: "OMNI-2", ProjectID: project.UUID},
+		{Summary: "Secret login", Key: "SEC-1", ProjectID: secret.UUID},
+	}
+	for i := range tasks {
+		tasks[i], err = taskRepository.Create(ctx, tasks[i])
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	for _, c := range []models.Comment{
+		{TaskID: tasks[1].UUID, AuthorID: &user.UUID, Body: "Slow after login"},
+		{TaskID: tasks[2].UUID, AuthorID: &user.UUID, Body: "Login of the secret project"},
+	} {
+		_, err = commentRepository.Create(ctx, c)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	results, total, err := service.Search(alice, krest.CollectionQuery{Search: "login", Limit: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || total != 2 {
+		t.Fatalf("expected a task and a comment of the visible project, got %+v", results)
+	}
+	byType := map[string]models.SearchResult{}
+	for _, result := range results {
+		byType[result.Type] = result
+	}
+	if task := byType[models.SearchResultTask]; task.Task == nil || task.Task.Key != "OMNI-1" || task.Task.Summary != "Login fails" {
+		t.Errorf("expected OMNI-1 to match, got %+v", results)
+	}
+	if comment := byType[models.SearchResultComment]; comment.Task == nil || comment.Task.Key != "OMNI-2" || comment.Snippet != "Slow after <mark>login</mark>" {
+		t.Errorf("expected the comment on OMNI-2 to match, got %+v", results)
+	}
+
+	// Pages are taken from the merged results.
+	page := []models.SearchResult{}
+	for offset := range 3 {
+		results, total, err = service.Search(alice, krest.CollectionQuery{Search: "login", Limit: 1, Offset: offset})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if total != 2 {
+			t.Errorf("expected 2 matches in total, got %d", total)
+		}
+		page = append(page, results...)
+	}
+	if len(page) != 2 || page[0].Type == page[1].Type || !strings.Contains(page[1].Snippet, "<mark>") {
+		t.Errorf("expected a result per page, got %+v", page)
+	}
+}
@@ -0,0 +1,16 @@
+package sprint
+
+import (
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskRelation loads the sprint of tasks, when expanded with ?expand=sprint.
+func TaskRelation(sprints krest.Repository[models.Sprint]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("sprint", "sprint_id", sprints,
+		func(task models.Task) *uuid.UUID { return task.SprintID },
+		func(task *models.Task, sprint *models.Sprint) { task.Sprint = sprint },
+	)
+}
@@ -0,0 +1,66 @@
+package sprint
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// SprintHandler implements the actions of sprints. [POST /v1/sprints/{uuid}:start, POST /v1/sprints/{uuid}:close]
+type SprintHandler struct {
+	service *SprintService
+}
+
+func NewSprintHandler(service *SprintService) *SprintHandler {
+	return &SprintHandler{service: service}
+}
+
+// Start starts a planned sprint.
+func (h *SprintHandler) Start(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Start the sprint.
+	sprint, err := h.service.Start(r.Context(), id)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusOK, sprint, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Close closes an active sprint, moving incomplete tasks to the backlog or another sprint, e.g. {"move_to": "<uuid>"}.
+func (h *SprintHandler) Close(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the request body, it's optional.
+	var request CloseRequest
+	if r.ContentLength != 0 {
+		err = krest.DecodeRequestBody(w, r, &request, krest.DefaultDecodeOptions)
+		if err != nil {
+			krest.WriteErrorResponse(w, err)
+			return
+		}
+	}
+
+	// Close the sprint.
+	sprint, err := h.service.Close(r.Context(), id, request)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusOK, sprint, krest.ResourceQuery{}, krest.MetaQuery{})
+}
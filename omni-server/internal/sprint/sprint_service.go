@@ -0,0 +1,223 @@
+package sprint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// CloseRequest closes a sprint, moving its incomplete tasks to another sprint, or the backlog without one.
+type CloseRequest struct {
+	MoveTo *uuid.UUID `json:"move_to"`
+}
+
+// SprintService wraps the sprint service, managing the state of sprints.
+// Sprints are created planned, started and closed using actions, and can't be changed once closed.
+// A project has at most one active sprint. Starting and closing sprints run in a transaction.
+// Implements krest.Service[models.Sprint]
+type SprintService struct {
+	krest.ServiceWrapper[models.Sprint]
+	tasks        krest.Service[models.Task]
+	transactions krest_orm.Transactor
+}
+
+// The transactor must run transactions of the database of the sprints and tasks, e.g. the sprint repository.
+func NewSprintService(service krest.Service[models.Sprint], tasks krest.Service[models.Task], transactions krest_orm.Transactor) *SprintService {
+	return &SprintService{ServiceWrapper: krest.ServiceWrapper[models.Sprint]{Service: service}, tasks: tasks, transactions: transactions}
+}
+
+func (s *SprintService) Create(ctx context.Context, sprint models.Sprint) (models.Sprint, error) {
+	err := validateDates(sprint)
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	sprint.State, sprint.Committed, sprint.Report = models.SprintPlanned, nil, nil
+	return s.Service.Create(ctx, sprint)
+}
+
+func (s *SprintService) Update(ctx context.Context, id uuid.UUID, sprint models.Sprint) (models.Sprint, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	if current.State == models.SprintClosed {
+		return models.Sprint{}, krest.NewError(http.StatusConflict, "sprint %s is closed", current.Name)
+	}
+	if sprint.ProjectID != current.ProjectID {
+		return models.Sprint{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "project_id", Rule: "readonly", Message: "sprints can't move to another project"}}}
+	}
+
+	err = validateDates(sprint)
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	sprint.State, sprint.Committed, sprint.Report = current.State, current.Committed, current.Report
+	return s.Service.Update(ctx, id, sprint)
+}
+
+// Start activates a planned sprint, recording the tasks it commits to.
+func (s *SprintService) Start(ctx context.Context, id uuid.UUID) (models.Sprint, error) {
+	var started models.Sprint
+	err := s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		started, err = s.start(ctx, id)
+		return err
+	})
+	return started, err
+}
+
+func (s *SprintService) start(ctx context.Context, id uuid.UUID) (models.Sprint, error) {
+	sprint, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	if sprint.State != models.SprintPlanned {
+		return models.Sprint{}, krest.NewError(http.StatusConflict, "only planned sprints can be started, sprint %s is %s", sprint.Name, sprint.State)
+	}
+
+	active, err := s.Service.List(ctx, krest.CollectionQuery{
+		Limit: 1,
+		Filters: []krest.Filter{
+			{Field: "project_id", Operator: krest.FilterEqual, Value: sprint.ProjectID},
+			{Field: "state", Operator: krest.FilterEqual, Value: models.SprintActive},
+		},
+	})
+	if err != nil {
+		return models.Sprint{}, err
+	}
+	if len(active) > 0 {
+		return models.Sprint{}, krest.NewError(http.StatusConflict, "sprint %s is already active, close it first", active[0].Name)
+	}
+
+	tasks, err := s.sprintTasks(ctx, id)
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	sprint.State, sprint.Committed = models.SprintActive, models.TaskIDs{}
+	for _, task := range tasks {
+		sprint.Committed = append(sprint.Committed, task.UUID)
+	}
+	if sprint.StartDate == nil {
+		now := time.Now().UTC()
+		sprint.StartDate = &now
+	}
+
+	return s.Service.Update(ctx, id, sprint)
+}
+
+// Close closes an active sprint. Incomplete tasks move to the sprint of the request, or the backlog,
+// and the sprint keeps a report of the committed and completed tasks.
+func (s *SprintService) Close(ctx context.Context, id uuid.UUID, request CloseRequest) (models.Sprint, error) {
+	var closed models.Sprint
+	err := s.transactions.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		closed, err = s.close(ctx, id, request)
+		return err
+	})
+	return closed, err
+}
+
+func (s *SprintService) close(ctx context.Context, id uuid.UUID, request CloseRequest) (models.Sprint, error) {
+	sprint, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	if sprint.State != models.SprintActive {
+		return models.Sprint{}, krest.NewError(http.StatusConflict, "only active sprints can be closed, sprint %s is %s", sprint.Name, sprint.State)
+	}
+
+	// Incomplete tasks can only move to an open sprint of the project.
+	if request.MoveTo != nil {
+		next, err := s.Service.Get(ctx, *request.MoveTo, krest.ResourceQuery{})
+		if krest.ErrorStatus(err) == http.StatusNotFound {
+			return models.Sprint{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "move_to", Rule: "ref", Message: fmt.Sprintf("references a nonexistent resource: %s", *request.MoveTo)}}}
+		}
+		if err != nil {
+			return models.Sprint{}, err
+		}
+		if next.UUID == sprint.UUID || next.ProjectID != sprint.ProjectID || next.State == models.SprintClosed {
+			return models.Sprint{}, &krest.ValidationError{Errors: []krest.FieldError{{Field: "move_to", Rule: "sprint", Message: "must be another open sprint of the project"}}}
+		}
+	}
+
+	tasks, err := s.sprintTasks(ctx, id)
+	if err != nil {
+		return models.Sprint{}, err
+	}
+
+	report := &models.SprintReport{
+		ClosedAt:          time.Now().UTC(),
+		CommittedTaskIDs:  sprint.Committed,
+		CompletedTaskIDs:  models.TaskIDs{},
+		IncompleteTaskIDs: models.TaskIDs{},
+		MovedToSprintID:   request.MoveTo,
+	}
+	if report.CommittedTaskIDs == nil {
+		report.CommittedTaskIDs = models.TaskIDs{}
+	}
+
+	for _, task := range tasks {
+		if task.CompletedAt != nil {
+			report.CompletedTaskIDs = append(report.CompletedTaskIDs, task.UUID)
+			continue
+		}
+
+		report.IncompleteTaskIDs = append(report.IncompleteTaskIDs, task.UUID)
+		err = s.moveTask(ctx, task.UUID, request.MoveTo)
+		if err != nil {
+			return models.Sprint{}, err
+		}
+	}
+
+	sprint.State, sprint.Report = models.SprintClosed, report
+	if sprint.EndDate == nil {
+		sprint.EndDate = &report.ClosedAt
+	}
+
+	return s.Service.Update(ctx, id, sprint)
+}
+
+// Returns the tasks of a sprint, with the time they were completed.
+func (s *SprintService) sprintTasks(ctx context.Context, id uuid.UUID) ([]models.Task, error) {
+	return s.tasks.List(ctx, krest.CollectionQuery{
+		Filters: []krest.Filter{{Field: "sprint_id", Operator: krest.FilterEqual, Value: id}},
+		Expand:  []string{"completed_at"},
+	})
+}
+
+// Moves a task to another sprint, or the backlog.
+func (s *SprintService) moveTask(ctx context.Context, id uuid.UUID, sprintID *uuid.UUID) error {
+	// Get the whole task, it's updated as a whole.
+	expand, err := krest.ExpandableFieldNames[models.Task]()
+	if err != nil {
+		return err
+	}
+	task, err := s.tasks.Get(ctx, id, krest.ResourceQuery{Expand: expand})
+	if err != nil {
+		return err
+	}
+
+	task.SprintID = sprintID
+	_, err = s.tasks.Update(ctx, id, task)
+	return err
+}
+
+// Sprints can't end before they start.
+func validateDates(sprint models.Sprint) error {
+	if sprint.StartDate != nil && sprint.EndDate != nil && sprint.EndDate.Before(*sprint.StartDate) {
+		return &krest.ValidationError{Errors: []krest.FieldError{{Field: "end_date", Rule: "after", Message: "must be after the start date"}}}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package sprint_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/sprint"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSprints(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	sprintRepository := krest_orm.NewGenericPostgresRepository[models.Sprint](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	tasks := sprint.NewTaskService(krest_orm.NewGenericService(taskRepository), sprintRepository)
+	sprints := sprint.NewSprintService(krest_orm.NewGenericService(sprintRepository), tasks, sprintRepository)
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other, err := projectRepository.Create(ctx, models.Project{Name: "Web", Key: "WEB"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, -1)
+	_, err = sprints.Create(ctx, models.Sprint{ProjectID: project.UUID, Name: "Backwards", StartDate: &start, EndDate: &end})
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for a sprint ending before it starts, got %v", err)
+	}
+
+	first, err := sprints.Create(ctx, models.Sprint{ProjectID: project.UUID, Name: "Sprint 1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if first.State != models.SprintPlanned {
+		t.Errorf("expected a planned sprint, got %s", first.State)
+	}
+	second, err := sprints.Create(ctx, models.Sprint{ProjectID: project.UUID, Name: "Sprint 2"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	foreign, err := sprints.Create(ctx, models.Sprint{ProjectID: other.UUID, Name: "Web 1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Tasks can only be planned into sprints of their project.
+	_, err = tasks.Create(ctx, models.Task{Summary: "Misplaced", ProjectID: project.UUID, SprintID: &foreign.UUID})
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for a sprint of another project, got %v", err)
+	}
+
+	now := time.Now().UTC()
+	done, err := tasks.Create(ctx, models.Task{Summary: "Done", ProjectID: project.UUID, SprintID: &first.UUID, CompletedAt: &now})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	open, err := tasks.Create(ctx, models.Task{Summary: "Open", ProjectID: project.UUID, SprintID: &first.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	_, err = sprints.Close(ctx, first.UUID, sprint.CloseRequest{})
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for closing a planned sprint, got %v", err)
+	}
+
+	first, err = sprints.Start(ctx, first.UUID)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if first.State != models.SprintActive || len(first.Committed) != 2 {
+		t.Errorf("expected an active sprint committed to 2 tasks, got %s with %v", first.State, first.Committed)
+	}
+
+	// A project has one active sprint at a time.
+	_, err = sprints.Start(ctx, second.UUID)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for starting a second sprint, got %v", err)
+	}
+
+	// Tasks added after the start aren't committed.
+	late, err := tasks.Create(ctx, models.Task{Summary: "Late", ProjectID: project.UUID, SprintID: &first.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Incomplete tasks move to the next sprint.
+	first, err = sprints.Close(ctx, first.UUID, sprint.CloseRequest{MoveTo: &second.UUID})
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if first.State != models.SprintClosed || first.Report == nil {
+		t.Fatalf("expected a closed sprint with a report, got %s", first.State)
+	}
+	if len(first.Report.CommittedTaskIDs) != 2 || len(first.Report.CompletedTaskIDs) != 1 || first.Report.CompletedTaskIDs[0] != done.UUID {
+		t.Errorf("expected 2 committed tasks and 1 completed task, got %+v", first.Report)
+	}
+	if len(first.Report.IncompleteTaskIDs) != 2 {
+		t.Errorf("expected 2 incomplete tasks, got %v", first.Report.IncompleteTaskIDs)
+	}
+
+	for _, id := range []uuid.UUID{open.UUID, late.UUID} {
+		task, err := tasks.Get(ctx, id, krest.ResourceQuery{Expand: []string{"sprint_id"}})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if task.SprintID == nil || *task.SprintID != second.UUID {
+			t.Errorf("expected task %s in the next sprint, got %v", id, task.SprintID)
+		}
+	}
+
+	// Closed sprints can't be changed, or planned into.
+	first.Name = "Renamed"
+	_, err = sprints.Update(ctx, first.UUID, first)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for updating a closed sprint, got %v", err)
+	}
+	_, err = tasks.Create(ctx, models.Task{Summary: "Too late", ProjectID: project.UUID, SprintID: &first.UUID})
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for planning into a closed sprint, got %v", err)
+	}
+
+	// Without a next sprint, incomplete tasks move to the backlog.
+	_, err = sprints.Start(ctx, second.UUID)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	_, err = sprints.Close(ctx, second.UUID, sprint.CloseRequest{MoveTo: &first.UUID})
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for moving tasks to a closed sprint, got %v", err)
+	}
+	second, err = sprints.Close(ctx, second.UUID, sprint.CloseRequest{})
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	task, err := tasks.Get(ctx, open.UUID, krest.ResourceQuery{Expand: []string{"sprint_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if task.SprintID != nil {
+		t.Errorf("expected the task in the backlog, got sprint %v", task.SprintID)
+	}
+	if second.Report.MovedToSprintID != nil {
+		t.Errorf("expected no next sprint in the report, got %v", second.Report.MovedToSprintID)
+	}
+}
@@ -0,0 +1,80 @@
+package sprint
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, planning tasks into open sprints of their project.
+// Tasks moving to another project leave their sprint.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+	sprints krest.Repository[models.Sprint]
+}
+
+func NewTaskService(service krest.Service[models.Task], sprints krest.Repository[models.Sprint]) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, sprints: sprints}
+}
+
+func (s *TaskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	err := s.validateSprint(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+func (s *TaskService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"project_id", "sprint_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Tasks moving to another project leave the sprint, unless they are planned into a sprint of the new project.
+	if current.ProjectID != task.ProjectID && sameSprint(current.SprintID, task.SprintID) {
+		task.SprintID = nil
+	}
+
+	if !sameSprint(current.SprintID, task.SprintID) {
+		err = s.validateSprint(ctx, task)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return s.Service.Update(ctx, id, task)
+}
+
+// Returns an error if the sprint of a task isn't an open sprint of the project of the task.
+func (s *TaskService) validateSprint(ctx context.Context, task models.Task) error {
+	if task.SprintID == nil {
+		return nil
+	}
+
+	sprint, err := s.sprints.Get(ctx, *task.SprintID, krest.ResourceQuery{})
+	if err != nil {
+		return err
+	}
+
+	if sprint.ProjectID != task.ProjectID {
+		return &krest.ValidationError{Errors: []krest.FieldError{{Field: "sprint_id", Rule: "project", Message: "must be a sprint of the project of the task"}}}
+	}
+	if sprint.State == models.SprintClosed {
+		return krest.NewError(http.StatusConflict, "sprint %s is closed", sprint.Name)
+	}
+
+	return nil
+}
+
+func sameSprint(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,56 @@
+package taskkey
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+)
+
+// KeyHandler gets tasks by key as well as by uuid. [GET /v1/tasks/OMNI-42]
+// Old keys redirect to the current key of the task.
+type KeyHandler struct {
+	service *KeyService
+	get     http.HandlerFunc
+}
+
+// NewKeyHandler returns a handler resolving keys for get, the handler getting tasks by uuid.
+func NewKeyHandler(service *KeyService, get http.HandlerFunc) *KeyHandler {
+	return &KeyHandler{service: service, get: get}
+}
+
+func (h *KeyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	// Tasks requested by uuid don't need resolving.
+	param := chi.URLParam(r, "uuid")
+	if _, err := uuid.Parse(param); err == nil {
+		h.get(w, r)
+		return
+	}
+
+	id, key, err := h.service.Resolve(r.Context(), param)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Old keys redirect to the current key, temporarily as the key may later resolve to another task.
+	if key != param {
+		location := path.Join(path.Dir(r.URL.Path), key)
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+		return
+	}
+
+	// Get the task by uuid.
+	routeContext := chi.RouteContext(r.Context())
+	for i, name := range routeContext.URLParams.Keys {
+		if name == "uuid" {
+			routeContext.URLParams.Values[i] = id.String()
+		}
+	}
+	h.get(w, r)
+}
@@ -0,0 +1,109 @@
+package taskkey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+)
+
+// KeyRepository stores the task number sequences of projects, and the keys tasks used to have.
+// Old keys are kept when a task moves to another project or its project's key changes, so they keep resolving.
+type KeyRepository struct {
+	db *sql.DB
+}
+
+// NewKeyRepository creates the tables of the repository, after the projects and tasks tables.
+func NewKeyRepository(db *sql.DB) *KeyRepository {
+	queries := []string{
+		"CREATE TABLE IF NOT EXISTS task_sequences (project_id UUID PRIMARY KEY REFERENCES projects(uuid) ON DELETE CASCADE, last_number INTEGER NOT NULL);",
+		"CREATE TABLE IF NOT EXISTS task_keys (organization_id UUID NOT NULL REFERENCES organizations(uuid) ON DELETE CASCADE, key TEXT NOT NULL, task_id UUID NOT NULL REFERENCES tasks(uuid) ON DELETE CASCADE, PRIMARY KEY (organization_id, key));",
+		// Numbers and keys are unique, tasks not numbered yet have neither.
+		"CREATE UNIQUE INDEX IF NOT EXISTS tasks_project_number ON tasks (project_id, number) WHERE number > 0;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS tasks_organization_key ON tasks (organization_id, key) WHERE key <> '';",
+	}
+	for _, query := range queries {
+		log.Printf("creating table: %s", query)
+		_, err := db.Exec(query)
+		if err != nil {
+			log.Fatalf("failed to create table: %v", err)
+		}
+	}
+
+	return &KeyRepository{db: db}
+}
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *KeyRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// NextNumber returns the next task number of a project. Concurrent calls get different numbers,
+// the database locks the row of the sequence while incrementing it.
+func (r *KeyRepository) NextNumber(ctx context.Context, projectID uuid.UUID) (int, error) {
+	var number int
+	err := krest_orm.Conn(ctx, r.db).QueryRowContext(ctx,
+		"INSERT INTO task_sequences (project_id, last_number) VALUES ($1, 1) ON CONFLICT (project_id) DO UPDATE SET last_number = task_sequences.last_number + 1 RETURNING last_number",
+		projectID,
+	).Scan(&number)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the next task number: %v", err)
+	}
+	return number, nil
+}
+
+// KeepKey keeps an old key of a task, replacing the task it used to resolve to.
+func (r *KeyRepository) KeepKey(ctx context.Context, key string, taskID uuid.UUID) error {
+	tenantID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return krest_orm.ErrMissingTenant
+	}
+
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO task_keys (organization_id, key, task_id) VALUES ($1, $2, $3) ON CONFLICT (organization_id, key) DO UPDATE SET task_id = excluded.task_id",
+		tenantID, key, taskID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to keep task key: %v", err)
+	}
+	return nil
+}
+
+// KeepProjectKeys keeps the current keys of the tasks of a project, before the key of the project changes.
+func (r *KeyRepository) KeepProjectKeys(ctx context.Context, projectID uuid.UUID) error {
+	tenantID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return krest_orm.ErrMissingTenant
+	}
+
+	_, err := krest_orm.Conn(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO task_keys (organization_id, key, task_id) SELECT organization_id, key, uuid FROM tasks WHERE organization_id = $1 AND project_id = $2 AND key <> '' ON CONFLICT (organization_id, key) DO UPDATE SET task_id = excluded.task_id",
+		tenantID, projectID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to keep task keys: %v", err)
+	}
+	return nil
+}
+
+// Lookup returns the task an old key resolves to, if any.
+func (r *KeyRepository) Lookup(ctx context.Context, key string) (uuid.UUID, bool, error) {
+	tenantID, ok := krest_orm.TenantFromContext(ctx)
+	if !ok {
+		return uuid.Nil, false, krest_orm.ErrMissingTenant
+	}
+
+	var taskID uuid.UUID
+	err := krest_orm.Conn(ctx, r.db).QueryRowContext(ctx, "SELECT task_id FROM task_keys WHERE organization_id = $1 AND key = $2", tenantID, key).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to look up task key: %v", err)
+	}
+	return taskID, true, nil
+}
@@ -0,0 +1,125 @@
+package taskkey
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Task keys are the key of the project and the number of the task in it, e.g. OMNI-42.
+var keyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*-[0-9]+$`)
+
+// KeyService wraps the task service, numbering tasks within their project.
+// Tasks moving to another project get the next number of that project, their old key keeps resolving.
+// Implements krest.Service[models.Task]
+type KeyService struct {
+	krest.ServiceWrapper[models.Task]
+	keys     *KeyRepository
+	projects krest.Repository[models.Project]
+	policy   *authz.Policy
+}
+
+func NewKeyService(service krest.Service[models.Task], keys *KeyRepository, projects krest.Repository[models.Project], policy *authz.Policy) *KeyService {
+	return &KeyService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, keys: keys, projects: projects, policy: policy}
+}
+
+func (s *KeyService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	err := s.number(ctx, &task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+func (s *KeyService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"project_id"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task.Number, task.Key = current.Number, current.Key
+	if current.ProjectID == task.ProjectID {
+		return s.Service.Update(ctx, id, task)
+	}
+
+	// The task moves to another project, keep its old key.
+	err = s.number(ctx, &task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	updated, err := s.Service.Update(ctx, id, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if current.Key != "" {
+		err = s.keys.KeepKey(ctx, current.Key, id)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return updated, nil
+}
+
+// Resolve returns the task a key refers to, and the current key of the task.
+// The current key differs from the given key if the task moved, or its project's key changed.
+func (s *KeyService) Resolve(ctx context.Context, key string) (uuid.UUID, string, error) {
+	if !keyPattern.MatchString(key) {
+		return uuid.Nil, "", krest.NewError(http.StatusBadRequest, "invalid task key: %s", key)
+	}
+
+	tasks, err := s.Service.List(ctx, krest.CollectionQuery{
+		Limit:   1,
+		Filters: []krest.Filter{{Field: "key", Operator: krest.FilterEqual, Value: key}},
+	})
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if len(tasks) > 0 {
+		return tasks[0].UUID, tasks[0].Key, nil
+	}
+
+	id, ok, err := s.keys.Lookup(ctx, key)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if !ok {
+		return uuid.Nil, "", krest.NewError(http.StatusNotFound, "task %s not found", key)
+	}
+
+	task, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return task.UUID, task.Key, nil
+}
+
+// Assigns the next number of the project of the task, if the authenticated user can add tasks to the project.
+func (s *KeyService) number(ctx context.Context, task *models.Task) error {
+	err := s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "projects %s not found", task.ProjectID))
+	if err != nil {
+		return err
+	}
+
+	project, err := s.projects.Get(ctx, task.ProjectID, krest.ResourceQuery{})
+	if err != nil {
+		return err
+	}
+
+	task.Number, err = s.keys.NextNumber(ctx, project.UUID)
+	if err != nil {
+		return err
+	}
+	task.Key = fmt.Sprintf("%s-%d", project.Key, task.Number)
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package taskkey_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/taskkey"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestTaskKeys(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	krest_orm.NewGenericPostgresRepository[models.Status](db)
+	krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	policy := authz.NewPolicy(membershipRepository)
+	keys := taskkey.NewKeyRepository(db)
+	tasks := taskkey.NewKeyService(krest_orm.NewGenericService(taskRepository), keys, projectRepository, policy)
+	projects := taskkey.NewProjectService(krest_orm.NewGenericService(projectRepository), keys, krest_orm.NewGenericService(taskRepository))
+
+	// Alice is a member of both projects, Bob of neither.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	alice, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	bobUser, err := userRepository.Create(ctx, models.User{Name: "Bob", Username: "bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	omni, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	web, err := projectRepository.Create(ctx, models.Project{Name: "Web", Key: "WEB"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for _, project := range []models.Project{omni, web} {
+		err = policy.AddMember(ctx, project.UUID, alice.UUID, authz.RoleMember)
+		if err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+	}
+	bob := auth.WithUser(ctx, bobUser)
+	ctx = auth.WithUser(ctx, alice)
+
+	// Tasks are only numbered in projects the user can add tasks to.
+	_, err = tasks.Create(bob, models.Task{Summary: "Task", ProjectID: omni.UUID})
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a project of others, got %v", err)
+	}
+
+	// Tasks created concurrently get different numbers.
+	var wg sync.WaitGroup
+	created := make([]models.Task, 10)
+	for i := range created {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			task, err := tasks.Create(ctx, models.Task{Summary: "Task", ProjectID: omni.UUID})
+			if err != nil {
+				t.Errorf("Create failed: %v", err)
+			}
+			created[i] = task
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for _, task := range created {
+		if seen[task.Key] {
+			t.Errorf("duplicate key: %s", task.Key)
+		}
+		seen[task.Key] = true
+	}
+	for i := 1; i <= 10; i++ {
+		if !seen[fmt.Sprintf("OMNI-%d", i)] {
+			t.Errorf("missing key OMNI-%d, got %v", i, seen)
+		}
+	}
+
+	id, key, err := tasks.Resolve(ctx, "OMNI-3")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if key != "OMNI-3" {
+		t.Errorf("expected OMNI-3 to be current, got %s", key)
+	}
+
+	// Moved tasks are renumbered, the old key resolves to the new one.
+	task, err := tasks.Get(ctx, id, krest.ResourceQuery{Expand: []string{"summary", "project_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	task.ProjectID = web.UUID
+	task, err = tasks.Update(ctx, id, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if task.Key != "WEB-1" {
+		t.Errorf("expected the moved task to be WEB-1, got %s", task.Key)
+	}
+
+	_, key, err = tasks.Resolve(ctx, "OMNI-3")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if key != "WEB-1" {
+		t.Errorf("expected OMNI-3 to resolve to WEB-1, got %s", key)
+	}
+
+	// Renamed projects rename the keys of their tasks, old keys keep resolving.
+	web.Key = "SITE"
+	_, err = projects.Update(ctx, web.UUID, web)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	revisions, err := taskRepository.ListRevisions(ctx, id, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 3 || revisions[2].Changes["key"].After != "SITE-1" {
+		t.Errorf("expected a revision of the renamed task, got %+v", revisions)
+	}
+
+	// Numbers and keys are unique.
+	duplicate, err := tasks.Get(ctx, id, krest.ResourceQuery{Expand: []string{"summary", "project_id"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	duplicate.UUID = uuid.Nil
+	_, err = taskRepository.Create(ctx, duplicate)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate key, got %v", err)
+	}
+
+	for _, old := range []string{"OMNI-3", "WEB-1"} {
+		_, key, err = tasks.Resolve(ctx, old)
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if key != "SITE-1" {
+			t.Errorf("expected %s to resolve to SITE-1, got %s", old, key)
+		}
+	}
+
+	_, _, err = tasks.Resolve(ctx, "OMNI-99")
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown key, got %v", err)
+	}
+
+	// Keys are scoped to the organization.
+	_, _, err = tasks.Resolve(krest_orm.WithTenant(context.Background(), uuid.New()), "OMNI-1")
+	if krest.ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a key of another organization, got %v", err)
+	}
+}
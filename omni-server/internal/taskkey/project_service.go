@@ -0,0 +1,81 @@
+package taskkey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// ProjectService wraps the project service, renaming the keys of the tasks of a project when its key changes.
+// Tasks are renamed through the task service in the transaction of the project update, so the renames are audited.
+// Implements krest.Service[models.Project]
+type ProjectService struct {
+	krest.ServiceWrapper[models.Project]
+	keys  *KeyRepository
+	tasks krest.Service[models.Task]
+}
+
+// The task service renames tasks without authorizing, the project service must authorize updating the project.
+func NewProjectService(service krest.Service[models.Project], keys *KeyRepository, tasks krest.Service[models.Task]) *ProjectService {
+	return &ProjectService{ServiceWrapper: krest.ServiceWrapper[models.Project]{Service: service}, keys: keys, tasks: tasks}
+}
+
+func (s *ProjectService) Update(ctx context.Context, id uuid.UUID, project models.Project) (models.Project, error) {
+	var updated models.Project
+	err := s.keys.Transaction(ctx, func(ctx context.Context) error {
+		current, err := s.Service.Get(ctx, id, krest.ResourceQuery{})
+		if err != nil {
+			return err
+		}
+
+		updated, err = s.Service.Update(ctx, id, project)
+		if err != nil {
+			return err
+		}
+
+		if updated.Key == current.Key {
+			return nil
+		}
+		return s.renameTasks(ctx, id, updated.Key)
+	})
+	if err != nil {
+		return models.Project{}, err
+	}
+
+	return updated, nil
+}
+
+// Keeps the current keys of the tasks of a project, and renumbers them with the new key of the project.
+func (s *ProjectService) renameTasks(ctx context.Context, projectID uuid.UUID, key string) error {
+	err := s.keys.KeepProjectKeys(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	expand, err := krest.ExpandableFieldNames[models.Task]()
+	if err != nil {
+		return err
+	}
+	tasks, err := s.tasks.List(ctx, krest.CollectionQuery{
+		Expand:  expand,
+		Filters: []krest.Filter{{Field: "project_id", Operator: krest.FilterEqual, Value: projectID}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if task.Number == 0 {
+			continue
+		}
+		task.Key = fmt.Sprintf("%s-%d", key, task.Number)
+		_, err = s.tasks.Update(ctx, task.UUID, task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,12 +2,19 @@ package user
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
 	"github.com/khaossystems/omni-server/internal/pkg/krest"
 	"github.com/khaossystems/omni-server/pkg/models"
 )
 
+var (
+	ErrNotSelf  = krest.NewError(http.StatusForbidden, "only the user themselves or an organization admin can change a user")
+	ErrNotAdmin = krest.NewError(http.StatusForbidden, "only an organization admin can grant the admin role")
+)
+
 // Implements krest.Service[models.User]
 type UserService struct {
 	repository UserRepository
@@ -25,14 +32,83 @@ func (s *UserService) List(ctx context.Context, query krest.CollectionQuery) ([]
 	return s.repository.List(ctx, query)
 }
 
+// Passwords are never stored in plaintext, they're hashed before being stored.
+// Users are created by other users of the organization, only the first user of an organization can sign up, and becomes its admin.
 func (s *UserService) Create(ctx context.Context, user models.User) (models.User, error) {
+	if current, ok := auth.UserFromContext(ctx); ok {
+		if user.Role == "" {
+			user.Role = models.UserRoleMember
+		}
+		if user.Role == models.UserRoleAdmin && current.Role != models.UserRoleAdmin {
+			return models.User{}, ErrNotAdmin
+		}
+	} else {
+		users, err := s.repository.List(ctx, krest.CollectionQuery{Limit: 1})
+		if err != nil {
+			return models.User{}, err
+		}
+		if len(users) > 0 {
+			return models.User{}, auth.ErrUnauthenticated
+		}
+		user.Role = models.UserRoleAdmin
+	}
+
+	hash, err := auth.HashPassword(user.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Password = hash
+
 	return s.repository.Create(ctx, user)
 }
 
+// Users can only be changed by themselves or an admin, and only admins can change roles.
+// The password is only hashed if it changed, as unchanged passwords are already hashed.
 func (s *UserService) Update(ctx context.Context, id uuid.UUID, user models.User) (models.User, error) {
+	current, err := s.authorize(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	existing, err := s.repository.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user.Role == "" {
+		user.Role = existing.Role
+	}
+	if user.Role != existing.Role && current.Role != models.UserRoleAdmin {
+		return models.User{}, ErrNotAdmin
+	}
+
+	if user.Password != existing.Password {
+		hash, err := auth.HashPassword(user.Password)
+		if err != nil {
+			return models.User{}, err
+		}
+		user.Password = hash
+	}
+
 	return s.repository.Update(ctx, id, user)
 }
 
 func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.authorize(ctx, id); err != nil {
+		return err
+	}
+
 	return s.repository.Delete(ctx, id)
 }
+
+// Returns the current user if they may change the user with the given ID, being that user or an admin.
+func (s *UserService) authorize(ctx context.Context, id uuid.UUID) (models.User, error) {
+	current, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.User{}, auth.ErrUnauthenticated
+	}
+	if current.UUID != id && current.Role != models.UserRoleAdmin {
+		return models.User{}, ErrNotSelf
+	}
+	return current, nil
+}
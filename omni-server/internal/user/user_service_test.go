@@ -0,0 +1,90 @@
+package user_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/user"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestUsers(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	service := user.NewUserService(krest_orm.NewGenericPostgresRepository[models.User](db))
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	// The first user signs up and becomes the admin, the others are created by users of the organization.
+	admin, err := service.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com", Password: "correct horse battery staple"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if admin.Role != models.UserRoleAdmin {
+		t.Errorf("expected the first user to be an admin, got %q", admin.Role)
+	}
+	_, err = service.Create(ctx, models.User{Name: "Mallory", Username: "mallory", Email: "mallory@example.com", Password: "correct horse battery staple"})
+	if krest.ErrorStatus(err) != http.StatusUnauthorized {
+		t.Errorf("expected 401 for signing up to an organization with users, got %v", err)
+	}
+
+	adminCtx := auth.WithUser(ctx, admin)
+	bob, err := service.Create(adminCtx, models.User{Name: "Bob", Username: "bob", Email: "bob@example.com", Password: "correct horse battery staple"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	carol, err := service.Create(adminCtx, models.User{Name: "Carol", Username: "carol", Email: "carol@example.com", Password: "correct horse battery staple"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if bob.Role != models.UserRoleMember {
+		t.Errorf("expected users to be members, got %q", bob.Role)
+	}
+	bobCtx := auth.WithUser(ctx, bob)
+
+	_, err = service.Update(ctx, bob.UUID, models.User{UUID: bob.UUID, Name: "Bobby", Username: bob.Username, Email: bob.Email, Password: bob.Password})
+	if krest.ErrorStatus(err) != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an anonymous update, got %v", err)
+	}
+	_, err = service.Update(bobCtx, carol.UUID, models.User{UUID: carol.UUID, Name: "Carol", Username: carol.Username, Email: "bob@evil.example", Password: carol.Password})
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for updating another user, got %v", err)
+	}
+	err = service.Delete(bobCtx, carol.UUID)
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for deleting another user, got %v", err)
+	}
+
+	// Users can change themselves, but not their role.
+	_, err = service.Update(bobCtx, bob.UUID, models.User{UUID: bob.UUID, Name: "Bob", Username: bob.Username, Email: bob.Email, Password: bob.Password, Role: models.UserRoleAdmin})
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for granting the admin role, got %v", err)
+	}
+	bob, err = service.Update(bobCtx, bob.UUID, models.User{UUID: bob.UUID, Name: "Bobby", Username: bob.Username, Email: bob.Email, Password: bob.Password})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if bob.Name != "Bobby" || bob.Role != models.UserRoleMember {
+		t.Errorf("Update returned incorrect data: got %+v", bob)
+	}
+
+	// Admins can change and delete other users.
+	_, err = service.Update(adminCtx, carol.UUID, models.User{UUID: carol.UUID, Name: "Carol", Username: carol.Username, Email: carol.Email, Password: carol.Password, Role: models.UserRoleAdmin})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	err = service.Delete(adminCtx, bob.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// Guard is a condition a task must meet to take a transition.
+// Returns a message describing what's missing, or an empty string if the task meets the condition.
+type Guard func(ctx context.Context, task models.Task) (string, error)
+
+// Guards are the conditions transitions can require, by name.
+type Guards map[string]Guard
+
+// DefaultGuards returns the guards on the fields of tasks.
+// Add guards that depend on other packages to the result, before passing it to the services.
+func DefaultGuards() Guards {
+	return Guards{
+		"assignee_required": func(ctx context.Context, task models.Task) (string, error) {
+			if task.AssigneeID == nil {
+				return "requires an assignee", nil
+			}
+			return "", nil
+		},
+		"description_required": func(ctx context.Context, task models.Task) (string, error) {
+			if task.Description == "" {
+				return "requires a description", nil
+			}
+			return "", nil
+		},
+	}
+}
+
+// Returns an error if a guard doesn't exist.
+func (g Guards) validate(name string) error {
+	if _, ok := g[name]; !ok {
+		return fmt.Errorf("unknown guard: %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package workflow
+
+import (
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// StatusRelation loads the status of tasks, when expanded with ?expand=status.
+func StatusRelation(statuses krest.Repository[models.Status]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("status", "status_id", statuses,
+		func(task models.Task) *uuid.UUID { return task.StatusID },
+		func(task *models.Task, status *models.Status) { task.Status = status },
+	)
+}
+
+// TypeRelation loads the type of tasks, when expanded with ?expand=type.
+func TypeRelation(types krest.Repository[models.TaskType]) krest_orm.Relation[models.Task] {
+	return krest_orm.BelongsTo("type", "type_id", types,
+		func(task models.Task) *uuid.UUID { return task.TypeID },
+		func(task *models.Task, taskType *models.TaskType) { task.Type = taskType },
+	)
+}
@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskTypeService wraps the task type service, validating workflows.
+// The statuses of a workflow must belong to the project of the task type, and guards must exist.
+// Implements krest.Service[models.TaskType]
+type TaskTypeService struct {
+	krest.ServiceWrapper[models.TaskType]
+	statuses krest.Repository[models.Status]
+	guards   Guards
+}
+
+func NewTaskTypeService(service krest.Service[models.TaskType], statuses krest.Repository[models.Status], guards Guards) *TaskTypeService {
+	return &TaskTypeService{ServiceWrapper: krest.ServiceWrapper[models.TaskType]{Service: service}, statuses: statuses, guards: guards}
+}
+
+func (s *TaskTypeService) Create(ctx context.Context, taskType models.TaskType) (models.TaskType, error) {
+	err := s.validate(ctx, taskType)
+	if err != nil {
+		return models.TaskType{}, err
+	}
+
+	return s.Service.Create(ctx, taskType)
+}
+
+func (s *TaskTypeService) Update(ctx context.Context, id uuid.UUID, taskType models.TaskType) (models.TaskType, error) {
+	err := s.validate(ctx, taskType)
+	if err != nil {
+		return models.TaskType{}, err
+	}
+
+	return s.Service.Update(ctx, id, taskType)
+}
+
+// Returns a validation error for statuses of other projects, and unknown guards.
+func (s *TaskTypeService) validate(ctx context.Context, taskType models.TaskType) error {
+	fieldErrors := []krest.FieldError{}
+
+	checkStatus := func(field string, id *uuid.UUID) error {
+		if id == nil {
+			return nil
+		}
+
+		status, err := s.statuses.Get(ctx, *id, krest.ResourceQuery{})
+		if krest.ErrorStatus(err) == http.StatusNotFound || (err == nil && status.ProjectID != taskType.ProjectID) {
+			fieldErrors = append(fieldErrors, krest.FieldError{Field: field, Rule: "project", Message: "must be a status of the project of the task type"})
+			return nil
+		}
+		return err
+	}
+
+	err := checkStatus("initial_status_id", taskType.InitialStatusID)
+	if err != nil {
+		return err
+	}
+
+	for i, transition := range taskType.Transitions {
+		field := fmt.Sprintf("transitions[%d]", i)
+		err = checkStatus(field+".from_status_id", transition.FromStatusID)
+		if err != nil {
+			return err
+		}
+		toStatusID := transition.ToStatusID
+		err = checkStatus(field+".to_status_id", &toStatusID)
+		if err != nil {
+			return err
+		}
+
+		for _, guard := range transition.Guards {
+			if err := s.guards.validate(guard); err != nil {
+				fieldErrors = append(fieldErrors, krest.FieldError{Field: field + ".guards", Rule: "guard", Message: err.Error()})
+			}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &krest.ValidationError{Errors: fieldErrors}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// WorkflowService wraps the task service, enforcing the workflow of the task type on status changes.
+// Illegal status changes are rejected with a 409, and the time of each status change is recorded on the task.
+// Implements krest.Service[models.Task]
+type WorkflowService struct {
+	krest.ServiceWrapper[models.Task]
+	statuses krest.Repository[models.Status]
+	types    krest.Repository[models.TaskType]
+	guards   Guards
+}
+
+// The guards must include those of the task types, see TaskTypeService.
+func NewWorkflowService(service krest.Service[models.Task], statuses krest.Repository[models.Status], types krest.Repository[models.TaskType], guards Guards) *WorkflowService {
+	return &WorkflowService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}, statuses: statuses, types: types, guards: guards}
+}
+
+// New tasks start in the initial status of their type.
+func (s *WorkflowService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	taskType, err := s.taskType(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if taskType != nil && taskType.InitialStatusID != nil {
+		if task.StatusID == nil {
+			task.StatusID = taskType.InitialStatusID
+		} else if *task.StatusID != *taskType.InitialStatusID && len(taskType.Transitions) > 0 {
+			return models.Task{}, krest.NewError(http.StatusConflict, "tasks of type %q start in the initial status of the type", taskType.Name)
+		}
+	}
+
+	status, err := s.status(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if status != nil {
+		recordTransition(&task, nil, status)
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+// Status changes must be allowed by a transition of the task type, and meet its guards.
+func (s *WorkflowService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	current, err := s.Service.Get(ctx, id, krest.ResourceQuery{Expand: []string{"project_id", "status_id", "type_id", "started_at", "completed_at", "status_changed_at"}})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// The workflow manages the timestamps.
+	task.StatusChangedAt, task.StartedAt, task.CompletedAt = current.StatusChangedAt, current.StartedAt, current.CompletedAt
+
+	taskType, err := s.taskType(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Tasks moving to a type without a status start in its initial status.
+	if task.StatusID == nil && taskType != nil {
+		task.StatusID = taskType.InitialStatusID
+	}
+
+	if sameID(current.StatusID, task.StatusID) {
+		// Tasks moving to another project or type keep their status, if it belongs to the project.
+		if current.ProjectID != task.ProjectID || !sameID(current.TypeID, task.TypeID) {
+			_, err = s.status(ctx, task)
+			if err != nil {
+				return models.Task{}, err
+			}
+		}
+		return s.Service.Update(ctx, id, task)
+	}
+
+	status, err := s.status(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	from, err := s.status(ctx, current)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if taskType != nil && len(taskType.Transitions) > 0 {
+		err = s.authorizeTransition(ctx, taskType, task, from, status)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	recordTransition(&task, from, status)
+	return s.Service.Update(ctx, id, task)
+}
+
+// Returns an error if the task type doesn't allow the status change, or the task doesn't meet its guards.
+func (s *WorkflowService) authorizeTransition(ctx context.Context, taskType *models.TaskType, task models.Task, from *models.Status, to *models.Status) error {
+	fromName, toName := statusName(from), statusName(to)
+
+	allowed := []string{}
+	for _, transition := range taskType.Transitions {
+		if transition.FromStatusID != nil && (from == nil || *transition.FromStatusID != from.UUID) {
+			continue
+		}
+
+		if to == nil || transition.ToStatusID != to.UUID {
+			name, err := s.statusNameByID(ctx, transition.ToStatusID)
+			if err != nil {
+				return err
+			}
+			allowed = append(allowed, fmt.Sprintf("%q", name))
+			continue
+		}
+
+		// The transition is allowed, if the task meets its guards.
+		for _, name := range transition.Guards {
+			guard, ok := s.guards[name]
+			if !ok {
+				return fmt.Errorf("unknown guard: %s", name)
+			}
+			message, err := guard(ctx, task)
+			if err != nil {
+				return err
+			}
+			if message != "" {
+				return krest.NewError(http.StatusConflict, "moving a %s from %q to %q %s", taskType.Name, fromName, toName, message)
+			}
+		}
+		return nil
+	}
+
+	if len(allowed) == 0 {
+		return krest.NewError(http.StatusConflict, "a %s can't be moved from %q", taskType.Name, fromName)
+	}
+	return krest.NewError(http.StatusConflict, "a %s can't be moved from %q to %q, it can be moved to %s", taskType.Name, fromName, toName, strings.Join(allowed, ", "))
+}
+
+// Returns the type of a task, nil for tasks without a type. The type must belong to the project of the task.
+func (s *WorkflowService) taskType(ctx context.Context, task models.Task) (*models.TaskType, error) {
+	if task.TypeID == nil {
+		return nil, nil
+	}
+
+	taskType, err := s.types.Get(ctx, *task.TypeID, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	if taskType.ProjectID != task.ProjectID {
+		return nil, &krest.ValidationError{Errors: []krest.FieldError{{Field: "type_id", Rule: "project", Message: "must be a type of the project of the task"}}}
+	}
+
+	return &taskType, nil
+}
+
+// Returns the status of a task, nil for tasks without a status. The status must belong to the project of the task.
+func (s *WorkflowService) status(ctx context.Context, task models.Task) (*models.Status, error) {
+	if task.StatusID == nil {
+		return nil, nil
+	}
+
+	status, err := s.statuses.Get(ctx, *task.StatusID, krest.ResourceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	if status.ProjectID != task.ProjectID {
+		return nil, &krest.ValidationError{Errors: []krest.FieldError{{Field: "status_id", Rule: "project", Message: "must be a status of the project of the task"}}}
+	}
+
+	return &status, nil
+}
+
+func (s *WorkflowService) statusNameByID(ctx context.Context, id uuid.UUID) (string, error) {
+	status, err := s.statuses.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return "", err
+	}
+	return status.Name, nil
+}
+
+// Records the time of a status change on the task, and when work started and was completed.
+// Reopened tasks are no longer completed.
+func recordTransition(task *models.Task, from *models.Status, to *models.Status) {
+	now := time.Now().UTC()
+	task.StatusChangedAt = &now
+
+	if to == nil {
+		return
+	}
+
+	if to.Category != models.StatusCategoryTodo && task.StartedAt == nil {
+		task.StartedAt = &now
+	}
+
+	if to.Category == models.StatusCategoryDone {
+		if from == nil || from.Category != models.StatusCategoryDone {
+			task.CompletedAt = &now
+		}
+	} else {
+		task.CompletedAt = nil
+	}
+}
+
+func sameID(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func statusName(status *models.Status) string {
+	if status == nil {
+		return "no status"
+	}
+	return status.Name
+}
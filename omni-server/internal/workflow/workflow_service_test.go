@@ -0,0 +1,134 @@
+package workflow_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/workflow"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestWorkflow(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	statusRepository := krest_orm.NewGenericPostgresRepository[models.Status](db)
+	taskTypeRepository := krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	types := workflow.NewTaskTypeService(krest_orm.NewGenericService(taskTypeRepository), statusRepository, workflow.DefaultGuards())
+	tasks := workflow.NewWorkflowService(krest_orm.NewGenericService(taskRepository), statusRepository, taskTypeRepository, workflow.DefaultGuards())
+
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
+	user, err := userRepository.Create(ctx, models.User{Name: "Alice", Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, err := projectRepository.Create(ctx, models.Project{Name: "Omni", Key: "OMNI"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	statuses := map[string]models.Status{}
+	for name, category := range map[string]string{"To Do": models.StatusCategoryTodo, "In Progress": models.StatusCategoryInProgress, "Done": models.StatusCategoryDone} {
+		created, err := statusRepository.Create(ctx, models.Status{ProjectID: project.UUID, Name: name, Category: category})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		statuses[name] = created
+	}
+	todo, inProgress, done := statuses["To Do"].UUID, statuses["In Progress"].UUID, statuses["Done"].UUID
+
+	// Guards must exist.
+	_, err = types.Create(ctx, models.TaskType{ProjectID: project.UUID, Name: "Bug", Transitions: models.Transitions{{Name: "Start", ToStatusID: inProgress, Guards: []string{"unknown"}}}})
+	var validationError *krest.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Errorf("expected a validation error for an unknown guard, got %v", err)
+	}
+
+	// Work can only be started when assigned, and must be started before it's done.
+	bug, err := types.Create(ctx, models.TaskType{
+		ProjectID:       project.UUID,
+		Name:            "Bug",
+		InitialStatusID: &todo,
+		Transitions: models.Transitions{
+			{Name: "Start", FromStatusID: &todo, ToStatusID: inProgress, Guards: []string{"assignee_required"}},
+			{Name: "Resolve", FromStatusID: &inProgress, ToStatusID: done},
+			{Name: "Reopen", FromStatusID: &done, ToStatusID: todo},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	task, err := tasks.Create(ctx, models.Task{Summary: "Crash", ProjectID: project.UUID, TypeID: &bug.UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if task.StatusID == nil || *task.StatusID != todo {
+		t.Fatalf("expected the task to start in the initial status, got %v", task.StatusID)
+	}
+
+	task.StatusID = &done
+	_, err = tasks.Update(ctx, task.UUID, task)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for a transition the workflow doesn't allow, got %v", err)
+	}
+
+	task.StatusID = &inProgress
+	_, err = tasks.Update(ctx, task.UUID, task)
+	if krest.ErrorStatus(err) != http.StatusConflict {
+		t.Errorf("expected 409 for starting an unassigned task, got %v", err)
+	}
+
+	task.AssigneeID = &user.UUID
+	task, err = tasks.Update(ctx, task.UUID, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if task.StartedAt == nil || task.StatusChangedAt == nil || task.CompletedAt != nil {
+		t.Errorf("expected the task to be started, got %+v", task)
+	}
+
+	task.StatusID = &done
+	task, err = tasks.Update(ctx, task.UUID, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if task.CompletedAt == nil {
+		t.Errorf("expected the task to be completed, got %+v", task)
+	}
+
+	// Reopened tasks are no longer completed, but remain started.
+	task.StatusID = &todo
+	task, err = tasks.Update(ctx, task.UUID, task)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if task.CompletedAt != nil || task.StartedAt == nil {
+		t.Errorf("expected the task to be reopened, got %+v", task)
+	}
+
+	// Tasks can't take their status to another project.
+	other, err := projectRepository.Create(ctx, models.Project{Name: "Web", Key: "WEB"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	task.ProjectID, task.TypeID = other.UUID, nil
+	_, err = tasks.Update(ctx, task.UUID, task)
+	if !errors.As(err, &validationError) || validationError.Errors[0].Field != "status_id" {
+		t.Errorf("expected a validation error for a status of another project, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package worklog
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TaskService wraps the task service, defaulting the remaining estimate of tasks to their original estimate.
+// Implements krest.Service[models.Task]
+type TaskService struct {
+	krest.ServiceWrapper[models.Task]
+}
+
+func NewTaskService(service krest.Service[models.Task]) *TaskService {
+	return &TaskService{ServiceWrapper: krest.ServiceWrapper[models.Task]{Service: service}}
+}
+
+func (s *TaskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	if task.RemainingEstimate == nil && task.OriginalEstimate != nil {
+		remaining := *task.OriginalEstimate
+		task.RemainingEstimate = &remaining
+	}
+
+	return s.Service.Create(ctx, task)
+}
+
+func (s *TaskService) Update(ctx context.Context, id uuid.UUID, task models.Task) (models.Task, error) {
+	if task.RemainingEstimate == nil && task.OriginalEstimate != nil {
+		remaining := *task.OriginalEstimate
+		task.RemainingEstimate = &remaining
+	}
+
+	return s.Service.Update(ctx, id, task)
+}
@@ -0,0 +1,52 @@
+package worklog
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// TimesheetQuery selects the worklogs of a timesheet, started from the start of From until the start of To.
+type TimesheetQuery struct {
+	From      time.Time
+	To        time.Time
+	UserID    *uuid.UUID
+	ProjectID *uuid.UUID
+}
+
+// TimesheetService aggregates worklogs by day, user and project.
+// Only work logged on tasks of projects the authenticated user is a member of is included.
+type TimesheetService struct {
+	repository *WorklogRepository
+	policy     *authz.Policy
+}
+
+func NewTimesheetService(repository *WorklogRepository, policy *authz.Policy) *TimesheetService {
+	return &TimesheetService{repository: repository, policy: policy}
+}
+
+// Timesheet returns the time logged by each user on each project per day, ordered by day, user and project.
+func (s *TimesheetService) Timesheet(ctx context.Context, query TimesheetQuery) ([]models.TimesheetEntry, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	projectIDs, err := s.policy.VisibleProjects(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if query.ProjectID != nil {
+		if !slices.Contains(projectIDs, *query.ProjectID) {
+			return []models.TimesheetEntry{}, nil
+		}
+		projectIDs = []uuid.UUID{*query.ProjectID}
+	}
+
+	return s.repository.Timesheet(ctx, query, projectIDs)
+}
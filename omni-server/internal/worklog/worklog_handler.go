@@ -0,0 +1,290 @@
+package worklog
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// WorklogHandler implements the http api for worklogs, nested under their task. [/v1/tasks/{uuid}/worklogs]
+type WorklogHandler struct {
+	service *WorklogService
+}
+
+func NewWorklogHandler(service *WorklogService) *WorklogHandler {
+	return &WorklogHandler{service: service}
+}
+
+// Returns the task of the url, and the worklog if the url has one. Writes an error response if they're invalid.
+func parseParams(w http.ResponseWriter, r *http.Request, withWorklog bool) (uuid.UUID, uuid.UUID, bool) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if !withWorklog {
+		return taskID, uuid.Nil, true
+	}
+
+	worklogID, err := uuid.Parse(chi.URLParam(r, "worklog"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return taskID, worklogID, true
+}
+
+// List lists the worklogs of a task. [GET /v1/tasks/{uuid}/worklogs]
+func (h *WorklogHandler) List(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Parse the query parameters
+	query, err := krest.ParseCollectionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Parse the meta query parameters
+	metaQuery, err := krest.ParseMetaQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = krest.ValidateCollectionQuery[models.Worklog](query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the worklogs
+	worklogs, err := h.service.List(r.Context(), taskID, query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteCollectionResponse(w, http.StatusOK, worklogs, len(worklogs), len(worklogs), query, metaQuery)
+}
+
+// Get returns a worklog of a task. [GET /v1/tasks/{uuid}/worklogs/{worklog}]
+func (h *WorklogHandler) Get(w http.ResponseWriter, r *http.Request) {
+	taskID, worklogID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Get the worklog
+	worklog, err := h.service.Get(r.Context(), taskID, worklogID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	krest.WriteResourceResponse(w, http.StatusOK, worklog, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Create logs work on a task, e.g. {"started_at": "2026-10-18T09:00:00Z", "duration": 90}. [POST /v1/tasks/{uuid}/worklogs]
+func (h *WorklogHandler) Create(w http.ResponseWriter, r *http.Request) {
+	taskID, _, ok := parseParams(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Parse the request body.
+	var worklog models.Worklog
+	err := krest.DecodeRequestBody(w, r, &worklog, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields are managed by the server, ignore them.
+	krest.ClearReadOnlyFields(&worklog)
+
+	// Validate the worklog.
+	err = krest.ValidateResource(r.Context(), worklog, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Create the worklog.
+	createdWorklog, err := h.service.Create(r.Context(), taskID, worklog)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusCreated, createdWorklog, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Update edits a worklog. [PATCH /v1/tasks/{uuid}/worklogs/{worklog}]
+func (h *WorklogHandler) Update(w http.ResponseWriter, r *http.Request) {
+	taskID, worklogID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Get the current worklog, the request body is applied on top of it.
+	worklog, err := h.service.Get(r.Context(), taskID, worklogID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+	current := worklog
+
+	// Parse the request body.
+	err = krest.DecodeRequestBody(w, r, &worklog, krest.DefaultDecodeOptions)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Read-only fields can't be changed.
+	krest.CopyReadOnlyFields(&worklog, current)
+
+	// Validate the worklog.
+	err = krest.ValidateResource(r.Context(), worklog, nil)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Update the worklog.
+	updatedWorklog, err := h.service.Update(r.Context(), taskID, worklogID, worklog)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	krest.WriteResourceResponse(w, http.StatusOK, updatedWorklog, krest.ResourceQuery{}, krest.MetaQuery{})
+}
+
+// Delete deletes a worklog. [DELETE /v1/tasks/{uuid}/worklogs/{worklog}]
+func (h *WorklogHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	taskID, worklogID, ok := parseParams(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Delete the worklog.
+	err := h.service.Delete(r.Context(), taskID, worklogID)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TimesheetHandler implements the timesheet report. [GET /v1/timesheet]
+type TimesheetHandler struct {
+	service *TimesheetService
+}
+
+func NewTimesheetHandler(service *TimesheetService) *TimesheetHandler {
+	return &TimesheetHandler{service: service}
+}
+
+// Get returns the time logged per day, user and project. [GET /v1/timesheet]
+// The days are given with from and to (inclusive, e.g. 2026-10-01), optionally filtered by user_id and project_id.
+// Responds with CSV for format=csv, or an Accept header of text/csv.
+func (h *TimesheetHandler) Get(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTimesheetQuery(r)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Get the timesheet
+	entries, err := h.service.Timesheet(r.Context(), query)
+	if err != nil {
+		krest.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Write the response
+	if r.URL.Query().Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		writeCSV(w, entries)
+		return
+	}
+	krest.WriteCollectionResponse(w, http.StatusOK, entries, len(entries), len(entries), krest.CollectionQuery{}, krest.MetaQuery{})
+}
+
+// Returns the timesheet query of the query parameters.
+func parseTimesheetQuery(r *http.Request) (TimesheetQuery, error) {
+	params := r.URL.Query()
+	query := TimesheetQuery{}
+
+	days := map[string]*time.Time{"from": &query.From, "to": &query.To}
+	for name, day := range days {
+		value := params.Get(name)
+		if value == "" {
+			return TimesheetQuery{}, krest.NewError(http.StatusBadRequest, "%s is required", name)
+		}
+
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return TimesheetQuery{}, krest.NewError(http.StatusBadRequest, "invalid %s, expected a date like 2026-10-01: %v", name, err)
+		}
+		*day = t
+	}
+	if query.To.Before(query.From) {
+		return TimesheetQuery{}, krest.NewError(http.StatusBadRequest, "to must not be before from")
+	}
+	query.To = query.To.AddDate(0, 0, 1)
+
+	ids := map[string]**uuid.UUID{"user_id": &query.UserID, "project_id": &query.ProjectID}
+	for name, id := range ids {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return TimesheetQuery{}, krest.NewError(http.StatusBadRequest, "invalid %s: %v", name, err)
+		}
+		*id = &parsed
+	}
+
+	return query, nil
+}
+
+// Writes the timesheet as CSV, with a header row.
+func writeCSV(w http.ResponseWriter, entries []models.TimesheetEntry) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="timesheet.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"date", "user_id", "user_name", "project_id", "project_key", "duration"})
+	for _, entry := range entries {
+		// Work of deleted users has no user.
+		userID := ""
+		if entry.UserID != nil {
+			userID = entry.UserID.String()
+		}
+		writer.Write([]string{entry.Date, userID, entry.UserName, entry.ProjectID.String(), entry.ProjectKey, strconv.Itoa(entry.Duration)})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,97 @@
+package worklog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+// WorklogRepository locks tasks while their remaining estimates change and aggregates timesheets,
+// the worklogs themselves are stored by the generic repository.
+type WorklogRepository struct {
+	db *sql.DB
+}
+
+// NewWorklogRepository queries the worklogs, tasks, users and projects tables, create them first.
+func NewWorklogRepository(db *sql.DB) *WorklogRepository {
+	return &WorklogRepository{db: db}
+}
+
+// Transaction runs fn in a transaction, see krest_orm.Transaction.
+func (r *WorklogRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return krest_orm.Transaction(ctx, r.db, fn)
+}
+
+// LockTask locks a task until the end of the transaction of the context, so its estimate can be changed from its current value.
+func (r *WorklogRepository) LockTask(ctx context.Context, taskID uuid.UUID) error {
+	return krest_orm.LockRow(ctx, r.db, "tasks", taskID)
+}
+
+// Timesheet sums the minutes logged by each user on each of the projects per day, ordered by day, user and project.
+func (r *WorklogRepository) Timesheet(ctx context.Context, query TimesheetQuery, projectIDs []uuid.UUID) ([]models.TimesheetEntry, error) {
+	if len(projectIDs) == 0 {
+		return []models.TimesheetEntry{}, nil
+	}
+
+	args := []interface{}{query.From.UTC(), query.To.UTC()}
+	placeholders := []string{}
+	for _, projectID := range projectIDs {
+		args = append(args, projectID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	conditions := fmt.Sprintf("worklogs.started_at >= $1 AND worklogs.started_at < $2 AND tasks.project_id IN (%s)", strings.Join(placeholders, ", "))
+	if query.UserID != nil {
+		args = append(args, *query.UserID)
+		conditions += fmt.Sprintf(" AND worklogs.user_id = $%d", len(args))
+	}
+
+	// Work of deleted users is kept without a user.
+	rows, err := krest_orm.Conn(ctx, r.db).QueryContext(ctx, fmt.Sprintf(`
+		SELECT DATE(worklogs.started_at) AS day, worklogs.user_id, COALESCE(users.name, ''), tasks.project_id, projects.key, SUM(worklogs.duration)
+		FROM worklogs
+		JOIN tasks ON tasks.uuid = worklogs.task_id
+		JOIN projects ON projects.uuid = tasks.project_id
+		LEFT JOIN users ON users.uuid = worklogs.user_id
+		WHERE %s
+		GROUP BY day, worklogs.user_id, users.name, tasks.project_id, projects.key
+		ORDER BY day, COALESCE(users.name, ''), projects.key`,
+		conditions,
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timesheet: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.TimesheetEntry{}
+	for rows.Next() {
+		var entry models.TimesheetEntry
+		var day interface{}
+		var userID uuid.NullUUID
+		err = rows.Scan(&day, &userID, &entry.UserName, &entry.ProjectID, &entry.ProjectKey, &entry.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query timesheet: %v", err)
+		}
+		if userID.Valid {
+			entry.UserID = &userID.UUID
+		}
+
+		// Postgres returns dates as times, SQLite as text.
+		switch day := day.(type) {
+		case time.Time:
+			entry.Date = day.Format(time.DateOnly)
+		case string:
+			entry.Date = day
+		case []byte:
+			entry.Date = string(day)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
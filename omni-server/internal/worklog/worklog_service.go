@@ -0,0 +1,262 @@
+package worklog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/pkg/models"
+)
+
+var ErrNotAuthor = krest.NewError(http.StatusForbidden, "only the author or a project admin can change a worklog")
+
+// WorklogService manages the work logged on tasks, keeping the remaining estimates of the tasks up to date.
+// Worklogs are visible to everyone who can see the task, members of the project can log work.
+// Only the author or a project admin can edit or delete a worklog. Changes are audited.
+type WorklogService struct {
+	worklogs   krest.Repository[models.Worklog]
+	repository *WorklogRepository
+	tasks      krest.Service[models.Task]
+	estimates  krest.Service[models.Task]
+	policy     *authz.Policy
+	auditLog   *audit.Log
+}
+
+// The task service must authorize reading tasks, worklogs are only visible through their task.
+// Remaining estimates are changed through the estimates service, which doesn't authorize, logging work doesn't require
+// changing the task. Wrap it in the audit service, so the changes are audited.
+func NewWorklogService(worklogs krest.Repository[models.Worklog], repository *WorklogRepository, tasks krest.Service[models.Task], estimates krest.Service[models.Task], policy *authz.Policy, auditLog *audit.Log) *WorklogService {
+	return &WorklogService{worklogs: worklogs, repository: repository, tasks: tasks, estimates: estimates, policy: policy, auditLog: auditLog}
+}
+
+// Returns a visible task, with its project.
+func (s *WorklogService) task(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	return s.tasks.Get(ctx, taskID, krest.ResourceQuery{Expand: []string{"project_id"}})
+}
+
+// List lists the worklogs of a task, oldest first unless sorted otherwise.
+func (s *WorklogService) List(ctx context.Context, taskID uuid.UUID, query krest.CollectionQuery) ([]models.Worklog, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Filters = append(query.Filters, krest.Filter{Field: "task_id", Operator: krest.FilterEqual, Value: taskID})
+	if len(query.Sort) == 0 {
+		query.Sort = []krest.Sort{{Field: "started_at"}}
+	}
+	return s.worklogs.List(ctx, query)
+}
+
+func (s *WorklogService) Get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Worklog, error) {
+	_, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	return s.get(ctx, taskID, id)
+}
+
+// Returns a worklog of a task, without authorizing.
+func (s *WorklogService) get(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Worklog, error) {
+	worklog, err := s.worklogs.Get(ctx, id, krest.ResourceQuery{})
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	if worklog.TaskID != taskID {
+		return models.Worklog{}, krest.NewError(http.StatusNotFound, "worklogs %s not found", id)
+	}
+
+	return worklog, nil
+}
+
+// Create logs work of the authenticated user on a task, and reduces the remaining estimate of the task.
+func (s *WorklogService) Create(ctx context.Context, taskID uuid.UUID, worklog models.Worklog) (models.Worklog, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Worklog{}, auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	err = s.policy.Authorize(ctx, task.ProjectID, authz.RoleMember, krest.NewError(http.StatusNotFound, "tasks %s not found", taskID))
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	worklog.TaskID = task.UUID
+	worklog.UserID = &user.UUID
+	worklog.StartedAt = worklog.StartedAt.UTC()
+	worklog.CreatedAt = time.Now().UTC()
+	var created models.Worklog
+	err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+		worklog.EstimateReduced, err = s.reduceEstimate(ctx, taskID, worklog.Duration)
+		if err != nil {
+			return err
+		}
+		created, err = s.worklogs.Create(ctx, worklog)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionCreate, task, created.UUID, nil, created)
+	})
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	return created, nil
+}
+
+// Update edits a worklog, and reduces the remaining estimate of the task by its new duration instead.
+func (s *WorklogService) Update(ctx context.Context, taskID uuid.UUID, id uuid.UUID, worklog models.Worklog) (models.Worklog, error) {
+	task, current, err := s.authorizeChange(ctx, taskID, id)
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	// Only the time and the comment can change.
+	edited := current
+	edited.StartedAt = worklog.StartedAt.UTC()
+	edited.Comment = worklog.Comment
+	var updated models.Worklog
+	err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if worklog.Duration != current.Duration {
+			_, err := s.changeEstimate(ctx, taskID, current.EstimateReduced)
+			if err != nil {
+				return err
+			}
+			edited.Duration = worklog.Duration
+			edited.EstimateReduced, err = s.reduceEstimate(ctx, taskID, worklog.Duration)
+			if err != nil {
+				return err
+			}
+		}
+		updated, err = s.worklogs.Update(ctx, id, edited)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionUpdate, task, id, current, updated)
+	})
+	if err != nil {
+		return models.Worklog{}, err
+	}
+
+	return updated, nil
+}
+
+// Delete deletes a worklog, and restores the remaining estimate of the task from before it was logged.
+func (s *WorklogService) Delete(ctx context.Context, taskID uuid.UUID, id uuid.UUID) error {
+	task, worklog, err := s.authorizeChange(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Transaction(ctx, func(ctx context.Context) error {
+		err := s.worklogs.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = s.changeEstimate(ctx, taskID, worklog.EstimateReduced)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionDelete, task, id, worklog, nil)
+	})
+}
+
+// Reduces the remaining estimate of a task by minutes, down to 0, and returns by how much it was reduced.
+func (s *WorklogService) reduceEstimate(ctx context.Context, taskID uuid.UUID, minutes int) (int, error) {
+	change, err := s.changeEstimate(ctx, taskID, -minutes)
+	return -change, err
+}
+
+// Adds minutes to the remaining estimate of a task, keeping it at least 0, and returns by how much it changed.
+// The task is locked while its estimate changes, so the change is based on its current estimate.
+// Tasks without an estimate are left without one. Must be called in a transaction.
+func (s *WorklogService) changeEstimate(ctx context.Context, taskID uuid.UUID, minutes int) (int, error) {
+	if minutes == 0 {
+		return 0, nil
+	}
+
+	err := s.repository.LockTask(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	expand, err := krest.ExpandableFieldNames[models.Task]()
+	if err != nil {
+		return 0, err
+	}
+	task, err := s.estimates.Get(ctx, taskID, krest.ResourceQuery{Expand: expand})
+	if err != nil {
+		return 0, err
+	}
+
+	previous := task.RemainingEstimate
+	if previous == nil {
+		previous = task.OriginalEstimate
+	}
+	if previous == nil {
+		return 0, nil
+	}
+
+	remaining := max(*previous+minutes, 0)
+	task.RemainingEstimate = &remaining
+	_, err = s.estimates.Update(ctx, taskID, task)
+	if err != nil {
+		return 0, err
+	}
+
+	return remaining - *previous, nil
+}
+
+// Records a change of a worklog in the audit log, pass nil as before or after for created and deleted worklogs.
+func (s *WorklogService) record(ctx context.Context, action audit.Action, task models.Task, id uuid.UUID, before interface{}, after interface{}) error {
+	err := s.auditLog.Record(ctx, action, "worklogs", id, task.ProjectID, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+// Returns the task and the worklog, if the authenticated user is its author or an admin of the project.
+func (s *WorklogService) authorizeChange(ctx context.Context, taskID uuid.UUID, id uuid.UUID) (models.Task, models.Worklog, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return models.Task{}, models.Worklog{}, auth.ErrUnauthenticated
+	}
+
+	task, err := s.task(ctx, taskID)
+	if err != nil {
+		return models.Task{}, models.Worklog{}, err
+	}
+
+	worklog, err := s.get(ctx, taskID, id)
+	if err != nil {
+		return models.Task{}, models.Worklog{}, err
+	}
+
+	if worklog.UserID != nil && *worklog.UserID == user.UUID {
+		return task, worklog, nil
+	}
+
+	role, err := s.policy.Role(ctx, task.ProjectID, user.UUID)
+	if err != nil {
+		return models.Task{}, models.Worklog{}, err
+	}
+	if !role.Includes(authz.RoleAdmin) {
+		return models.Task{}, models.Worklog{}, ErrNotAuthor
+	}
+
+	return task, worklog, nil
+}
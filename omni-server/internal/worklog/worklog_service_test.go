@@ -0,0 +1,183 @@
+package worklog_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/pkg/krest"
+	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/worklog"
+	"github.com/khaossystems/omni-server/pkg/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestWorklogs(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
+	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	worklogRepository := krest_orm.NewGenericPostgresRepository[models.Worklog](db)
+	policy := authz.NewPolicy(membershipRepository)
+	authorized := authz.NewPolicyService(krest_orm.NewGenericService(taskRepository), policy, authz.TaskRules)
+	tasks := worklog.NewTaskService(authorized)
+	repository := worklog.NewWorklogRepository(db)
+	auditLog := audit.NewLog(krest_orm.NewGenericPostgresRepository[models.AuditEvent](db))
+	audited := audit.NewService(krest_orm.NewGenericService(taskRepository), auditLog, func(task models.Task) uuid.UUID { return task.ProjectID })
+	worklogs := worklog.NewWorklogService(worklogRepository, repository, authorized, audited, policy, auditLog)
+	timesheets := worklog.NewTimesheetService(repository, policy)
+
+	// Alice and Bob are members of Omni, only Bob of Web.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+	users, projects := map[string]context.Context{}, map[string]models.Project{}
+	for _, key := range []string{"OMNI", "WEB"} {
+		project, err := projectRepository.Create(ctx, models.Project{Name: key, Key: key})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		projects[key] = project
+	}
+	for name, keys := range map[string][]string{"alice": {"OMNI"}, "bob": {"OMNI", "WEB"}} {
+		user, err := userRepository.Create(ctx, models.User{Name: name, Username: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		for _, key := range keys {
+			err = policy.AddMember(ctx, projects[key].UUID, user.UUID, authz.RoleMember)
+			if err != nil {
+				t.Fatalf("AddMember failed: %v", err)
+			}
+		}
+		users[name] = auth.WithUser(ctx, user)
+	}
+	alice, bob := users["alice"], users["bob"]
+
+	// The remaining estimate defaults to the original estimate.
+	estimate := 240
+	task, err := tasks.Create(alice, models.Task{Summary: "Estimated", ProjectID: projects["OMNI"].UUID, OriginalEstimate: &estimate})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if task.RemainingEstimate == nil || *task.RemainingEstimate != 240 {
+		t.Errorf("expected a remaining estimate of 240, got %v", task.RemainingEstimate)
+	}
+	web, err := tasks.Create(bob, models.Task{Summary: "Web", ProjectID: projects["WEB"].UUID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	remaining := func() int {
+		task, err := taskRepository.Get(ctx, task.UUID, krest.ResourceQuery{Expand: []string{"remaining_estimate"}})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if task.RemainingEstimate == nil {
+			return -1
+		}
+		return *task.RemainingEstimate
+	}
+
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	first, err := worklogs.Create(alice, task.UUID, models.Worklog{StartedAt: day, Duration: 90})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = worklogs.Create(alice, task.UUID, models.Worklog{StartedAt: day.Add(4 * time.Hour), Duration: 30})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = worklogs.Create(bob, task.UUID, models.Worklog{StartedAt: day.AddDate(0, 0, 1), Duration: 60})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = worklogs.Create(bob, web.UUID, models.Worklog{StartedAt: day, Duration: 45})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if remaining() != 60 {
+		t.Errorf("expected 60 minutes remaining, got %d", remaining())
+	}
+
+	// Remaining estimates don't go below 0, and deleting a worklog restores the estimate from before it.
+	first.Duration = 300
+	_, err = worklogs.Update(alice, task.UUID, first.UUID, first)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if remaining() != 0 {
+		t.Errorf("expected nothing remaining, got %d", remaining())
+	}
+	err = worklogs.Delete(bob, task.UUID, first.UUID)
+	if krest.ErrorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for deleting a worklog of another user, got %v", err)
+	}
+	err = worklogs.Delete(alice, task.UUID, first.UUID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if remaining() != 150 {
+		t.Errorf("expected 150 minutes remaining, got %d", remaining())
+	}
+
+	// Logging, editing and deleting work is audited.
+	events, err := auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: first.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	last := len(events) - 1
+	if len(events) < 3 || events[0].Action != string(audit.ActionCreate) || events[0].ResourceType != "worklogs" || events[0].ProjectID != task.ProjectID ||
+		events[last-1].Changes["duration"].After != float64(300) || events[last].Action != string(audit.ActionDelete) {
+		t.Errorf("expected the worklog to be audited when logged, edited and deleted, got %+v", events)
+	}
+
+	// So are the changes of the remaining estimate, which are kept in the revisions of the task.
+	revisions, err := taskRepository.ListRevisions(ctx, task.UUID, krest.CollectionQuery{})
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 7 || revisions[6].Changes["remaining_estimate"].After != float64(150) {
+		t.Errorf("expected a revision for each change of the remaining estimate, got %+v", revisions)
+	}
+	events, err = auditLog.List(ctx, krest.CollectionQuery{Filters: []krest.Filter{{Field: "resource_id", Operator: krest.FilterEqual, Value: task.UUID}}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(events) != 6 || events[5].Changes["remaining_estimate"].Before != float64(0) {
+		t.Errorf("expected the changes of the remaining estimate to be audited, got %+v", events)
+	}
+
+	// Timesheets aggregate by day, user and project, in projects the user is a member of.
+	entries, err := timesheets.Timesheet(alice, worklog.TimesheetQuery{From: day.Truncate(24 * time.Hour), To: day.AddDate(0, 0, 2)})
+	if err != nil {
+		t.Fatalf("Timesheet failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries in Omni, got %+v", entries)
+	}
+	if entries[0].Date != "2026-10-01" || entries[0].UserName != "alice" || entries[0].Duration != 30 {
+		t.Errorf("expected 30 minutes of alice on 2026-10-01, got %+v", entries[0])
+	}
+	if entries[1].Date != "2026-10-02" || entries[1].UserName != "bob" || entries[1].ProjectKey != "OMNI" || entries[1].Duration != 60 {
+		t.Errorf("expected 60 minutes of bob on 2026-10-02, got %+v", entries[1])
+	}
+
+	entries, err = timesheets.Timesheet(bob, worklog.TimesheetQuery{From: day.Truncate(24 * time.Hour), To: day.AddDate(0, 0, 1).Truncate(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Timesheet failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected the work of alice in Omni and bob in Web on 2026-10-01, got %+v", entries)
+	}
+}
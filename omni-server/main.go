@@ -1,19 +1,49 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/khaossystems/omni-server/internal/attachment"
+	"github.com/khaossystems/omni-server/internal/audit"
+	"github.com/khaossystems/omni-server/internal/auth"
+	"github.com/khaossystems/omni-server/internal/authz"
+	"github.com/khaossystems/omni-server/internal/comment"
+	"github.com/khaossystems/omni-server/internal/customfield"
+	"github.com/khaossystems/omni-server/internal/hierarchy"
+	"github.com/khaossystems/omni-server/internal/label"
+	"github.com/khaossystems/omni-server/internal/link"
+	"github.com/khaossystems/omni-server/internal/mail"
+	"github.com/khaossystems/omni-server/internal/mention"
+	"github.com/khaossystems/omni-server/internal/notification"
+	"github.com/khaossystems/omni-server/internal/organization"
+	"github.com/khaossystems/omni-server/internal/participant"
 	"github.com/khaossystems/omni-server/internal/pkg/krest"
 	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
+	"github.com/khaossystems/omni-server/internal/ranking"
+	"github.com/khaossystems/omni-server/internal/search"
+	"github.com/khaossystems/omni-server/internal/sprint"
+	"github.com/khaossystems/omni-server/internal/taskkey"
+	"github.com/khaossystems/omni-server/internal/user"
+	"github.com/khaossystems/omni-server/internal/workflow"
+	"github.com/khaossystems/omni-server/internal/worklog"
 	"github.com/khaossystems/omni-server/pkg/models"
 	"github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -95,6 +125,68 @@ func connectToSQLiteDatabase() (*sql.DB, error) {
 	return db, nil
 }
 
+func createBlobStore() *attachment.LocalBlobStore {
+	// Attachments are stored next to the SQLite database.
+	dataPath := os.Getenv("OMNI_DATA_PATH")
+	if dataPath == "" {
+		log.Fatal("Missing required environment variables")
+	}
+
+	store, err := attachment.NewLocalBlobStore(filepath.Join(dataPath, "attachments"))
+	if err != nil {
+		log.Fatalf("Failed to create the attachment store: %v", err)
+	}
+
+	return store
+}
+
+func attachmentLimits() attachment.Limits {
+	limits := attachment.DefaultLimits
+
+	// Get the limits from environment variables, e.g. OMNI_ATTACHMENT_TYPES=image/,application/pdf
+	if maxSize := os.Getenv("OMNI_ATTACHMENT_MAX_SIZE"); maxSize != "" {
+		size, err := strconv.ParseInt(maxSize, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid OMNI_ATTACHMENT_MAX_SIZE, expected a number of bytes: %s", maxSize)
+		}
+		limits.MaxSize = size
+	}
+	if types := os.Getenv("OMNI_ATTACHMENT_TYPES"); types != "" {
+		limits.Types = strings.Split(types, ",")
+	}
+
+	return limits
+}
+
+func createMailer() mail.Mailer {
+	// Get the mail server from environment variables, e.g. OMNI_SMTP_HOST=smtp.example.com
+	from := os.Getenv("OMNI_MAIL_FROM")
+	if from == "" {
+		from = "Omni <omni@localhost>"
+	}
+	host := os.Getenv("OMNI_SMTP_HOST")
+	if host != "" {
+		port := os.Getenv("OMNI_SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mail.NewSMTPMailer(host, port, os.Getenv("OMNI_SMTP_USER"), os.Getenv("OMNI_SMTP_PASSWORD"), from)
+	}
+
+	// Without a mail server, emails are written next to the SQLite database.
+	dataPath := os.Getenv("OMNI_DATA_PATH")
+	if dataPath == "" {
+		log.Fatal("Missing required environment variables")
+	}
+
+	mailer, err := mail.NewFileMailer(filepath.Join(dataPath, "mail"), from)
+	if err != nil {
+		log.Fatalf("Failed to create the mailer: %v", err)
+	}
+
+	return mailer
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -107,7 +199,10 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
-func createRouter(db *sql.DB) *chi.Mux {
+// worker is background work of the server, running until the context is done.
+type worker func(ctx context.Context)
+
+func createRouter(db *sql.DB) (*chi.Mux, []worker) {
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
@@ -116,48 +211,395 @@ func createRouter(db *sql.DB) *chi.Mux {
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Range", "If-Range", organization.OrganizationHeader},
+		ExposedHeaders:   []string{"Link", "Content-Range", "Content-Disposition"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
+	// Every change going through the services below is recorded in the audit log.
+	auditRepository := krest_orm.NewGenericPostgresRepository[models.AuditEvent](db)
+	auditLog := audit.NewLog(auditRepository)
+
+	organizationRepository := krest_orm.NewGenericPostgresRepository[models.Organization](db)
+	organizationService := organization.NewOrganizationService(audit.NewService(krest_orm.NewGenericService(organizationRepository), auditLog, nil))
+	organizationHandler := krest.NewHandler(organizationService)
+
 	userRepository := krest_orm.NewGenericPostgresRepository[models.User](db)
-	userService := krest_orm.NewGenericService(userRepository)
+	userService := audit.NewService(user.NewUserService(userRepository), auditLog, nil)
 	userHandler := krest.NewHandler(userService)
 
-	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
-	taskService := krest_orm.NewGenericService(taskRepository)
-	taskHandler := krest.NewHandler(taskService)
+	sessionRepository := krest_orm.NewGenericPostgresRepository[models.Session](db)
+	authService := auth.NewAuthService(userRepository, sessionRepository, auth.DefaultConfig)
+	authHandler := auth.NewHandler(authService)
+	router.Use(authService.Middleware)
+
+	tokenRepository := krest_orm.NewGenericPostgresRepository[models.Token](db)
+	tokenService := auth.NewTokenService(tokenRepository, audit.NewService(krest_orm.NewGenericService(tokenRepository), auditLog, nil), userRepository)
+	tokenHandler := auth.NewTokenHandler(tokenService)
+	router.Use(tokenService.Middleware)
+
+	// Scope every request to an organization, after authentication.
+	router.Use(organizationService.Middleware)
 
 	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
-	projectService := krest_orm.NewGenericService(projectRepository)
+	membershipRepository := krest_orm.NewGenericPostgresRepository[models.Membership](db)
+	auditedMembershipService := audit.NewService(krest_orm.NewGenericService(membershipRepository), auditLog, func(membership models.Membership) uuid.UUID { return membership.ProjectID })
+	policy := authz.NewPolicy(auditedMembershipService)
+	auditHandler := audit.NewHandler(auditLog, policy)
+
+	membershipService := authz.NewPolicyService(auditedMembershipService, policy, authz.Rules[models.Membership]{
+		Project:      func(membership models.Membership) uuid.UUID { return membership.ProjectID },
+		ProjectField: "project_id",
+		Read:         authz.RoleViewer,
+		Create:       authz.RoleAdmin,
+		Update:       authz.RoleAdmin,
+		Delete:       authz.RoleAdmin,
+	})
+	membershipHandler := krest.NewHandler(membershipService)
+
+	statusRepository := krest_orm.NewGenericPostgresRepository[models.Status](db)
+	auditedStatusService := audit.NewService(krest_orm.NewGenericService(statusRepository), auditLog, func(status models.Status) uuid.UUID { return status.ProjectID })
+	statusService := authz.NewPolicyService(auditedStatusService, policy, authz.Rules[models.Status]{
+		Project:      func(status models.Status) uuid.UUID { return status.ProjectID },
+		ProjectField: "project_id",
+		Read:         authz.RoleViewer,
+		Create:       authz.RoleAdmin,
+		Update:       authz.RoleAdmin,
+		Delete:       authz.RoleAdmin,
+	})
+	statusHandler := krest.NewHandler(statusService)
+
+	// Transitions can require the blockers of a task to be completed.
+	linkRepository := link.NewLinkRepository(db)
+	guards := workflow.DefaultGuards()
+	guards["blockers_resolved"] = link.BlockersResolvedGuard(linkRepository)
+
+	taskTypeRepository := krest_orm.NewGenericPostgresRepository[models.TaskType](db)
+	auditedTaskTypeService := audit.NewService(krest_orm.NewGenericService(taskTypeRepository), auditLog, func(taskType models.TaskType) uuid.UUID { return taskType.ProjectID })
+	taskTypeService := authz.NewPolicyService(customfield.NewTaskTypeService(workflow.NewTaskTypeService(auditedTaskTypeService, statusRepository, guards)), policy, authz.Rules[models.TaskType]{
+		Project:      func(taskType models.TaskType) uuid.UUID { return taskType.ProjectID },
+		ProjectField: "project_id",
+		Read:         authz.RoleViewer,
+		Create:       authz.RoleAdmin,
+		Update:       authz.RoleAdmin,
+		Delete:       authz.RoleAdmin,
+	})
+	taskTypeHandler := krest.NewHandler(taskTypeService)
+
+	sprintRepository := krest_orm.NewGenericPostgresRepository[models.Sprint](db)
+	labelRepository := krest_orm.NewGenericPostgresRepository[models.Label](db)
+
+	taskRepository := krest_orm.NewGenericPostgresRepository[models.Task](db)
+	taskLinkRepository := krest_orm.NewGenericPostgresRepository[models.TaskLink](db)
+	worklogRepository := krest_orm.NewGenericPostgresRepository[models.Worklog](db)
+	attachmentRepository := krest_orm.NewGenericPostgresRepository[models.Attachment](db)
+	notificationRepository := krest_orm.NewGenericPostgresRepository[models.Notification](db)
+	notificationPreferencesRepository := krest_orm.NewGenericPostgresRepository[models.NotificationPreferences](db)
+	emailRepository := krest_orm.NewGenericPostgresRepository[models.Email](db)
+	auditedTaskService := audit.NewService(krest_orm.NewGenericService(taskRepository), auditLog, func(task models.Task) uuid.UUID { return task.ProjectID })
+	authorizedTaskService := authz.NewPolicyService(auditedTaskService, policy, authz.TaskRules)
+
+	// Number tasks within their project, e.g. OMNI-42.
+	keyRepository := taskkey.NewKeyRepository(db)
+	keyService := taskkey.NewKeyService(authorizedTaskService, keyRepository, projectRepository, policy)
+
+	auditedProjectService := audit.NewService(krest_orm.NewGenericService(projectRepository), auditLog, func(project models.Project) uuid.UUID { return project.UUID })
+	projectService := authz.NewProjectService(taskkey.NewProjectService(auditedProjectService, keyRepository, auditedTaskService), policy, projectRepository)
 	projectHandler := krest.NewHandler(projectService)
 
+	commentRepository := krest_orm.NewGenericPostgresRepository[models.Comment](db)
+
+	// Search needs a full-text index, on SQLite the server must be built with -tags sqlite_fts5, see the Makefile.
+	for _, err := range []error{taskRepository.SearchError(), commentRepository.SearchError()} {
+		if err != nil {
+			log.Fatalf("Full-text search is unavailable: %v", err)
+		}
+	}
+	commentService := comment.NewCommentService(commentRepository, authorizedTaskService, policy, auditLog)
+	commentHandler := comment.NewCommentHandler(commentService)
+
+	// Rank tasks in the backlog of their project, rebalancing ranks in the background.
+	rebalancer := ranking.NewRebalancer(db, auditedTaskService)
+	rankService := ranking.NewRankService(keyService, rebalancer)
+	rankHandler := ranking.NewRankHandler(rankService)
+
+	// Plan tasks into sprints, closing sprints moves their incomplete tasks.
+	auditedSprintService := audit.NewService(krest_orm.NewGenericService(sprintRepository), auditLog, func(sprint models.Sprint) uuid.UUID { return sprint.ProjectID })
+	sprintService := sprint.NewSprintService(authz.NewPolicyService(auditedSprintService, policy, authz.Rules[models.Sprint]{
+		Project:      func(sprint models.Sprint) uuid.UUID { return sprint.ProjectID },
+		ProjectField: "project_id",
+		Read:         authz.RoleViewer,
+		Create:       authz.RoleMember,
+		Update:       authz.RoleMember,
+		Delete:       authz.RoleMember,
+	}), rankService, sprintRepository)
+	sprintHandler := krest.NewHandler(sprintService)
+	sprintActionHandler := sprint.NewSprintHandler(sprintService)
+	sprintTaskService := sprint.NewTaskService(rankService, sprintRepository)
+
+	customFieldService := customfield.NewCustomFieldService(sprintTaskService, taskTypeRepository, userRepository)
+	workflowService := workflow.NewWorkflowService(customFieldService, statusRepository, taskTypeRepository, guards)
+
+	// Report tasks as their creator, reporters and assignees watch their tasks.
+	watcherRepository := participant.NewWatcherRepository(db)
+	watcherService := participant.NewWatcherService(watcherRepository, authorizedTaskService, userRepository, policy, auditLog)
+	watcherHandler := participant.NewWatcherHandler(watcherService)
+
+	// Label tasks, with labels of the organization or the project.
+	taskLabelRepository := label.NewTaskLabelRepository(db)
+	auditedLabelService := audit.NewService(krest_orm.NewGenericService(labelRepository), auditLog, func(label models.Label) uuid.UUID {
+		if label.ProjectID == nil {
+			return uuid.Nil
+		}
+		return *label.ProjectID
+	})
+	labelService := label.NewLabelService(auditedLabelService, taskLabelRepository, policy)
+	labelHandler := krest.NewHandler(labelService)
+	taskLabelService := label.NewTaskLabelService(taskLabelRepository, labelRepository, authorizedTaskService, policy, auditLog)
+	taskLabelHandler := label.NewTaskLabelHandler(taskLabelService)
+
+	// Break tasks into sub-tasks, grouped by epics.
+	hierarchyRepository := hierarchy.NewHierarchyRepository(db)
+	hierarchyService := hierarchy.NewHierarchyService(workflowService, hierarchyRepository, taskTypeRepository)
+
+	// Link tasks to each other.
+	linkService := link.NewLinkService(taskLinkRepository, linkRepository, authorizedTaskService, policy, auditLog)
+	linkHandler := link.NewLinkHandler(linkService)
+
+	// Log work on tasks, reducing their remaining estimates.
+	worklogTimeRepository := worklog.NewWorklogRepository(db)
+	worklogService := worklog.NewWorklogService(worklogRepository, worklogTimeRepository, authorizedTaskService, auditedTaskService, policy, auditLog)
+	worklogHandler := worklog.NewWorklogHandler(worklogService)
+	timesheetHandler := worklog.NewTimesheetHandler(worklog.NewTimesheetService(worklogTimeRepository, policy))
+
+	// Attach files to tasks, removing the files no attachment uses anymore in the background.
+	attachmentStore := createBlobStore()
+	attachmentService := attachment.NewAttachmentService(attachmentRepository, attachmentStore, authorizedTaskService, policy, auditLog, attachmentLimits())
+	attachmentHandler := attachment.NewAttachmentHandler(attachmentService)
+	orphanCollector := attachment.NewOrphanCollector(attachment.NewAttachmentRepository(db), attachmentStore, time.Hour)
+
+	// Notify assignees and watchers of changes to their tasks.
+	notifier := notification.NewNotifier(notificationRepository, notificationPreferencesRepository, watcherRepository, taskRepository, policy)
+	notificationHandler := notification.NewNotificationHandler(notification.NewNotificationService(notificationRepository, notificationPreferencesRepository))
+	commentService.OnCreate(notifier.CommentHook())
+
+	// Email notifications through a queue sending them in the background, so mail outages don't fail requests.
+	mailQueue := mail.NewQueue(emailRepository, createMailer(), mail.DefaultMaxAttempts, time.Minute)
+	emailer := notification.NewEmailer(mailQueue, notificationRepository, notificationPreferencesRepository, userRepository, taskRepository)
+	notifier.OnNotify(emailer.Hook())
+
+	// Notify users mentioned in descriptions and comments, and link the tasks they reference.
+	mentioner := mention.NewMentioner(userRepository, authorizedTaskService, linkService, notifier)
+	commentService.OnCreate(mentioner.CommentHook())
+
+	participantService := participant.NewTaskService(hierarchyService, watcherRepository)
+	labelTaskService := label.NewTaskService(participantService, labelRepository)
+	notificationTaskService := notification.NewTaskService(worklog.NewTaskService(labelTaskService), notifier, statusRepository)
+	mentionTaskService := mention.NewTaskService(notificationTaskService, mentioner)
+	taskService := krest_orm.NewRelationService[models.Task](mentionTaskService,
+		comment.TaskRelation(commentRepository),
+		workflow.StatusRelation(statusRepository),
+		workflow.TypeRelation(taskTypeRepository),
+		sprint.TaskRelation(sprintRepository),
+		participant.AssigneeRelation(userRepository),
+		participant.ReporterRelation(userRepository),
+		participant.WatchersRelation(watcherRepository, userRepository),
+		label.TaskRelation(taskLabelRepository, labelRepository),
+		hierarchy.ParentRelation(taskRepository),
+		hierarchy.ProgressRelation(hierarchyRepository),
+		mention.DescriptionHTMLRelation(),
+	)
+	taskHandler := krest.NewHandler(taskService)
+	taskKeyHandler := taskkey.NewKeyHandler(keyService, taskHandler.Get)
+	taskRevisionHandler := krest_orm.NewRevisionHandler(taskService, taskRepository)
+	assignedTaskHandler := participant.NewAssignedTaskHandler(taskService, userRepository)
+	hierarchyHandler := hierarchy.NewHierarchyHandler(taskService, hierarchyRepository)
+	searchHandler := search.NewSearchHandler(search.NewSearchService(taskRepository, commentRepository, policy))
+
 	router.Route("/v1", func(v2 chi.Router) {
+		// Auth
+		v2.Post("/auth/login", authHandler.Login)
+		v2.Post("/auth/logout", authHandler.Logout)
+		v2.Get("/auth/me", authHandler.Me)
+		v2.Post("/auth/signup", userHandler.Create)
+
+		// Organizations
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("organizations"))
+			r.Get("/organizations/{uuid}", organizationHandler.Get)
+			r.Get("/organizations", organizationHandler.List)
+			r.Post("/organizations", organizationHandler.Create)
+			r.Patch("/organizations/{uuid}", organizationHandler.Update)
+		})
+
+		// Audit events
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("audit_events"))
+			r.Get("/audit-events/{uuid}", auditHandler.Get)
+			r.Get("/audit-events", auditHandler.List)
+		})
+
 		// Users
-		v2.Get("/users/{uuid}", userHandler.Get)
-		v2.Get("/users", userHandler.List)
-		v2.Post("/users", userHandler.Create)
-		v2.Patch("/users/{uuid}", userHandler.Update)
-		v2.Delete("/users/{uuid}", userHandler.Delete)
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireUser)
+			r.Use(auth.RequireScope("users"))
+			r.Get("/users/{uuid}", userHandler.Get)
+			r.Get("/users", userHandler.List)
+			r.Post("/users", userHandler.Create)
+			r.Patch("/users/{uuid}", userHandler.Update)
+			r.Delete("/users/{uuid}", userHandler.Delete)
+		})
+
+		// Tokens
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("tokens"))
+			r.Get("/users/{uuid}/tokens", tokenHandler.List)
+			r.Post("/users/{uuid}/tokens", tokenHandler.Create)
+			r.Delete("/users/{uuid}/tokens/{token}", tokenHandler.Delete)
+		})
 
 		// Tasks
-		v2.Get("/tasks/{uuid}", taskHandler.Get)
-		v2.Get("/tasks", taskHandler.List)
-		v2.Post("/tasks", taskHandler.Create)
-		v2.Patch("/tasks/{uuid}", taskHandler.Update)
-		v2.Delete("/tasks/{uuid}", taskHandler.Delete)
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("tasks"))
+			r.Get("/tasks/{uuid}", taskKeyHandler.Get)
+			r.Get("/tasks", taskHandler.List)
+			r.Get("/search", searchHandler.Search)
+			r.Post("/tasks", taskHandler.Create)
+			r.Patch("/tasks/{uuid}", taskHandler.Update)
+			r.Delete("/tasks/{uuid}", taskHandler.Delete)
+			r.Post("/tasks/{uuid}:move", rankHandler.Move)
+			r.Get("/tasks/{uuid}/revisions", taskRevisionHandler.List)
+			r.Get("/tasks/{uuid}/revisions/{number}", taskRevisionHandler.Get)
+			r.Post("/tasks/{uuid}/revisions/{number}:revert", taskRevisionHandler.Revert)
+			r.Get("/tasks/{uuid}/children", hierarchyHandler.Children)
+			r.Get("/tasks/{uuid}/descendants", hierarchyHandler.Descendants)
+			r.Get("/tasks/{uuid}/watchers", watcherHandler.List)
+			r.Post("/tasks/{uuid}/watchers", watcherHandler.Watch)
+			r.Delete("/tasks/{uuid}/watchers", watcherHandler.Unwatch)
+			r.Get("/users/{uuid}/assigned-tasks", assignedTaskHandler.List)
+			r.Get("/tasks/{uuid}/labels", taskLabelHandler.List)
+			r.Post("/tasks/{uuid}/labels", taskLabelHandler.Attach)
+			r.Delete("/tasks/{uuid}/labels/{label}", taskLabelHandler.Detach)
+			r.Get("/tasks/{uuid}/links", linkHandler.List)
+			r.Post("/tasks/{uuid}/links", linkHandler.Create)
+			r.Delete("/tasks/{uuid}/links/{link}", linkHandler.Delete)
+		})
+
+		// Attachments
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("attachments"))
+			r.Get("/tasks/{uuid}/attachments", attachmentHandler.List)
+			r.Post("/tasks/{uuid}/attachments", attachmentHandler.Create)
+			r.Get("/tasks/{uuid}/attachments/{attachment}", attachmentHandler.Get)
+			r.Get("/tasks/{uuid}/attachments/{attachment}/content", attachmentHandler.Content)
+			r.Delete("/tasks/{uuid}/attachments/{attachment}", attachmentHandler.Delete)
+		})
+
+		// Notifications of the authenticated user
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("notifications"))
+			r.Get("/me/notifications", notificationHandler.List)
+			r.Post("/me/notifications:read-all", notificationHandler.MarkAllRead)
+			r.Post("/me/notifications/{uuid}:read", notificationHandler.MarkRead)
+			r.Get("/me/notification-preferences", notificationHandler.Preferences)
+			r.Patch("/me/notification-preferences", notificationHandler.UpdatePreferences)
+		})
+
+		// Worklogs
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("worklogs"))
+			r.Get("/tasks/{uuid}/worklogs", worklogHandler.List)
+			r.Post("/tasks/{uuid}/worklogs", worklogHandler.Create)
+			r.Get("/tasks/{uuid}/worklogs/{worklog}", worklogHandler.Get)
+			r.Patch("/tasks/{uuid}/worklogs/{worklog}", worklogHandler.Update)
+			r.Delete("/tasks/{uuid}/worklogs/{worklog}", worklogHandler.Delete)
+			r.Get("/timesheet", timesheetHandler.Get)
+		})
+
+		// Statuses
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("statuses"))
+			r.Get("/statuses/{uuid}", statusHandler.Get)
+			r.Get("/statuses", statusHandler.List)
+			r.Post("/statuses", statusHandler.Create)
+			r.Patch("/statuses/{uuid}", statusHandler.Update)
+			r.Delete("/statuses/{uuid}", statusHandler.Delete)
+		})
+
+		// Task types
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("task_types"))
+			r.Get("/task-types/{uuid}", taskTypeHandler.Get)
+			r.Get("/task-types", taskTypeHandler.List)
+			r.Post("/task-types", taskTypeHandler.Create)
+			r.Patch("/task-types/{uuid}", taskTypeHandler.Update)
+			r.Delete("/task-types/{uuid}", taskTypeHandler.Delete)
+		})
+
+		// Sprints
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("sprints"))
+			r.Get("/sprints/{uuid}", sprintHandler.Get)
+			r.Get("/sprints", sprintHandler.List)
+			r.Post("/sprints", sprintHandler.Create)
+			r.Patch("/sprints/{uuid}", sprintHandler.Update)
+			r.Delete("/sprints/{uuid}", sprintHandler.Delete)
+			r.Post("/sprints/{uuid}:start", sprintActionHandler.Start)
+			r.Post("/sprints/{uuid}:close", sprintActionHandler.Close)
+		})
+
+		// Labels
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("labels"))
+			r.Get("/labels/{uuid}", labelHandler.Get)
+			r.Get("/labels", labelHandler.List)
+			r.Post("/labels", labelHandler.Create)
+			r.Patch("/labels/{uuid}", labelHandler.Update)
+			r.Delete("/labels/{uuid}", labelHandler.Delete)
+		})
+
+		// Comments
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("comments"))
+			r.Get("/tasks/{uuid}/comments", commentHandler.List)
+			r.Post("/tasks/{uuid}/comments", commentHandler.Create)
+			r.Get("/tasks/{uuid}/comments/{comment}", commentHandler.Get)
+			r.Patch("/tasks/{uuid}/comments/{comment}", commentHandler.Update)
+			r.Delete("/tasks/{uuid}/comments/{comment}", commentHandler.Delete)
+			r.Get("/tasks/{uuid}/comments/{comment}/revisions", commentHandler.Revisions)
+		})
 
 		// Projects
-		v2.Get("/projects/{uuid}", projectHandler.Get)
-		v2.Get("/projects", projectHandler.List)
-		v2.Post("/projects", projectHandler.Create)
-		v2.Patch("/projects/{uuid}", projectHandler.Update)
-		v2.Delete("/projects/{uuid}", projectHandler.Delete)
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("projects"))
+			r.Get("/projects/{uuid}", projectHandler.Get)
+			r.Get("/projects", projectHandler.List)
+			r.Post("/projects", projectHandler.Create)
+			r.Patch("/projects/{uuid}", projectHandler.Update)
+			r.Delete("/projects/{uuid}", projectHandler.Delete)
+		})
+
+		// Memberships
+		v2.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope("memberships"))
+			r.Get("/memberships/{uuid}", membershipHandler.Get)
+			r.Get("/memberships", membershipHandler.List)
+			r.Post("/memberships", membershipHandler.Create)
+			r.Patch("/memberships/{uuid}", membershipHandler.Update)
+			r.Delete("/memberships/{uuid}", membershipHandler.Delete)
+		})
 	})
 
-	return router
+	workers := []worker{
+		rebalancer.Run,
+		func(ctx context.Context) { orphanCollector.Run(ctx, time.Hour) },
+		func(ctx context.Context) { mailQueue.Run(ctx, 30*time.Second) },
+		// Digests are due once a day, when they were last emailed is stored so restarts don't delay them.
+		func(ctx context.Context) { emailer.RunDigests(ctx, 5*time.Minute) },
+	}
+
+	return router, workers
 }
 
 func main() {
@@ -178,8 +620,20 @@ func main() {
 	}
 	defer db.Close()
 
-	// Create api router.
-	router := createRouter(db)
+	// Shut down on interrupt, finishing the requests and background work in progress.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Create api router, and start the background work.
+	router, workers := createRouter(db)
+	var wg sync.WaitGroup
+	for _, run := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(ctx)
+		}()
+	}
 
 	// Get port from environment variable.
 	port := os.Getenv("OMNI_API_PORT")
@@ -188,10 +642,27 @@ func main() {
 	}
 
 	// Start the server.
-	err = http.ListenAndServe(fmt.Sprintf(":%s", port), router)
-	if err != nil {
+	server := &http.Server{Addr: fmt.Sprintf(":%s", port), Handler: router}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		log.Println("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("Failed to shut down the server: %v", err)
+		}
+	}()
+
+	log.Println("Starting on port " + port)
+	err = server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
 		log.Panicf("Error starting server: %v", err)
-	} else {
-		log.Println("Successfully started on port " + port)
 	}
+
+	// Wait for the requests in progress and the background work, the database is closed after them.
+	<-shutdown
+	wg.Wait()
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Attachment is a file uploaded to a task.
+* The content is stored once per digest, attachments with the same content share it.
+ */
+type Attachment struct {
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	TaskID         uuid.UUID `json:"task_id" krest:"readonly" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE"`
+	UploaderID     uuid.UUID `json:"uploader_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE SET NULL"`
+	Name           string    `json:"name" krest:"readonly"`      // The file name of the upload.
+	Size           int64     `json:"size" krest:"readonly"`      // In bytes.
+	MimeType       string    `json:"mime_type" krest:"readonly"` // Sniffed from the content, not taken from the upload.
+	Digest         string    `json:"digest" krest:"readonly"`    // The SHA-256 of the content, in hex.
+	CreatedAt      time.Time `json:"created_at" krest:"readonly"`
+}
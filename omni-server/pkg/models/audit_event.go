@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* AuditEvent records a change to a resource, who made it and when.
+* Audit events are append-only, they can't be changed or deleted through the api.
+ */
+type AuditEvent struct {
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+
+	// The user that made the change, nil for anonymous requests, e.g. signing up.
+	ActorID   uuid.UUID `json:"actor_id"`
+	RequestID string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// The action is one of create, update or delete. The resource type is the table of the resource.
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   uuid.UUID `json:"resource_id"`
+
+	// The project of the resource, nil for resources outside of projects. Used to authorize reading the event.
+	ProjectID uuid.UUID `json:"project_id"`
+
+	Changes AuditChanges `json:"changes" krest_orm:"type:JSONB"`
+}
+
+/*
+* AuditChanges are the changed fields of a resource, stored as JSON.
+ */
+type AuditChanges map[string]AuditChange
+
+/*
+* AuditChange is the value of a field before and after a change, nil for fields of created or deleted resources.
+ */
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	return jsonValue(c)
+}
+
+func (c *AuditChanges) Scan(src interface{}) error {
+	*c = nil
+	return scanJSON(src, c)
+}
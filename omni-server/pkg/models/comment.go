@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Comment represents a comment on a task, the body is markdown.
+* Every version of a comment is kept as a revision, as its edit history.
+ */
+type Comment struct {
+	UUID           uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk,revisions"`
+	OrganizationID uuid.UUID  `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	TaskID         uuid.UUID  `json:"task_id" krest:"readonly" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE"`
+	AuthorID       *uuid.UUID `json:"author_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE SET NULL"` // Nil for deleted users.
+	Body           string     `json:"body" krest_orm:"searchable" krest_validate:"required,max:65535"`
+	CreatedAt      time.Time  `json:"created_at" krest:"readonly"`
+	EditedAt       *time.Time `json:"edited_at" krest:"readonly"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "database/sql/driver"
+
+// Types of custom fields.
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date" // YYYY-MM-DD
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldUser        = "user" // UUID of a user.
+)
+
+/*
+* CustomField defines a field tasks of a task type have, in addition to the built-in fields.
+* Values are stored by key, select fields choose from the options, and tasks get the default if they don't set a value.
+ */
+type CustomField struct {
+	Key      string      `json:"key"`
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Options  []string    `json:"options,omitempty"`
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default,omitempty"`
+}
+
+// CustomFields are stored as JSON.
+type CustomFields []CustomField
+
+func (f CustomFields) Value() (driver.Value, error) {
+	return jsonValue(f)
+}
+
+func (f *CustomFields) Scan(src interface{}) error {
+	*f = nil
+	return scanJSON(src, f)
+}
+
+/*
+* CustomFieldValues are the values of the custom fields of a task, by key. Stored as JSON.
+ */
+type CustomFieldValues map[string]interface{}
+
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	return jsonValue(v)
+}
+
+func (v *CustomFieldValues) Scan(src interface{}) error {
+	*v = nil
+	return scanJSON(src, v)
+}
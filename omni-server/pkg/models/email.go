@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Email is an email waiting in the mail queue, kept after it's sent.
+* Emails that fail to send are retried with backoff, until they run out of attempts.
+ */
+type Email struct {
+	UUID          uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Text          string     `json:"text"`
+	HTML          string     `json:"html"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
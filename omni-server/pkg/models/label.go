@@ -0,0 +1,15 @@
+package models
+
+import "github.com/google/uuid"
+
+/*
+* Label tags tasks, e.g. "backend" or "regression". Tasks have any number of labels.
+* Labels without a project are shared by all projects of the organization, others only label tasks of their project.
+ */
+type Label struct {
+	UUID           uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID  `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID      *uuid.UUID `json:"project_id" krest_orm:"fk:projects(uuid) ON DELETE CASCADE" krest_validate:"ref:projects"`
+	Name           string     `json:"name" krest_validate:"required,max:255"`
+	Color          string     `json:"color" krest_validate:"required,pattern:^#[0-9a-fA-F]{6}$"` // E.g. #d73a4a
+}
@@ -0,0 +1,18 @@
+package models
+
+import "github.com/google/uuid"
+
+/*
+* Membership grants a user a role in a project.
+* Roles are ordered, each role includes the permissions of the roles before it:
+*  - viewer: Can read the project and its tasks.
+*  - member: Can create, edit and delete tasks, and plan sprints.
+*  - admin: Can edit and delete the project, and manage its members.
+ */
+type Membership struct {
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID      uuid.UUID `json:"project_id" krest_orm:"fk:projects(uuid) ON DELETE CASCADE,unique:user_id" krest_validate:"required,ref:projects"`
+	UserID         uuid.UUID `json:"user_id" krest_orm:"fk:users(uuid) ON DELETE CASCADE" krest_validate:"required,ref:users"`
+	Role           string    `json:"role" krest_validate:"required,oneof:viewer|member|admin"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notifications, the task events users are notified of.
+const (
+	NotificationAssigned      = "assigned"
+	NotificationStatusChanged = "status_changed"
+	NotificationCommented     = "commented"
+	NotificationMentioned     = "mentioned"
+)
+
+// How users want notifications emailed, each one as it happens or all of them in a daily digest.
+const (
+	NotificationEmailOff       = "off"
+	NotificationEmailImmediate = "immediate"
+	NotificationEmailDigest    = "digest"
+)
+
+/*
+* Notification tells a user about an event on a task, e.g. a task being assigned to them.
+ */
+type Notification struct {
+	UUID           uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID  `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	UserID         uuid.UUID  `json:"user_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE CASCADE"`   // The user notified.
+	ActorID        *uuid.UUID `json:"actor_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE SET NULL"` // The user causing the event.
+	TaskID         uuid.UUID  `json:"task_id" krest:"readonly" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE"`
+	Kind           string     `json:"kind" krest:"readonly"`
+	Message        string     `json:"message" krest:"readonly"` // E.g. alice moved OMNI-42 to Done.
+	CreatedAt      time.Time  `json:"created_at" krest:"readonly"`
+	ReadAt         *time.Time `json:"read_at" krest:"readonly"`
+	EmailedAt      *time.Time `json:"emailed_at" krest:"readonly"` // When the notification was emailed, alone or in a digest.
+}
+
+/*
+* NotificationPreferences are the kinds of notifications a user wants, users without preferences get every kind.
+ */
+type NotificationPreferences struct {
+	UUID           uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID  `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	UserID         uuid.UUID  `json:"user_id" krest:"readonly" krest_orm:"unique:organization_id,fk:users(uuid) ON DELETE CASCADE"`
+	Assigned       bool       `json:"assigned"`
+	StatusChanged  bool       `json:"status_changed"`
+	Commented      bool       `json:"commented"`
+	Mentioned      bool       `json:"mentioned"`
+	Email          string     `json:"email" krest_validate:"oneof:off|immediate|digest"` // Defaults to immediate.
+	LastDigestAt   *time.Time `json:"last_digest_at" krest:"readonly"`                   // When the user was last emailed a digest.
+}
+
+/*
+* Wants returns whether the preferences include a kind of notification.
+ */
+func (p NotificationPreferences) Wants(kind string) bool {
+	switch kind {
+	case NotificationAssigned:
+		return p.Assigned
+	case NotificationStatusChanged:
+		return p.StatusChanged
+	case NotificationCommented:
+		return p.Commented
+	case NotificationMentioned:
+		return p.Mentioned
+	}
+	return false
+}
+
+/*
+* EmailDelivery returns how the user wants notifications emailed.
+ */
+func (p NotificationPreferences) EmailDelivery() string {
+	if p.Email == "" {
+		return NotificationEmailImmediate
+	}
+	return p.Email
+}
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+/*
+* Organization represents a company sharing the deployment, the tenant of most other resources.
+* Resources of an organization are never visible to other organizations.
+ */
+type Organization struct {
+	UUID uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	Name string    `json:"name" krest_validate:"required,max:255"`
+}
@@ -6,13 +6,14 @@ import "github.com/google/uuid"
 * Project represents a project in the system.
  */
 type Project struct {
-	UUID uuid.UUID `json:"uuid" krest_orm:"pk"`
-	Name string    `json:"name"`
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	Name           string    `json:"name" krest_validate:"required,max:255"`
 	//Tasks []*Task   `json:"tasks" krest:"expandable" krest_orm:"fk:Project"`
 
 	/*
 	* The project key is a short, human-readable identifier for the project.
-	* The key is NOT garanteed to be unique.
+	* The key is unique within an organization.
 	 */
-	Key string `json:"key"`
+	Key string `json:"key" krest_orm:"unique:organization_id" krest_validate:"required,max:10,pattern:^[A-Z][A-Z0-9]*$"`
 }
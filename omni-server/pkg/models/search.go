@@ -0,0 +1,18 @@
+package models
+
+// Types of search results.
+const (
+	SearchResultTask    = "task"
+	SearchResultComment = "comment"
+)
+
+/*
+* SearchResult is a task or a comment matching a search, with a snippet of the matching text.
+ */
+type SearchResult struct {
+	Type    string   `json:"type"`
+	Rank    float64  `json:"rank"`    // Relevance of the result, higher is more relevant.
+	Snippet string   `json:"snippet"` // HTML of the matching text, the matches are highlighted with <mark>.
+	Task    *Task    `json:"task"`    // The task matching, or the task of the comment matching.
+	Comment *Comment `json:"comment"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Session represents a signed in user, identified by the session cookie.
+* Only a hash of the session token is stored, the token itself is only known by the client.
+ */
+type Session struct {
+	UUID      uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	UserID    uuid.UUID `json:"user_id" krest_orm:"fk:users(uuid) ON DELETE CASCADE"`
+	TokenHash string    `json:"token_hash" krest:"writeonly" krest_orm:"unique,notnull"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
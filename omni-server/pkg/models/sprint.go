@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a sprint, sprints are planned, then active, then closed.
+const (
+	SprintPlanned = "planned"
+	SprintActive  = "active"
+	SprintClosed  = "closed"
+)
+
+/*
+* Sprint is a timeboxed iteration of a project, tasks are planned into sprints.
+* Starting a sprint records the tasks committed to, closing it records what was completed.
+ */
+type Sprint struct {
+	UUID           uuid.UUID     `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID     `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID      uuid.UUID     `json:"project_id" krest_orm:"fk:projects(uuid) ON DELETE CASCADE" krest_validate:"required,ref:projects"`
+	Name           string        `json:"name" krest_validate:"required,max:255"`
+	Goal           string        `json:"goal" krest_validate:"max:65535"`
+	StartDate      *time.Time    `json:"start_date"`
+	EndDate        *time.Time    `json:"end_date"`
+	State          string        `json:"state" krest:"readonly"`
+	Committed      TaskIDs       `json:"committed_task_ids" krest:"readonly" krest_orm:"type:JSONB"`
+	Report         *SprintReport `json:"report" krest:"readonly" krest_orm:"type:JSONB"`
+}
+
+/*
+* SprintReport is a snapshot of the work of a sprint when it closed.
+* Incomplete tasks moved to the next sprint, or the backlog if there is none.
+ */
+type SprintReport struct {
+	ClosedAt          time.Time  `json:"closed_at"`
+	CommittedTaskIDs  TaskIDs    `json:"committed_task_ids"`
+	CompletedTaskIDs  TaskIDs    `json:"completed_task_ids"`
+	IncompleteTaskIDs TaskIDs    `json:"incomplete_task_ids"`
+	MovedToSprintID   *uuid.UUID `json:"moved_to_sprint_id"`
+}
+
+func (r SprintReport) Value() (driver.Value, error) {
+	return jsonValue(r)
+}
+
+func (r *SprintReport) Scan(src interface{}) error {
+	return scanJSON(src, r)
+}
+
+// TaskIDs are stored as JSON.
+type TaskIDs []uuid.UUID
+
+func (ids TaskIDs) Value() (driver.Value, error) {
+	return jsonValue(ids)
+}
+
+func (ids *TaskIDs) Scan(src interface{}) error {
+	*ids = nil
+	return scanJSON(src, ids)
+}
@@ -3,10 +3,23 @@ package models
 import "github.com/google/uuid"
 
 /*
-* Status represents the status of a task.
+* Status represents the status of a task. Statuses belong to a project.
+* The category groups statuses across projects and workflows:
+*  - todo: Work hasn't started.
+*  - in-progress: Work has started.
+*  - done: Work is finished, the task is resolved.
  */
 type Status struct {
-	UUID        uuid.UUID `json:"uuid" krest_orm:"pk"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID      uuid.UUID `json:"project_id" krest_orm:"fk:projects(uuid) ON DELETE CASCADE" krest_validate:"required,ref:projects"`
+	Name           string    `json:"name" krest_validate:"required,max:255"`
+	Description    string    `json:"description"`
+	Category       string    `json:"category" krest_validate:"required,oneof:todo|in-progress|done"`
 }
+
+const (
+	StatusCategoryTodo       = "todo"
+	StatusCategoryInProgress = "in-progress"
+	StatusCategoryDone       = "done"
+)
@@ -1,16 +1,65 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 /*
 * Task represents a task in the system.
+* Every version of a task is kept as a revision.
  */
 type Task struct {
-	UUID        uuid.UUID `db:"uuid" json:"uuid" krest_orm:"pk"`
-	Summary     string    `db:"summary" json:"summary" krest:"expandable"`
-	Description string    `db:"description" json:"description" krest:"expandable"`
-	ProjectID   uuid.UUID `db:"project_id" json:"project_id" krest:"expandable" krest_orm:"fk:projects(uuid)"`
+	UUID           uuid.UUID  `db:"uuid" json:"uuid" krest:"readonly" krest_orm:"pk,revisions"`
+	Number         int        `db:"number" json:"number" krest:"readonly"`
+	Key            string     `db:"key" json:"key" krest:"readonly"`   // E.g. OMNI-42, the key of the project and the number of the task in it.
+	Rank           string     `db:"rank" json:"rank" krest:"readonly"` // Position of the task in the backlog of its project, ordered lexicographically.
+	Summary        string     `db:"summary" json:"summary" krest:"expandable" krest_orm:"searchable" krest_validate:"required,max:255"`
+	Description    string     `db:"description" json:"description" krest:"expandable" krest_orm:"searchable" krest_validate:"max:65535"`
+	OrganizationID uuid.UUID  `db:"organization_id" json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID      uuid.UUID  `db:"project_id" json:"project_id" krest:"expandable" krest_orm:"fk:projects(uuid)" krest_validate:"required,ref:projects"`
+	TypeID         *uuid.UUID `db:"type_id" json:"type_id" krest:"expandable" krest_orm:"fk:task_types(uuid)" krest_validate:"ref:task_types"`
+	StatusID       *uuid.UUID `db:"status_id" json:"status_id" krest:"expandable" krest_orm:"fk:statuses(uuid)" krest_validate:"ref:statuses"`
+	AssigneeID     *uuid.UUID `db:"assignee_id" json:"assignee_id" krest:"expandable" krest_orm:"fk:users(uuid) ON DELETE SET NULL" krest_validate:"ref:users"`
+	ReporterID     *uuid.UUID `db:"reporter_id" json:"reporter_id" krest:"expandable,readonly" krest_orm:"fk:users(uuid) ON DELETE SET NULL" krest_validate:"ref:users"` // The user creating the task.
+	ParentID       *uuid.UUID `db:"parent_id" json:"parent_id" krest:"expandable" krest_orm:"fk:tasks(uuid) ON DELETE SET NULL" krest_validate:"ref:tasks"`
+	SprintID       *uuid.UUID `db:"sprint_id" json:"sprint_id" krest:"expandable" krest_orm:"fk:sprints(uuid) ON DELETE SET NULL" krest_validate:"ref:sprints"`
+
+	// Values of the custom fields of the task type, see CustomField.
+	Fields CustomFieldValues `db:"fields" json:"fields" krest_orm:"type:JSONB"`
+
+	// Estimates in minutes, logging work reduces the remaining estimate, see Worklog.
+	OriginalEstimate  *int `db:"original_estimate" json:"original_estimate" krest:"expandable" krest_validate:"min:0"`
+	RemainingEstimate *int `db:"remaining_estimate" json:"remaining_estimate" krest:"expandable" krest_validate:"min:0"` // Defaults to the original estimate.
+
+	// Set by the workflow when the status changes, when work starts, and when the task is resolved.
+	StatusChangedAt *time.Time `db:"status_changed_at" json:"status_changed_at" krest:"expandable,readonly"`
+	StartedAt       *time.Time `db:"started_at" json:"started_at" krest:"expandable,readonly"`
+	CompletedAt     *time.Time `db:"completed_at" json:"completed_at" krest:"expandable,readonly"`
+
+	Type     *TaskType `json:"type" krest:"expandable" krest_orm:"ignore"`
+	Status   *Status   `json:"status" krest:"expandable" krest_orm:"ignore"`
+	Project  *Project  `json:"project" krest:"expandable" krest_orm:"ignore"`
+	Sprint   *Sprint   `json:"sprint" krest:"expandable" krest_orm:"ignore"`
+	Assignee *User     `json:"assignee" krest:"expandable" krest_orm:"ignore"`
+	Reporter *User     `json:"reporter" krest:"expandable" krest_orm:"ignore"`
+	Watchers []User    `json:"watchers" krest:"expandable,readonly" krest_orm:"ignore"`
+	Labels   []Label   `json:"labels" krest:"expandable,readonly" krest_orm:"ignore"`
+	Comments []Comment `json:"comments" krest:"expandable,readonly" krest_orm:"ignore"`
+	Parent   *Task     `json:"parent" krest:"expandable" krest_orm:"ignore"`
+	Progress *Progress `json:"progress" krest:"expandable,readonly" krest_orm:"ignore"`
 
-	Status  *Status  `json:"status" krest:"expandable" krest_orm:"ignore"`
-	Project *Project `json:"project" krest:"expandable" krest_orm:"ignore"`
+	// The description rendered from markdown to sanitized HTML, requested with ?fields=description_html.
+	DescriptionHTML *string `json:"description_html" krest:"expandable,readonly" krest_orm:"ignore"`
+}
+
+/*
+* Progress rolls up the completion of the descendants of a task, its children and their children.
+* Tasks without descendants have no progress to roll up, their percent is 0.
+ */
+type Progress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Percent   int `json:"percent"`
 }
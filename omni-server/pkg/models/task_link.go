@@ -0,0 +1,52 @@
+package models
+
+import "github.com/google/uuid"
+
+// Types of task links, each type has an inverse, relates-to is its own inverse.
+const (
+	LinkBlocks       = "blocks"
+	LinkBlockedBy    = "blocked-by"
+	LinkRelatesTo    = "relates-to"
+	LinkDuplicates   = "duplicates"
+	LinkDuplicatedBy = "duplicated-by"
+	LinkMentions     = "mentions"
+	LinkMentionedIn  = "mentioned-in" // Created when the description or a comment of another task references the task.
+)
+
+// InverseLinkTypes maps each link type to the type of the link stored in the other direction.
+var InverseLinkTypes = map[string]string{
+	LinkBlocks:       LinkBlockedBy,
+	LinkBlockedBy:    LinkBlocks,
+	LinkRelatesTo:    LinkRelatesTo,
+	LinkDuplicates:   LinkDuplicatedBy,
+	LinkDuplicatedBy: LinkDuplicates,
+	LinkMentions:     LinkMentionedIn,
+	LinkMentionedIn:  LinkMentions,
+}
+
+/*
+* TaskLink is a typed link from one task to another, e.g. OMNI-1 blocks OMNI-2.
+* Every link is stored along with its inverse, e.g. OMNI-2 blocked-by OMNI-1, so both tasks list it.
+ */
+type TaskLink struct {
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	SourceID       uuid.UUID `json:"source_id" krest:"readonly" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE"`
+	TargetID       uuid.UUID `json:"target_id" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE" krest_validate:"required,ref:tasks"`
+	Type           string    `json:"type" krest_validate:"required,oneof:blocks|blocked-by|relates-to|duplicates|duplicated-by|mentions|mentioned-in"`
+
+	Target *Task `json:"target" krest:"expandable" krest_orm:"ignore"`
+}
+
+/*
+* TaskLinks are the links of a task, grouped by type.
+ */
+type TaskLinks struct {
+	Blocks       []TaskLink `json:"blocks"`
+	BlockedBy    []TaskLink `json:"blocked-by"`
+	RelatesTo    []TaskLink `json:"relates-to"`
+	Duplicates   []TaskLink `json:"duplicates"`
+	DuplicatedBy []TaskLink `json:"duplicated-by"`
+	Mentions     []TaskLink `json:"mentions"`
+	MentionedIn  []TaskLink `json:"mentioned-in"`
+}
@@ -1,12 +1,50 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"database/sql/driver"
+
+	"github.com/google/uuid"
+)
 
 /*
 * TaskType defines how a family of tasks should be handled.
+* The workflow of a task type defines the statuses its tasks start in, and the allowed status changes.
+* Task types without transitions don't restrict status changes.
+* Custom fields of a task type are fields its tasks have in addition to the built-in fields.
+* Tasks of an epic type group tasks of their project as their children, epics have no parent themselves.
  */
 type TaskType struct {
-	UUID        uuid.UUID `json:"uuid" krest_orm:"pk"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
+	UUID            uuid.UUID    `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID  uuid.UUID    `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	ProjectID       uuid.UUID    `json:"project_id" krest_orm:"fk:projects(uuid) ON DELETE CASCADE" krest_validate:"required,ref:projects"`
+	Name            string       `json:"name" krest_validate:"required,max:255"`
+	Description     string       `json:"description"`
+	Epic            bool         `json:"epic"`
+	InitialStatusID *uuid.UUID   `json:"initial_status_id" krest_orm:"fk:statuses(uuid) ON DELETE SET NULL" krest_validate:"ref:statuses"`
+	Transitions     Transitions  `json:"transitions" krest_orm:"type:JSONB"`
+	Fields          CustomFields `json:"fields" krest_orm:"type:JSONB"`
+}
+
+/*
+* Transition allows tasks to move from one status to another.
+* Transitions without a from status are allowed from any status.
+* Guards are conditions the task must meet, e.g. assignee_required.
+ */
+type Transition struct {
+	Name         string     `json:"name"`
+	FromStatusID *uuid.UUID `json:"from_status_id"`
+	ToStatusID   uuid.UUID  `json:"to_status_id"`
+	Guards       []string   `json:"guards"`
+}
+
+// Transitions are stored as JSON.
+type Transitions []Transition
+
+func (t Transitions) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+func (t *Transitions) Scan(src interface{}) error {
+	*t = nil
+	return scanJSON(src, t)
 }
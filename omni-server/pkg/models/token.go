@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Token represents a personal access token, used by scripts and CI jobs to call the API as a user.
+* Only a hash of the token is stored, the token itself is only returned once, when it's created.
+ */
+type Token struct {
+	UUID   uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	UserID uuid.UUID `json:"user_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE CASCADE"`
+	Name   string    `json:"name" krest_validate:"required,max:255"`
+
+	/*
+	* Space separated list of scopes, e.g. "read:projects write:tasks", or "read:*" for all resources.
+	* Write access implies read access.
+	 */
+	Scopes string `json:"scopes" krest_validate:"required,max:1024"`
+
+	// First characters of the token, so it can be recognized.
+	Prefix     string    `json:"prefix" krest:"readonly"`
+	TokenHash  string    `json:"token_hash" krest:"readonly,writeonly" krest_orm:"unique,notnull"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at" krest:"readonly"`
+	CreatedAt  time.Time `json:"created_at" krest:"readonly"`
+
+	// The token itself, only set when the token is created.
+	Token string `json:"token,omitempty" krest:"readonly" krest_orm:"ignore"`
+}
@@ -4,10 +4,21 @@ import "github.com/google/uuid"
 
 /*
 * User represents a user of the system.
+* Users belong to a single organization, their email is unique across organizations to sign in with.
+* Their username is unique in the organization, to @mention them with.
+* Admins of the organization can manage its other users, the first user of an organization is its admin.
  */
 type User struct {
-	UUID     uuid.UUID `json:"uuid" krest_orm:"pk"`
-	Name     string    `json:"name"`
-	Email    string    `json:"email"`
-	Password string    `json:"password"`
+	UUID           uuid.UUID `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID uuid.UUID `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	Name           string    `json:"name" krest_validate:"required,max:255"`
+	Username       string    `json:"username" krest_orm:"unique:organization_id" krest_validate:"required,max:64,pattern:^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9_])?$"`
+	Email          string    `json:"email" krest_orm:"unique" krest_validate:"required,email"`
+	Password       string    `json:"password" krest:"writeonly" krest_validate:"required,min:8"`
+	Role           string    `json:"role" krest_validate:"oneof:member|admin"`
 }
+
+const (
+	UserRoleMember = "member"
+	UserRoleAdmin  = "admin"
+)
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* Worklog is time a user spent working on a task, logging it reduces the remaining estimate of the task.
+* Worklogs of deleted users are kept without a user, for the timesheets of the project.
+ */
+type Worklog struct {
+	UUID            uuid.UUID  `json:"uuid" krest:"readonly" krest_orm:"pk"`
+	OrganizationID  uuid.UUID  `json:"organization_id" krest:"readonly" krest_orm:"tenant,fk:organizations(uuid) ON DELETE CASCADE"`
+	TaskID          uuid.UUID  `json:"task_id" krest:"readonly" krest_orm:"fk:tasks(uuid) ON DELETE CASCADE"`
+	UserID          *uuid.UUID `json:"user_id" krest:"readonly" krest_orm:"fk:users(uuid) ON DELETE SET NULL"`
+	StartedAt       time.Time  `json:"started_at" krest_validate:"required"`
+	Duration        int        `json:"duration" krest_validate:"required,min:1"` // In minutes.
+	Comment         string     `json:"comment" krest_validate:"max:65535"`
+	EstimateReduced int        `json:"estimate_reduced" krest:"readonly"` // In minutes, less than the duration if the estimate was reduced to 0.
+	CreatedAt       time.Time  `json:"created_at" krest:"readonly"`
+}
+
+/*
+* TimesheetEntry is the time a user logged on the tasks of a project in a day (UTC), in minutes.
+ */
+type TimesheetEntry struct {
+	Date       string     `json:"date"`    // E.g. 2026-10-18.
+	UserID     *uuid.UUID `json:"user_id"` // Nil for deleted users.
+	UserName   string     `json:"user_name"`
+	ProjectID  uuid.UUID  `json:"project_id"`
+	ProjectKey string     `json:"project_key"`
+	Duration   int        `json:"duration"`
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/khaossystems/omni-server/internal/pkg/krest"
 	"github.com/khaossystems/omni-server/internal/pkg/krest_orm"
 	"github.com/khaossystems/omni-server/pkg/models"
@@ -22,17 +23,21 @@ func TestCreateProject(t *testing.T) {
 	projectRepository := krest_orm.NewGenericPostgresRepository[models.Project](db)
 	projectService := krest_orm.NewGenericService(projectRepository)
 
+	// Projects belong to an organization.
+	ctx := krest_orm.WithTenant(context.Background(), uuid.New())
+
 	// Create a project.
 	project := models.Project{
 		Name: "Test Project",
+		Key:  "TEST",
 	}
-	_, err = projectService.Create(context.Background(), project)
+	_, err = projectService.Create(ctx, project)
 	if err != nil {
 		t.Fatalf("failed to create project: %v", err)
 	}
 
 	// Make sure the project was created.
-	projects, err := projectService.List(context.Background(), krest.CollectionQuery{})
+	projects, err := projectService.List(ctx, krest.CollectionQuery{})
 	if err != nil {
 		t.Fatalf("failed to list projects: %v", err)
 	}